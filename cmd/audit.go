@@ -0,0 +1,96 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/KofClubs/siwa/node/audit"
+	"github.com/spf13/cobra"
+	"go.dedis.ch/kyber/v3"
+)
+
+var NoAuditPathErr = fmt.Errorf("no path of audit log")
+
+var (
+	trustedPublicKeys map[string]string
+
+	// auditCmd inspects the audit log written by siwa serve, at the path of the "audit" section of the config file
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the audit log of signed answers",
+	}
+
+	auditVerifyCmd = &cobra.Command{
+		Use:   "verify [path]",
+		Short: "Verify the hash chain and signatures of the audit log",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			unmarshalledLog := &audit.UnmarshalledLog{}
+			err := unmarshalKey("audit", unmarshalledLog)
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				unmarshalledLog.Path = args[0]
+			}
+			if unmarshalledLog.Path == "" {
+				return NoAuditPathErr
+			}
+			publicKeys := make(map[string]kyber.Point)
+			for groupId, publicKeyHex := range trustedPublicKeys {
+				publicKeyBytes, err := hex.DecodeString(publicKeyHex)
+				if err != nil {
+					return err
+				}
+				publicKeys[groupId], err = crypto.DecodeBlsPublicKey(crypto.GetBlsSuite(), publicKeyBytes)
+				if err != nil {
+					return err
+				}
+			}
+
+			file, err := os.Open(unmarshalledLog.Path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			count, err := audit.Verify(file, publicKeys)
+			if err != nil {
+				return fmt.Errorf("%w after %d verified entries", err, count)
+			}
+			fmt.Printf("%d entries verified\n", count)
+			return nil
+		},
+	}
+)
+
+func init() {
+	auditVerifyCmd.Flags().StringToStringVar(&trustedPublicKeys, "public-key", nil,
+		"trusted distributed public key of a group in hex, as group_id=key, entries of every group must be signed by "+
+			"its trusted key")
+	_ = auditVerifyCmd.MarkFlagRequired("public-key")
+	auditCmd.AddCommand(auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/KofClubs/siwa/node"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	groupId               string
+	createThresholdPolicy string
+	createThreshold       float64
+	indexPolicy           string
+	newThresholdPolicy    string
+	newThreshold          float64
+
+	// groupCmd manages groups recorded in the "groups" section of the config file, which take effect when nodes
+	// are created from the config file
+	groupCmd = &cobra.Command{
+		Use:   "group",
+		Short: "Manage groups in the config file",
+		Long: "Manage groups in the \"groups\" section of the config file. Changes take effect when nodes are " +
+			"created from the config file.",
+		PersistentPreRunE: loadGroups,
+	}
+
+	groupCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Add a group with a threshold policy to the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy, err := parseThresholdPolicyFlags(cmd, createThresholdPolicy, createThreshold)
+			if err != nil {
+				return err
+			}
+			dkgIndexPolicy, err := node.ParseIndexPolicy(indexPolicy)
+			if err != nil {
+				return err
+			}
+			group, err := node.CreateGroup(groupId, policy, dkgIndexPolicy)
+			if err != nil {
+				return err
+			}
+			fmt.Println(group.Id)
+			return saveGroups()
+		},
+	}
+
+	groupListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List groups with counts of nodes configured in them",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeCounts, err := countNodesByGroup()
+			if err != nil {
+				return err
+			}
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(writer, "ID\tNODES\tTHRESHOLD\tTHRESHOLD POLICY\tINDEX POLICY")
+			for _, group := range node.ListGroups() {
+				nodeCount := nodeCounts[group.Id]
+				_, _ = fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\n", group.Id, nodeCount,
+					group.ThresholdPolicy.ThresholdOf(nodeCount), group.ThresholdPolicy, group.IndexPolicy)
+			}
+			if nodeCounts[""] > 0 {
+				_, _ = fmt.Fprintf(writer, "%v nodes without group id are scheduled into groups when created\n",
+					nodeCounts[""])
+			}
+			return writer.Flush()
+		},
+	}
+
+	groupDissolveCmd = &cobra.Command{
+		Use:   "dissolve <group id>",
+		Short: "Remove a group and nodes configured in it from the config file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := node.DissolveGroup(args[0])
+			if err != nil {
+				return err
+			}
+			removeNodesOfGroup(args[0])
+			return saveGroups()
+		},
+	}
+
+	groupThresholdCmd = &cobra.Command{
+		Use:   "threshold <group id>",
+		Short: "Change the threshold policy of a group in the config file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy, err := parseThresholdPolicyFlags(cmd, newThresholdPolicy, newThreshold)
+			if err != nil {
+				return err
+			}
+			err = node.SetThreshold(args[0], policy)
+			if err != nil {
+				return err
+			}
+			return saveGroups()
+		},
+	}
+)
+
+func init() {
+	groupCreateCmd.Flags().StringVar(&createThresholdPolicy, "threshold-policy", "majority",
+		"threshold policy: majority, absolute, fraction or minimum, absolute if only --threshold is set")
+	groupCreateCmd.Flags().Float64Var(&createThreshold, "threshold", 0,
+		"node count of absolute policy, or node fraction of fraction policy")
+	groupCreateCmd.Flags().StringVar(&groupId, "id", "", "group id (default is generated)")
+	groupCreateCmd.Flags().StringVar(&indexPolicy, "index-policy", "join_sequence",
+		"dkg index policy: join_sequence or public_key")
+	groupThresholdCmd.Flags().StringVar(&newThresholdPolicy, "threshold-policy", "majority",
+		"threshold policy: majority, absolute, fraction or minimum, absolute if only --threshold is set")
+	groupThresholdCmd.Flags().Float64Var(&newThreshold, "threshold", 0,
+		"node count of absolute policy, or node fraction of fraction policy")
+
+	groupCmd.AddCommand(groupCreateCmd, groupListCmd, groupDissolveCmd, groupThresholdCmd)
+	rootCmd.AddCommand(groupCmd)
+}
+
+// parseThresholdPolicyFlags takes --threshold alone as the node count of the absolute policy
+func parseThresholdPolicyFlags(cmd *cobra.Command, policyType string, threshold float64) (node.ThresholdPolicy,
+	error) {
+	if cmd.Flags().Changed("threshold") && !cmd.Flags().Changed("threshold-policy") {
+		policyType = "absolute"
+	}
+	return node.ParseThresholdPolicy(policyType, threshold)
+}
+
+func loadGroups(cmd *cobra.Command, args []string) error {
+	unmarshalledGroups := make([]*node.UnmarshalledGroup, 0)
+	err := unmarshalKey("groups", &unmarshalledGroups)
+	if err != nil {
+		return err
+	}
+	for _, unmarshalledGroup := range unmarshalledGroups {
+		_, err = unmarshalledGroup.CreateGroup()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countNodesByGroup counts nodes in the "nodes" section by group ids, without creating them
+func countNodesByGroup() (map[string]int, error) {
+	unmarshalledNodes := make([]*node.UnmarshalledNode, 0)
+	err := unmarshalKey("nodes", &unmarshalledNodes)
+	if err != nil {
+		return nil, err
+	}
+	nodeCounts := make(map[string]int)
+	for _, unmarshalledNode := range unmarshalledNodes {
+		nodeCounts[unmarshalledNode.GroupId]++
+	}
+	return nodeCounts, nil
+}
+
+// removeNodesOfGroup removes nodes of the group from the "nodes" section, which could not be created any more
+func removeNodesOfGroup(groupId string) {
+	rawNodes, ok := viper.Get("nodes").([]interface{})
+	if !ok {
+		return
+	}
+	keptNodes := make([]interface{}, 0, len(rawNodes))
+	for _, rawNode := range rawNodes {
+		if nodeConfig, ok := rawNode.(map[string]interface{}); ok && nodeConfig["group_id"] == groupId {
+			continue
+		}
+		keptNodes = append(keptNodes, rawNode)
+	}
+	viper.Set("nodes", keptNodes)
+}
+
+func saveGroups() error {
+	unmarshalledGroups := make([]map[string]interface{}, 0)
+	for _, group := range node.ListGroups() {
+		unmarshalledGroup := group.ToUnmarshalledGroup()
+		unmarshalledGroups = append(unmarshalledGroups, map[string]interface{}{
+			"id":               unmarshalledGroup.Id,
+			"threshold_policy": unmarshalledGroup.ThresholdPolicy,
+			"threshold":        unmarshalledGroup.Threshold,
+			"index_policy":     unmarshalledGroup.IndexPolicy,
+		})
+	}
+	viper.Set("groups", unmarshalledGroups)
+
+	if viper.ConfigFileUsed() != "" {
+		return viper.WriteConfig()
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	return viper.WriteConfigAs(filepath.Join(home, ".siwa.yaml"))
+}
@@ -25,13 +25,17 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/KofClubs/siwa/node"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
+	cfgFile   string
+	stateFile string
 
 	// rootCmd represents the base command when called without any subcommands
 	rootCmd = &cobra.Command{
@@ -66,6 +70,8 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.siwa.yaml)")
+	rootCmd.PersistentFlags().StringVar(&stateFile, "state", "",
+		"state file of dkg indices, never the config file (default is $HOME/.siwa.state.json)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -94,4 +100,21 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		_, _ = fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
+
+	// dkg indices of nodes are kept in the state file, so that they are the same after restarts
+	if stateFile == "" {
+		home, err := os.UserHomeDir()
+		cobra.CheckErr(err)
+		stateFile = filepath.Join(home, ".siwa.state.json")
+	}
+	cobra.CheckErr(node.LoadDkgState(stateFile))
+}
+
+// unmarshalKey unmarshals a config section into structs tagged by yaml, like UnmarshalledNode, embedded structs
+// are inlined
+func unmarshalKey(key string, rawVal interface{}) error {
+	return viper.UnmarshalKey(key, rawVal, func(decoderConfig *mapstructure.DecoderConfig) {
+		decoderConfig.TagName = "yaml"
+		decoderConfig.Squash = true
+	})
 }
@@ -0,0 +1,263 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/KofClubs/siwa/node"
+	"github.com/KofClubs/siwa/node/audit"
+	"github.com/KofClubs/siwa/node/chain"
+	"github.com/KofClubs/siwa/node/ethereum"
+	"github.com/KofClubs/siwa/node/feed"
+	"github.com/KofClubs/siwa/node/telemetry"
+	"github.com/MonteCarloClub/log"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	CreateNodeErr       = fmt.Errorf("fail to create node")
+	IllegalChainTypeErr = fmt.Errorf("illegal chain type")
+)
+
+// UnmarshalledChain is an item of the "chains" section, other fields configure the adapter of the type
+type UnmarshalledChain struct {
+	// Type is ethereum
+	Type string `yaml:"type"`
+	// CheckRegistry refuses to answer by a group whose distributed public key differs from the registry
+	CheckRegistry bool `yaml:"check_registry"`
+
+	ethereum.UnmarshalledAdapter `yaml:",inline"`
+}
+
+var (
+	listenAddress string
+
+	// serveCmd runs nodes in the "nodes" section of the config file, publishes feeds in the "feeds" section,
+	// streams their reports at /stream, answers requests from chains in the "chains" section, exposes metrics at
+	// /metrics, health at /healthz and /readyz and states of nodes at /status, spans are exported as the "tracing"
+	// section configures, and signed answers are appended to the log of the "audit" section
+	serveCmd = &cobra.Command{
+		Use:     "serve",
+		Short:   "Run nodes, publish feeds and answer requests from chains",
+		Args:    cobra.NoArgs,
+		PreRunE: loadGroups,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			unmarshalledTracing := &telemetry.UnmarshalledTracing{}
+			err := unmarshalKey("tracing", unmarshalledTracing)
+			if err != nil {
+				return err
+			}
+			shutdownTracing, err := unmarshalledTracing.CreateTracerProvider(ctx)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = shutdownTracing(context.Background())
+			}()
+
+			unmarshalledLog := &audit.UnmarshalledLog{}
+			err = unmarshalKey("audit", unmarshalledLog)
+			if err != nil {
+				return err
+			}
+			node.AuditLog, err = unmarshalledLog.CreateLog()
+			if err != nil {
+				return err
+			}
+			if node.AuditLog != nil {
+				defer node.AuditLog.Close()
+			}
+
+			err = createNodes()
+			if err != nil {
+				return err
+			}
+			feeds, err := createFeeds()
+			if err != nil {
+				return err
+			}
+			responders, err := createResponders(ctx)
+			if err != nil {
+				return err
+			}
+			broker := feed.NewBroker(0)
+			for _, feedOfConfig := range feeds {
+				feedOfConfig.Sinks = append(feedOfConfig.Sinks, broker)
+			}
+
+			serveMux := http.NewServeMux()
+			serveMux.Handle("/stream", broker)
+			serveMux.Handle("/metrics", promhttp.HandlerFor(node.MetricsRegistry, promhttp.HandlerOpts{}))
+			serveMux.HandleFunc("/healthz", serveHealth(node.CheckHealth))
+			serveMux.HandleFunc("/readyz", serveHealth(node.CheckReadiness))
+			serveMux.HandleFunc("/status", func(writer http.ResponseWriter, request *http.Request) {
+				writeJson(writer, http.StatusOK, node.Statuses(request.Context()))
+			})
+			server := &http.Server{
+				Addr:    listenAddress,
+				Handler: telemetry.Handler(serveMux),
+				// streams end with the serving context
+				BaseContext: func(net.Listener) context.Context { return ctx },
+			}
+			serverErr := make(chan error, 1)
+			go func() {
+				serverErr <- server.ListenAndServe()
+			}()
+			log.Info("serving", "address", listenAddress)
+
+			waitGroup := &sync.WaitGroup{}
+			waitGroup.Add(1 + len(responders))
+			go func() {
+				defer waitGroup.Done()
+				feed.NewScheduler(feeds).Run(ctx)
+			}()
+			for _, responder := range responders {
+				go func(responder *chain.Responder) {
+					defer waitGroup.Done()
+					_ = responder.Run(ctx)
+				}(responder)
+			}
+			select {
+			case <-ctx.Done():
+			case err = <-serverErr:
+				stop()
+				waitGroup.Wait()
+				return err
+			}
+			waitGroup.Wait()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err = server.Shutdown(shutdownCtx)
+			return err
+		},
+	}
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&listenAddress, "listen", "localhost:8080", "address to serve http on")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// createNodes creates nodes, and runs dkgs of groups with at least 2 nodes
+func createNodes() error {
+	unmarshalledNodes := make([]*node.UnmarshalledNode, 0)
+	err := unmarshalKey("nodes", &unmarshalledNodes)
+	if err != nil {
+		return err
+	}
+	for _, unmarshalledNode := range unmarshalledNodes {
+		if unmarshalledNode.CreateNode() == nil {
+			return CreateNodeErr
+		}
+	}
+	for _, group := range node.ListGroups() {
+		if len(group.NodeIds) < 2 {
+			log.Warn("too few nodes to run dkg", "group id", group.Id, "node count", len(group.NodeIds))
+			continue
+		}
+		err = node.RunDkg(group.Id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createFeeds() ([]*feed.Feed, error) {
+	unmarshalledFeeds := make([]*feed.UnmarshalledFeed, 0)
+	err := unmarshalKey("feeds", &unmarshalledFeeds)
+	if err != nil {
+		return nil, err
+	}
+	feeds := make([]*feed.Feed, 0, len(unmarshalledFeeds))
+	for _, unmarshalledFeed := range unmarshalledFeeds {
+		feedOfConfig, err := unmarshalledFeed.CreateFeed()
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feedOfConfig)
+	}
+	return feeds, nil
+}
+
+// createResponders creates adapters of chains, each of which is answered by a responder
+func createResponders(ctx context.Context) ([]*chain.Responder, error) {
+	unmarshalledChains := make([]*UnmarshalledChain, 0)
+	err := unmarshalKey("chains", &unmarshalledChains)
+	if err != nil {
+		return nil, err
+	}
+	responders := make([]*chain.Responder, 0, len(unmarshalledChains))
+	for _, unmarshalledChain := range unmarshalledChains {
+		var adapter chain.ChainAdapter
+		switch unmarshalledChain.Type {
+		case "ethereum":
+			adapter, err = unmarshalledChain.CreateAdapter(ctx)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			log.Error("fail to create chain adapter", "type", unmarshalledChain.Type, "err", IllegalChainTypeErr)
+			return nil, IllegalChainTypeErr
+		}
+		responder := chain.NewResponder(adapter)
+		responder.CheckRegistry = unmarshalledChain.CheckRegistry
+		responders = append(responders, responder)
+	}
+	return responders, nil
+}
+
+// serveHealth responds the result of the check, with status 503 if not ok
+func serveHealth(check func(ctx context.Context) node.Health) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		health := check(request.Context())
+		statusCode := http.StatusOK
+		if !health.Ok {
+			statusCode = http.StatusServiceUnavailable
+		}
+		writeJson(writer, statusCode, health)
+	}
+}
+
+func writeJson(writer http.ResponseWriter, statusCode int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	err := json.NewEncoder(writer).Encode(value)
+	if err != nil {
+		log.Warn("fail to write response", "err", err)
+	}
+}
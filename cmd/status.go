@@ -0,0 +1,83 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KofClubs/siwa/node"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusServer string
+
+	// statusCmd prints states of nodes run by siwa serve
+	statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Print states of nodes run by siwa serve",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			httpClient := &http.Client{Timeout: 10 * time.Second}
+			response, err := httpClient.Get(strings.TrimSuffix(statusServer, "/") + "/status")
+			if err != nil {
+				return err
+			}
+			defer response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				return fmt.Errorf("unexpected status of server: %s", response.Status)
+			}
+			statuses := make([]*node.NodeStatus, 0)
+			err = json.NewDecoder(response.Body).Decode(&statuses)
+			if err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(writer, "NODE\tGROUP\tDKG INDEX\tCERTIFIED\tQUERIER\tPUBLIC KEY")
+			for _, status := range statuses {
+				querierState := status.QuerierSource + " ok"
+				if status.QuerierErr != "" {
+					querierState = status.QuerierSource + " " + status.QuerierErr
+				}
+				publicKeyFingerprint := status.PublicKeyFingerprint
+				if publicKeyFingerprint == "" {
+					publicKeyFingerprint = "-"
+				}
+				fmt.Fprintf(writer, "%s\t%s\t%d\t%t\t%s\t%s\n", status.Id, status.GroupId, status.DkgIndex,
+					status.Certified, querierState, publicKeyFingerprint)
+			}
+			return writer.Flush()
+		},
+	}
+)
+
+func init() {
+	statusCmd.Flags().StringVar(&statusServer, "server", "http://localhost:8080", "url of siwa serve")
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,517 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+	"reflect"
+
+	bn256 "github.com/ethereum/go-ethereum/crypto/bn256/cloudflare"
+	"go.dedis.ch/fixbuf"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/group/mod"
+	"go.dedis.ch/kyber/v3/util/random"
+	"go.dedis.ch/kyber/v3/xof/blake2xb"
+)
+
+// BlsSuite is the pairing suite of alt_bn128, the curve of the pairing precompiles of ethereum, so that contracts
+// verify signatures of groups. Public keys are in G2 and signatures are in G1, points are marshalled as the input of
+// the precompiles.
+type BlsSuite struct {
+	g1, g2, gt *altBn128Group
+	// kyber.Group is G2, the group of keys
+	kyber.Group
+}
+
+func newBlsSuite() *BlsSuite {
+	suite := &BlsSuite{
+		g1: &altBn128Group{name: "alt_bn128.G1", pointLen: 64, newPoint: func() kyber.Point { return newG1Point() }},
+		g2: &altBn128Group{name: "alt_bn128.G2", pointLen: 128, newPoint: func() kyber.Point { return newG2Point() }},
+		gt: &altBn128Group{name: "alt_bn128.GT", pointLen: 384, newPoint: func() kyber.Point { return newGtPoint() }},
+	}
+	suite.Group = suite.g2
+	return suite
+}
+
+func (suite *BlsSuite) G1() kyber.Group {
+	return suite.g1
+}
+
+func (suite *BlsSuite) G2() kyber.Group {
+	return suite.g2
+}
+
+func (suite *BlsSuite) GT() kyber.Group {
+	return suite.gt
+}
+
+func (suite *BlsSuite) Pair(p1 kyber.Point, p2 kyber.Point) kyber.Point {
+	return &gtPoint{p: bn256.Pair(p1.(*g1Point).p, p2.(*g2Point).p)}
+}
+
+func (suite *BlsSuite) String() string {
+	return "alt_bn128.adapter"
+}
+
+var (
+	scalarType  = reflect.TypeOf((*kyber.Scalar)(nil)).Elem()
+	pointType   = reflect.TypeOf((*kyber.Point)(nil)).Elem()
+	g1PointType = reflect.TypeOf(g1Point{})
+	g2PointType = reflect.TypeOf(g2Point{})
+	gtPointType = reflect.TypeOf(gtPoint{})
+)
+
+func (suite *BlsSuite) New(t reflect.Type) interface{} {
+	switch t {
+	case scalarType:
+		return suite.Scalar()
+	case pointType:
+		return suite.Point()
+	case g1PointType:
+		return newG1Point()
+	case g2PointType:
+		return newG2Point()
+	case gtPointType:
+		return newGtPoint()
+	}
+	return nil
+}
+
+func (suite *BlsSuite) Read(r io.Reader, objs ...interface{}) error {
+	return fixbuf.Read(r, suite, objs...)
+}
+
+func (suite *BlsSuite) Write(w io.Writer, objs ...interface{}) error {
+	return fixbuf.Write(w, objs)
+}
+
+func (suite *BlsSuite) Hash() hash.Hash {
+	return sha256.New()
+}
+
+func (suite *BlsSuite) XOF(seed []byte) kyber.XOF {
+	return blake2xb.New(seed)
+}
+
+func (suite *BlsSuite) RandomStream() cipher.Stream {
+	return random.New()
+}
+
+type altBn128Group struct {
+	name     string
+	pointLen int
+	newPoint func() kyber.Point
+}
+
+func (group *altBn128Group) String() string {
+	return group.name
+}
+
+func (group *altBn128Group) ScalarLen() int {
+	return mod.NewInt64(0, bn256.Order).MarshalSize()
+}
+
+func (group *altBn128Group) Scalar() kyber.Scalar {
+	return mod.NewInt64(0, bn256.Order)
+}
+
+func (group *altBn128Group) PointLen() int {
+	return group.pointLen
+}
+
+func (group *altBn128Group) Point() kyber.Point {
+	return group.newPoint()
+}
+
+var (
+	unsupportedErr = errors.New("unsupported operation")
+	pointLengthErr = errors.New("illegal point length")
+)
+
+// pickScalar is a random scalar, points are picked as multiples of bases
+func pickScalar(rand cipher.Stream) *big.Int {
+	return &mod.NewInt64(0, bn256.Order).Pick(rand).(*mod.Int).V
+}
+
+func scalarOf(s kyber.Scalar) *big.Int {
+	return &s.(*mod.Int).V
+}
+
+func equalMarshalled(p, q kyber.Point) bool {
+	pData, _ := p.MarshalBinary()
+	qData, _ := q.MarshalBinary()
+	return subtle.ConstantTimeCompare(pData, qData) == 1
+}
+
+func marshalTo(p kyber.Point, w io.Writer) (int, error) {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(data)
+}
+
+func unmarshalFrom(p kyber.Point, r io.Reader) (int, error) {
+	data := make([]byte, p.MarshalSize())
+	n, err := io.ReadFull(r, data)
+	if err != nil {
+		return n, err
+	}
+	return n, p.UnmarshalBinary(data)
+}
+
+type g1Point struct {
+	p *bn256.G1
+}
+
+func newG1Point() *g1Point {
+	return &g1Point{p: new(bn256.G1).ScalarBaseMult(big.NewInt(0))}
+}
+
+func (p *g1Point) Equal(q kyber.Point) bool {
+	return equalMarshalled(p, q)
+}
+
+func (p *g1Point) Null() kyber.Point {
+	p.p.ScalarBaseMult(big.NewInt(0))
+	return p
+}
+
+func (p *g1Point) Base() kyber.Point {
+	p.p.ScalarBaseMult(big.NewInt(1))
+	return p
+}
+
+func (p *g1Point) Pick(rand cipher.Stream) kyber.Point {
+	p.p.ScalarBaseMult(pickScalar(rand))
+	return p
+}
+
+func (p *g1Point) Set(q kyber.Point) kyber.Point {
+	p.p.Set(q.(*g1Point).p)
+	return p
+}
+
+func (p *g1Point) Clone() kyber.Point {
+	return &g1Point{p: new(bn256.G1).Set(p.p)}
+}
+
+func (p *g1Point) EmbedLen() int {
+	panic(unsupportedErr)
+}
+
+func (p *g1Point) Embed(data []byte, rand cipher.Stream) kyber.Point {
+	panic(unsupportedErr)
+}
+
+func (p *g1Point) Data() ([]byte, error) {
+	return nil, unsupportedErr
+}
+
+func (p *g1Point) Add(a, b kyber.Point) kyber.Point {
+	p.p.Add(a.(*g1Point).p, b.(*g1Point).p)
+	return p
+}
+
+func (p *g1Point) Sub(a, b kyber.Point) kyber.Point {
+	negative := new(bn256.G1).Neg(b.(*g1Point).p)
+	p.p.Add(a.(*g1Point).p, negative)
+	return p
+}
+
+func (p *g1Point) Neg(a kyber.Point) kyber.Point {
+	p.p.Neg(a.(*g1Point).p)
+	return p
+}
+
+func (p *g1Point) Mul(s kyber.Scalar, q kyber.Point) kyber.Point {
+	if q == nil {
+		p.p.ScalarBaseMult(scalarOf(s))
+	} else {
+		p.p.ScalarMult(q.(*g1Point).p, scalarOf(s))
+	}
+	return p
+}
+
+// MarshalBinary is x and y in 32 bytes big endian, zeros for the infinity
+func (p *g1Point) MarshalBinary() ([]byte, error) {
+	// marshalling makes the point affine, which is not safe to share
+	return new(bn256.G1).Set(p.p).Marshal(), nil
+}
+
+func (p *g1Point) UnmarshalBinary(data []byte) error {
+	if len(data) != p.MarshalSize() {
+		return pointLengthErr
+	}
+	_, err := p.p.Unmarshal(data)
+	return err
+}
+
+func (p *g1Point) MarshalSize() int {
+	return 64
+}
+
+func (p *g1Point) MarshalTo(w io.Writer) (int, error) {
+	return marshalTo(p, w)
+}
+
+func (p *g1Point) UnmarshalFrom(r io.Reader) (int, error) {
+	return unmarshalFrom(p, r)
+}
+
+func (p *g1Point) String() string {
+	return p.p.String()
+}
+
+// Hash maps the message to G1 by try-and-increment, x starts from sha256 of the message modulo p and increments
+// until x³ + 3 is a square, y is its root (x³ + 3)^((p + 1) / 4). Contracts map messages the same way.
+func (p *g1Point) Hash(message []byte) kyber.Point {
+	digest := sha256.Sum256(message)
+	x := new(big.Int).SetBytes(digest[:])
+	x.Mod(x, bn256.P)
+	data := make([]byte, 64)
+	for {
+		t := new(big.Int).Exp(x, big.NewInt(3), bn256.P)
+		t.Add(t, curveB).Mod(t, bn256.P)
+		if y := new(big.Int).ModSqrt(t, bn256.P); y != nil {
+			x.FillBytes(data[:32])
+			y.FillBytes(data[32:])
+			if _, err := p.p.Unmarshal(data); err == nil {
+				return p
+			}
+		}
+		x.Add(x, big.NewInt(1)).Mod(x, bn256.P)
+	}
+}
+
+var curveB = big.NewInt(3)
+
+type g2Point struct {
+	p *bn256.G2
+}
+
+func newG2Point() *g2Point {
+	return &g2Point{p: new(bn256.G2).ScalarBaseMult(big.NewInt(0))}
+}
+
+func (p *g2Point) Equal(q kyber.Point) bool {
+	return equalMarshalled(p, q)
+}
+
+func (p *g2Point) Null() kyber.Point {
+	p.p.ScalarBaseMult(big.NewInt(0))
+	return p
+}
+
+func (p *g2Point) Base() kyber.Point {
+	p.p.ScalarBaseMult(big.NewInt(1))
+	return p
+}
+
+func (p *g2Point) Pick(rand cipher.Stream) kyber.Point {
+	p.p.ScalarBaseMult(pickScalar(rand))
+	return p
+}
+
+func (p *g2Point) Set(q kyber.Point) kyber.Point {
+	p.p.Set(q.(*g2Point).p)
+	return p
+}
+
+func (p *g2Point) Clone() kyber.Point {
+	return &g2Point{p: new(bn256.G2).Set(p.p)}
+}
+
+func (p *g2Point) EmbedLen() int {
+	panic(unsupportedErr)
+}
+
+func (p *g2Point) Embed(data []byte, rand cipher.Stream) kyber.Point {
+	panic(unsupportedErr)
+}
+
+func (p *g2Point) Data() ([]byte, error) {
+	return nil, unsupportedErr
+}
+
+func (p *g2Point) Add(a, b kyber.Point) kyber.Point {
+	p.p.Add(a.(*g2Point).p, b.(*g2Point).p)
+	return p
+}
+
+func (p *g2Point) Sub(a, b kyber.Point) kyber.Point {
+	negative := new(bn256.G2).Neg(b.(*g2Point).p)
+	p.p.Add(a.(*g2Point).p, negative)
+	return p
+}
+
+func (p *g2Point) Neg(a kyber.Point) kyber.Point {
+	p.p.Neg(a.(*g2Point).p)
+	return p
+}
+
+func (p *g2Point) Mul(s kyber.Scalar, q kyber.Point) kyber.Point {
+	if q == nil {
+		p.p.ScalarBaseMult(scalarOf(s))
+	} else {
+		p.p.ScalarMult(q.(*g2Point).p, scalarOf(s))
+	}
+	return p
+}
+
+// MarshalBinary is x and y in 64 bytes, the imaginary part before the real one, zeros for the infinity
+func (p *g2Point) MarshalBinary() ([]byte, error) {
+	return new(bn256.G2).Set(p.p).Marshal(), nil
+}
+
+func (p *g2Point) UnmarshalBinary(data []byte) error {
+	if len(data) != p.MarshalSize() {
+		return pointLengthErr
+	}
+	_, err := p.p.Unmarshal(data)
+	return err
+}
+
+func (p *g2Point) MarshalSize() int {
+	return 128
+}
+
+func (p *g2Point) MarshalTo(w io.Writer) (int, error) {
+	return marshalTo(p, w)
+}
+
+func (p *g2Point) UnmarshalFrom(r io.Reader) (int, error) {
+	return unmarshalFrom(p, r)
+}
+
+func (p *g2Point) String() string {
+	return p.p.String()
+}
+
+type gtPoint struct {
+	p *bn256.GT
+}
+
+func newGtPoint() *gtPoint {
+	return (&gtPoint{p: new(bn256.GT)}).Null().(*gtPoint)
+}
+
+func (p *gtPoint) Equal(q kyber.Point) bool {
+	return equalMarshalled(p, q)
+}
+
+// Null is the identity of GT, the pairing of the infinity
+func (p *gtPoint) Null() kyber.Point {
+	p.p.Set(bn256.Pair(newG1Point().p, newG2Point().Base().(*g2Point).p))
+	return p
+}
+
+func (p *gtPoint) Base() kyber.Point {
+	p.p.Set(bn256.Pair(newG1Point().Base().(*g1Point).p, newG2Point().Base().(*g2Point).p))
+	return p
+}
+
+func (p *gtPoint) Pick(rand cipher.Stream) kyber.Point {
+	p.Base()
+	p.p.ScalarMult(p.p, pickScalar(rand))
+	return p
+}
+
+func (p *gtPoint) Set(q kyber.Point) kyber.Point {
+	p.p.Set(q.(*gtPoint).p)
+	return p
+}
+
+func (p *gtPoint) Clone() kyber.Point {
+	return &gtPoint{p: new(bn256.GT).Set(p.p)}
+}
+
+func (p *gtPoint) EmbedLen() int {
+	panic(unsupportedErr)
+}
+
+func (p *gtPoint) Embed(data []byte, rand cipher.Stream) kyber.Point {
+	panic(unsupportedErr)
+}
+
+func (p *gtPoint) Data() ([]byte, error) {
+	return nil, unsupportedErr
+}
+
+// Add is the multiplication in GT
+func (p *gtPoint) Add(a, b kyber.Point) kyber.Point {
+	p.p.Add(a.(*gtPoint).p, b.(*gtPoint).p)
+	return p
+}
+
+func (p *gtPoint) Sub(a, b kyber.Point) kyber.Point {
+	negative := new(bn256.GT).Neg(b.(*gtPoint).p)
+	p.p.Add(a.(*gtPoint).p, negative)
+	return p
+}
+
+func (p *gtPoint) Neg(a kyber.Point) kyber.Point {
+	p.p.Neg(a.(*gtPoint).p)
+	return p
+}
+
+func (p *gtPoint) Mul(s kyber.Scalar, q kyber.Point) kyber.Point {
+	if q == nil {
+		q = newGtPoint().Base()
+	}
+	p.p.ScalarMult(q.(*gtPoint).p, scalarOf(s))
+	return p
+}
+
+func (p *gtPoint) MarshalBinary() ([]byte, error) {
+	return p.p.Marshal(), nil
+}
+
+func (p *gtPoint) UnmarshalBinary(data []byte) error {
+	if len(data) != p.MarshalSize() {
+		return pointLengthErr
+	}
+	_, err := p.p.Unmarshal(data)
+	return err
+}
+
+func (p *gtPoint) MarshalSize() int {
+	return 384
+}
+
+func (p *gtPoint) MarshalTo(w io.Writer) (int, error) {
+	return marshalTo(p, w)
+}
+
+func (p *gtPoint) UnmarshalFrom(r io.Reader) (int, error) {
+	return unmarshalFrom(p, r)
+}
+
+func (p *gtPoint) String() string {
+	return p.p.String()
+}
@@ -0,0 +1,65 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package crypto
+
+import (
+	"testing"
+
+	bn256 "github.com/ethereum/go-ethereum/crypto/bn256/cloudflare"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/key"
+)
+
+func TestBlsSuite(t *testing.T) {
+	suite := GetBlsSuite()
+	pair := key.NewKeyPair(suite)
+	message := []byte("1.5")
+	signature, err := bls.Sign(suite, pair.Private, message)
+	require.Nil(t, err)
+	assert.Len(t, signature, 64)
+	assert.Nil(t, bls.Verify(suite, pair.Public, message, signature))
+	assert.NotNil(t, bls.Verify(suite, pair.Public, []byte("2"), signature))
+
+	// points are marshalled as the input of the pairing precompile
+	publicKeyBytes := EncodeBlsPublicKey(pair.Public)
+	require.Len(t, publicKeyBytes, 128)
+	publicKey := new(bn256.G2)
+	_, err = publicKey.Unmarshal(publicKeyBytes)
+	require.Nil(t, err)
+	hash := suite.G1().Point().(*g1Point).Hash(message)
+	signaturePoint := suite.G1().Point()
+	require.Nil(t, signaturePoint.UnmarshalBinary(signature))
+	negative := new(bn256.G1).Neg(signaturePoint.(*g1Point).p)
+	base := new(bn256.G2).Set(suite.G2().Point().Base().(*g2Point).p)
+	assert.True(t, bn256.PairingCheck([]*bn256.G1{negative, hash.(*g1Point).p}, []*bn256.G2{base, publicKey}))
+
+	// the pairing is bilinear
+	scalar := suite.G1().Scalar().Pick(suite.RandomStream())
+	g1 := suite.G1().Point().Mul(scalar, nil)
+	left := suite.Pair(g1, suite.G2().Point().Base())
+	right := suite.Pair(suite.G1().Point().Base(), suite.G2().Point().Mul(scalar, nil))
+	assert.True(t, left.Equal(right))
+	assert.True(t, suite.GT().Point().Mul(scalar, nil).Equal(left))
+	assert.True(t, suite.GT().Point().Null().Equal(suite.Pair(suite.G1().Point().Null(), suite.G2().Point().Base())))
+}
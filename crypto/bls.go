@@ -28,14 +28,13 @@ import (
 	"github.com/MonteCarloClub/log"
 	"github.com/MonteCarloClub/utils"
 	"go.dedis.ch/kyber/v3"
-	"go.dedis.ch/kyber/v3/pairing/bn256"
 )
 
-func GetBlsSuite() *bn256.Suite {
-	return bn256.NewSuiteG2()
+func GetBlsSuite() *BlsSuite {
+	return newBlsSuite()
 }
 
-func GetBlsPrivateKey(suite *bn256.Suite, privateKeyString string) (kyber.Scalar, error) {
+func GetBlsPrivateKey(suite *BlsSuite, privateKeyString string) (kyber.Scalar, error) {
 	if suite == nil {
 		log.Error("nil suite", "err", utils.NilPtrDerefErr)
 		return nil, utils.NilPtrDerefErr
@@ -56,7 +55,7 @@ func GetBlsPrivateKey(suite *bn256.Suite, privateKeyString string) (kyber.Scalar
 	return scalar, nil
 }
 
-func GetBlsPublicKey(suite *bn256.Suite, blsPrivateKey kyber.Scalar) (kyber.Point, error) {
+func GetBlsPublicKey(suite *BlsSuite, blsPrivateKey kyber.Scalar) (kyber.Point, error) {
 	if suite == nil {
 		log.Error("nil suite", "err", utils.NilPtrDerefErr)
 		return nil, utils.NilPtrDerefErr
@@ -26,10 +26,9 @@ import (
 	"github.com/MonteCarloClub/log"
 	"github.com/MonteCarloClub/utils"
 	"go.dedis.ch/kyber/v3"
-	"go.dedis.ch/kyber/v3/pairing/bn256"
 )
 
-func DecodeBlsPublicKey(suite *bn256.Suite, data []byte) (kyber.Point, error) {
+func DecodeBlsPublicKey(suite *BlsSuite, data []byte) (kyber.Point, error) {
 	if suite == nil {
 		log.Error("nil suite", "err", utils.NilPtrDerefErr)
 		return nil, utils.NilPtrDerefErr
@@ -25,7 +25,6 @@ package crypto
 import (
 	"github.com/MonteCarloClub/log"
 	"go.dedis.ch/kyber/v3"
-	"go.dedis.ch/kyber/v3/pairing/bn256"
 	pedersendkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
 )
 
@@ -36,7 +35,7 @@ type DistributedKeyGenerator struct {
 	PedersendkgDeals map[int]*pedersendkg.Deal
 }
 
-func CreateDistributedKeyGenerator(suite *bn256.Suite, privateKey kyber.Scalar, publicKeys []kyber.Point, threshold int) (*DistributedKeyGenerator, error) {
+func CreateDistributedKeyGenerator(suite *BlsSuite, privateKey kyber.Scalar, publicKeys []kyber.Point, threshold int) (*DistributedKeyGenerator, error) {
 	pedersenDkg, err := pedersendkg.NewDistKeyGenerator(suite, privateKey, publicKeys, threshold)
 	if err != nil {
 		log.Error("fail to create pedersen distributed key generator", "err", err)
@@ -24,12 +24,11 @@ package crypto
 
 import (
 	"github.com/MonteCarloClub/log"
-	"go.dedis.ch/kyber/v3/pairing/bn256"
 	"go.dedis.ch/kyber/v3/share"
 	"go.dedis.ch/kyber/v3/sign/tbls"
 )
 
-func Sign(signerSuite *bn256.Suite, signerDkg *DistributedKeyGenerator, message string) []byte {
+func Sign(signerSuite *BlsSuite, signerDkg *DistributedKeyGenerator, message string) []byte {
 	if signerDkg == nil || signerDkg.PedersenDkg == nil {
 		log.Error("nil dkg of signer")
 		return nil
@@ -49,7 +48,7 @@ func Sign(signerSuite *bn256.Suite, signerDkg *DistributedKeyGenerator, message
 	return signatures
 }
 
-func Verify(verifierSuite *bn256.Suite, verifierDkg *DistributedKeyGenerator, message string, signature []byte) bool {
+func Verify(verifierSuite *BlsSuite, verifierDkg *DistributedKeyGenerator, message string, signature []byte) bool {
 	if verifierSuite == nil || verifierDkg == nil || verifierDkg.PedersenDkg == nil {
 		log.Error("nil suite or dkg of verifier")
 		return false
@@ -66,7 +65,7 @@ func Verify(verifierSuite *bn256.Suite, verifierDkg *DistributedKeyGenerator, me
 	return err == nil
 }
 
-func Recover(verifierSuite *bn256.Suite, verifierDkg *DistributedKeyGenerator, t, n int,
+func Recover(verifierSuite *BlsSuite, verifierDkg *DistributedKeyGenerator, t, n int,
 	message string, signatures [][]byte) ([]byte, bool) {
 	if verifierSuite == nil || verifierDkg == nil || verifierDkg.PedersenDkg == nil {
 		log.Error("nil suite or dkg of verifier")
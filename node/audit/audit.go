@@ -0,0 +1,347 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package audit keeps an append-only, hash-chained log of signed answers, and verifies it offline
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/MonteCarloClub/log"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/sign/tbls"
+)
+
+// maxEntrySize bounds a line of the log, as entries carry signatures of every node
+const maxEntrySize = 16 << 20
+
+var (
+	BrokenChainErr       = fmt.Errorf("audit log chain broken")
+	HashMismatchErr      = fmt.Errorf("hash of audit entry mismatched")
+	SignatureErr         = fmt.Errorf("signature of audit entry not verified")
+	PublicKeyMismatchErr = fmt.Errorf("public key of audit entry mismatched the trusted one")
+	UntrustedGroupErr    = fmt.Errorf("no trusted public key of the group of audit entry")
+	EntryTooLargeErr     = fmt.Errorf("audit entry too large")
+)
+
+// Answer is what a node answered, binary values are in hex
+type Answer struct {
+	NodeId     string `json:"node_id"`
+	ResultType string `json:"result_type,omitempty"`
+	// Result is the raw result of the querier before normalization
+	Result           string    `json:"result,omitempty"`
+	Message          string    `json:"message,omitempty"`
+	PartialSignature string    `json:"partial_signature,omitempty"`
+	AnsweredAt       time.Time `json:"answered_at"`
+	Err              string    `json:"error,omitempty"`
+}
+
+// Entry is a signed answer of a group, chained to the previous entry by PrevHash, binary values are in hex
+type Entry struct {
+	Sequence uint64 `json:"sequence"`
+	GroupId  string `json:"group_id"`
+	// RequestId is the id of the request on a chain answered by the entry, empty if none
+	RequestId  string `json:"request_id,omitempty"`
+	Expression string `json:"expression"`
+	Threshold  int    `json:"threshold"`
+	NodeCount  int    `json:"node_count"`
+	// Message is the normalized value agreed by Signers, and Signature is the recovered threshold signature of its
+	// payload signed with the group id, the request id and the expression
+	Message   string   `json:"message"`
+	Signature string   `json:"signature"`
+	Signers   []string `json:"signers"`
+	// PublicKey is the distributed public key of the group when signed
+	PublicKey string    `json:"public_key"`
+	Answers   []Answer  `json:"answers"`
+	QueriedAt time.Time `json:"queried_at"`
+	SignedAt  time.Time `json:"signed_at"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash,omitempty"`
+}
+
+// digest hashes the json of the entry without its hash
+func (entry Entry) digest() (string, error) {
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// UnmarshalledLog is the "audit" section of the config file
+type UnmarshalledLog struct {
+	// Path of the log, no entry is written if empty
+	Path string `yaml:"path"`
+}
+
+// CreateLog opens the log, which is nil if no path is configured
+func (unmarshalledLog *UnmarshalledLog) CreateLog() (*Log, error) {
+	if unmarshalledLog.Path == "" {
+		return nil, nil
+	}
+	return Open(unmarshalledLog.Path)
+}
+
+// Log appends entries to a file in json lines
+type Log struct {
+	mutex        sync.Mutex
+	file         *os.File
+	lastSequence uint64
+	lastHash     string
+}
+
+// Open opens the log at path, and continues the chain of existing entries, which is checked by sequences and
+// hashes. A last line not terminated was written partly when the process stopped, its report was never returned, so
+// it is truncated.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		log.Error("fail to open audit log", "path", path, "err", err)
+		return nil, err
+	}
+	auditLog := &Log{file: file}
+	state := &chainState{}
+	reader := bufio.NewReader(file)
+	size := int64(0)
+	for {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Warn("truncate partly written audit entry", "path", path, "after sequence", state.lastSequence,
+					"size", len(line))
+				err = file.Truncate(size)
+				if err != nil {
+					_ = file.Close()
+					return nil, err
+				}
+			}
+			break
+		}
+		if err == nil {
+			entry := Entry{}
+			err = json.Unmarshal(line, &entry)
+			if err == nil {
+				err = state.next(&entry)
+			}
+		}
+		if err != nil {
+			log.Error("fail to continue audit log", "path", path, "after sequence", state.lastSequence, "err", err)
+			_ = file.Close()
+			return nil, err
+		}
+		size += int64(len(line))
+	}
+	auditLog.lastSequence, auditLog.lastHash = state.lastSequence, state.lastHash
+	return auditLog, nil
+}
+
+// readLine reads a line with its newline, or what is left before io.EOF
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	line := make([]byte, 0)
+	for {
+		fragment, err := reader.ReadSlice('\n')
+		line = append(line, fragment...)
+		if len(line) > maxEntrySize {
+			return nil, EntryTooLargeErr
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// chainState is the last entry of a chain
+type chainState struct {
+	lastSequence uint64
+	lastHash     string
+}
+
+// next checks that the entry continues the chain, and moves the state to it
+func (state *chainState) next(entry *Entry) error {
+	if entry.Sequence != state.lastSequence+1 || entry.PrevHash != state.lastHash {
+		return BrokenChainErr
+	}
+	hash, err := entry.digest()
+	if err != nil {
+		return err
+	}
+	if hash != entry.Hash {
+		return HashMismatchErr
+	}
+	state.lastSequence, state.lastHash = entry.Sequence, entry.Hash
+	return nil
+}
+
+// Append chains the entry to the last one and writes it synchronously, Sequence, PrevHash and Hash are set
+func (auditLog *Log) Append(entry *Entry) error {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+
+	entry.Sequence = auditLog.lastSequence + 1
+	entry.PrevHash = auditLog.lastHash
+	hash, err := entry.digest()
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = auditLog.file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	err = auditLog.file.Sync()
+	if err != nil {
+		return err
+	}
+	auditLog.lastSequence, auditLog.lastHash = entry.Sequence, entry.Hash
+	return nil
+}
+
+func (auditLog *Log) Close() error {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+	return auditLog.file.Close()
+}
+
+// Verify checks the chain of entries read from reader, the recovered signature of every entry against its public
+// key, and partial signatures of signers against the recovered signature. The public key of every entry should be
+// the trusted one of its group, an entry of a group without a trusted public key fails. It returns the count of
+// verified entries.
+func Verify(reader io.Reader, trustedPublicKeys map[string]kyber.Point) (int, error) {
+	suite := crypto.GetBlsSuite()
+	count := 0
+	state := &chainState{}
+	scanner := newScanner(reader)
+	for scanner.Scan() {
+		entry := Entry{}
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			log.Error("fail to parse audit entry", "after sequence", state.lastSequence, "err", err)
+			return count, err
+		}
+		err = state.next(&entry)
+		if err == nil {
+			err = verifySignatures(suite, &entry, trustedPublicKeys)
+		}
+		if err != nil {
+			log.Error("fail to verify audit log", "sequence", entry.Sequence, "err", err)
+			return count, err
+		}
+		count++
+	}
+	return count, scanner.Err()
+}
+
+func verifySignatures(suite *crypto.BlsSuite, entry *Entry, trustedPublicKeys map[string]kyber.Point) error {
+	publicKeyBytes, err := hex.DecodeString(entry.PublicKey)
+	if err != nil {
+		return SignatureErr
+	}
+	publicKey, err := crypto.DecodeBlsPublicKey(suite, publicKeyBytes)
+	if err != nil {
+		return SignatureErr
+	}
+	trustedPublicKey, ok := trustedPublicKeys[entry.GroupId]
+	if !ok {
+		return UntrustedGroupErr
+	}
+	if !trustedPublicKey.Equal(publicKey) {
+		return PublicKeyMismatchErr
+	}
+	message, err := hex.DecodeString(entry.Message)
+	if err != nil {
+		return SignatureErr
+	}
+	signature, err := hex.DecodeString(entry.Signature)
+	if err != nil {
+		return SignatureErr
+	}
+	err = bls.Verify(suite, publicKey, []byte(crypto.SignedPayload(entry.GroupId, entry.RequestId, entry.Expression,
+		string(message))),
+		signature)
+	if err != nil {
+		return SignatureErr
+	}
+
+	// partial signatures of signers interpolate to the recovered signature
+	signers := make(map[string]bool)
+	for _, signer := range entry.Signers {
+		signers[signer] = true
+	}
+	pubShares := make([]*share.PubShare, 0, len(entry.Signers))
+	for _, answer := range entry.Answers {
+		if !signers[answer.NodeId] || len(pubShares) >= entry.Threshold {
+			continue
+		}
+		if answer.Message != entry.Message {
+			return SignatureErr
+		}
+		partialSignature, err := hex.DecodeString(answer.PartialSignature)
+		if err != nil {
+			return SignatureErr
+		}
+		sigShare := tbls.SigShare(partialSignature)
+		index, err := sigShare.Index()
+		if err != nil {
+			return SignatureErr
+		}
+		point := suite.G1().Point()
+		err = point.UnmarshalBinary(sigShare.Value())
+		if err != nil {
+			return SignatureErr
+		}
+		pubShares = append(pubShares, &share.PubShare{I: index, V: point})
+	}
+	if entry.Threshold <= 0 || len(pubShares) < entry.Threshold {
+		return SignatureErr
+	}
+	recovered, err := share.RecoverCommit(suite.G1(), pubShares, entry.Threshold, entry.NodeCount)
+	if err != nil {
+		return SignatureErr
+	}
+	recoveredSignature, err := recovered.MarshalBinary()
+	if err != nil || hex.EncodeToString(recoveredSignature) != entry.Signature {
+		return SignatureErr
+	}
+	return nil
+}
+
+func newScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	return scanner
+}
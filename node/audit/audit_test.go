@@ -0,0 +1,227 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package audit
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/sign/tbls"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// signedEntry signs the message by threshold of n shares of a random key, as the group would
+func signedEntry(t *testing.T, groupId string, threshold, n int, message string) (*Entry, kyber.Point) {
+	suite := crypto.GetBlsSuite()
+	priPoly := share.NewPriPoly(suite.G2(), threshold, nil, random.New())
+	pubPoly := priPoly.Commit(suite.G2().Point().Base())
+	entry := &Entry{
+		GroupId:    groupId,
+		Expression: "price",
+		Threshold:  threshold,
+		NodeCount:  n,
+		Message:    hex.EncodeToString([]byte(message)),
+		PublicKey:  hex.EncodeToString(crypto.EncodeBlsPublicKey(pubPoly.Commit())),
+		QueriedAt:  time.Now(),
+	}
+	payload := []byte(crypto.SignedPayload(entry.GroupId, entry.RequestId, entry.Expression, message))
+	partialSignatures := make([][]byte, 0, n)
+	for _, priShare := range priPoly.Shares(n) {
+		partialSignature, err := tbls.Sign(suite, priShare, payload)
+		require.Nil(t, err)
+		partialSignatures = append(partialSignatures, partialSignature)
+		nodeId := string(rune('a' + priShare.I))
+		entry.Signers = append(entry.Signers, nodeId)
+		entry.Answers = append(entry.Answers, Answer{
+			NodeId:           nodeId,
+			ResultType:       "decimal",
+			Result:           message + ".0",
+			Message:          entry.Message,
+			PartialSignature: hex.EncodeToString(partialSignature),
+			AnsweredAt:       time.Now(),
+		})
+	}
+	signature, err := tbls.Recover(suite, pubPoly, payload, partialSignatures, threshold, n)
+	require.Nil(t, err)
+	entry.Signature = hex.EncodeToString(signature)
+	entry.SignedAt = time.Now()
+	return entry, pubPoly.Commit()
+}
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := Open(path)
+	require.Nil(t, err)
+	trustedPublicKeys := make(map[string]kyber.Point)
+	entry, publicKey := signedEntry(t, "a", 2, 3, "1.5")
+	trustedPublicKeys["a"] = publicKey
+	require.Nil(t, auditLog.Append(entry))
+	assert.Equal(t, uint64(1), entry.Sequence)
+	assert.Empty(t, entry.PrevHash)
+	secondEntry, publicKey := signedEntry(t, "b", 3, 4, "2")
+	trustedPublicKeys["b"] = publicKey
+	require.Nil(t, auditLog.Append(secondEntry))
+	assert.Equal(t, entry.Hash, secondEntry.PrevHash)
+	require.Nil(t, auditLog.Close())
+
+	// the chain continues after reopening
+	auditLog, err = Open(path)
+	require.Nil(t, err)
+	thirdEntry, publicKey := signedEntry(t, "c", 2, 2, "3")
+	require.Nil(t, auditLog.Append(thirdEntry))
+	assert.Equal(t, uint64(3), thirdEntry.Sequence)
+	assert.Equal(t, secondEntry.Hash, thirdEntry.PrevHash)
+	require.Nil(t, auditLog.Close())
+
+	// every group needs a trusted public key
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	count, err := Verify(bytes.NewReader(data), trustedPublicKeys)
+	assert.Equal(t, UntrustedGroupErr, err)
+	assert.Equal(t, 2, count)
+	trustedPublicKeys["c"] = publicKey
+	count, err = Verify(bytes.NewReader(data), trustedPublicKeys)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+	trustedPublicKeys["b"] = publicKey
+	count, err = Verify(bytes.NewReader(data), trustedPublicKeys)
+	assert.Equal(t, PublicKeyMismatchErr, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := Open(path)
+	require.Nil(t, err)
+	entry, _ := signedEntry(t, "group", 2, 3, "1")
+	require.Nil(t, auditLog.Append(entry))
+	require.Nil(t, auditLog.Close())
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	// a partly written entry is truncated, and the chain continues after the last complete one
+	secondEntry, _ := signedEntry(t, "group", 2, 3, "2")
+	secondData, err := json.Marshal(secondEntry)
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(path, append(data, secondData[:len(secondData)/2]...), 0644))
+	auditLog, err = Open(path)
+	require.Nil(t, err)
+	thirdEntry, _ := signedEntry(t, "other", 2, 3, "3")
+	require.Nil(t, auditLog.Append(thirdEntry))
+	assert.Equal(t, uint64(2), thirdEntry.Sequence)
+	assert.Equal(t, entry.Hash, thirdEntry.PrevHash)
+	require.Nil(t, auditLog.Close())
+	data, err = os.ReadFile(path)
+	require.Nil(t, err)
+	lines := strings.SplitAfter(string(data), "\n")
+	require.Len(t, lines, 3)
+	assert.Empty(t, lines[2])
+
+	// a broken chain is not continued
+	tampered := strings.Replace(lines[0], `"expression":"price"`, `"expression":"forged"`, 1)
+	require.Nil(t, os.WriteFile(path, []byte(tampered+lines[1]), 0644))
+	_, err = Open(path)
+	assert.Equal(t, HashMismatchErr, err)
+	require.Nil(t, os.WriteFile(path, []byte(lines[1]), 0644))
+	_, err = Open(path)
+	assert.Equal(t, BrokenChainErr, err)
+	require.Nil(t, os.WriteFile(path, []byte("not json\n"+lines[0]), 0644))
+	_, err = Open(path)
+	assert.NotNil(t, err)
+}
+
+func TestVerifyTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := Open(path)
+	require.Nil(t, err)
+	entries := make([]*Entry, 0)
+	trustedPublicKeys := make(map[string]kyber.Point)
+	for _, message := range []string{"1", "2", "3"} {
+		entry, publicKey := signedEntry(t, message, 2, 3, message)
+		require.Nil(t, auditLog.Append(entry))
+		entries = append(entries, entry)
+		trustedPublicKeys[message] = publicKey
+	}
+	require.Nil(t, auditLog.Close())
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	// an edited entry
+	tampered := strings.Replace(lines[1], `"expression":"price"`, `"expression":"forged"`, 1)
+	count, err := Verify(strings.NewReader(lines[0]+tampered+lines[2]), trustedPublicKeys)
+	assert.Equal(t, HashMismatchErr, err)
+	assert.Equal(t, 1, count)
+
+	// a deleted entry
+	count, err = Verify(strings.NewReader(lines[0]+lines[2]), trustedPublicKeys)
+	assert.Equal(t, BrokenChainErr, err)
+	assert.Equal(t, 1, count)
+
+	// a rewritten entry with its hash recomputed breaks the chain after it
+	rewritten := *entries[1]
+	rewritten.QueriedAt = rewritten.QueriedAt.Add(-time.Hour)
+	rewritten.Hash, err = rewritten.digest()
+	require.Nil(t, err)
+	var buffer bytes.Buffer
+	buffer.WriteString(lines[0])
+	require.Nil(t, json.NewEncoder(&buffer).Encode(rewritten))
+	buffer.WriteString(lines[2])
+	count, err = Verify(&buffer, trustedPublicKeys)
+	assert.Equal(t, BrokenChainErr, err)
+	assert.Equal(t, 2, count)
+
+	// a forged signature with a consistent chain
+	forged, publicKey := signedEntry(t, "forged", 2, 3, "1")
+	trustedPublicKeys["forged"] = publicKey
+	otherEntry, _ := signedEntry(t, "forged", 2, 3, "1")
+	forged.Signature = otherEntry.Signature
+	forged.Sequence = 1
+	forged.Hash, err = forged.digest()
+	require.Nil(t, err)
+	buffer.Reset()
+	require.Nil(t, json.NewEncoder(&buffer).Encode(forged))
+	_, err = Verify(&buffer, trustedPublicKeys)
+	assert.Equal(t, SignatureErr, err)
+
+	// a partial signature of another message
+	forged, trustedPublicKeys["forged"] = signedEntry(t, "forged", 2, 3, "1")
+	forged.Answers[0].PartialSignature = otherEntry.Answers[0].PartialSignature
+	forged.Sequence = 1
+	forged.Hash, err = forged.digest()
+	require.Nil(t, err)
+	buffer.Reset()
+	require.Nil(t, json.NewEncoder(&buffer).Encode(forged))
+	_, err = Verify(&buffer, trustedPublicKeys)
+	assert.Equal(t, SignatureErr, err)
+}
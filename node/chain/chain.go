@@ -0,0 +1,172 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package chain answers requests from chains by threshold signed reports of groups, independent of any chain
+package chain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KofClubs/siwa/node"
+	"github.com/KofClubs/siwa/node/telemetry"
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	"go.dedis.ch/kyber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestBufferSize = 64
+
+var tracer = otel.Tracer("github.com/KofClubs/siwa/node/chain")
+
+var (
+	GroupNotRegisteredErr = fmt.Errorf("group not registered")
+	RegistryMismatchErr   = fmt.Errorf("group in registry mismatches local group")
+)
+
+// Request asks a group to answer an expression, Id is unique on the chain of the request
+type Request struct {
+	Id         string
+	GroupId    string
+	Expression string
+	Requester  string
+	// Raw is the request in the form of the chain
+	Raw interface{}
+}
+
+// RegisteredGroup is a group published to the registry on a chain
+type RegisteredGroup struct {
+	Id        string
+	Version   uint64
+	Threshold int
+	// NodeIds lists node ids by dkg indices
+	NodeIds              []string
+	PublicKeys           map[string]kyber.Point
+	DistributedPublicKey kyber.Point
+}
+
+// ChainAdapter connects the request and response pipeline of nodes to a chain
+type ChainAdapter interface {
+	// Subscribe sends requests on the chain to requests until ctx is done
+	Subscribe(ctx context.Context, requests chan<- *Request) error
+	// Submit sends the report answering the request to the chain, and returns the id of the submission
+	Submit(ctx context.Context, request *Request, report *node.Report) (string, error)
+	// GetGroup reads the group from the registry on the chain, or returns GroupNotRegisteredErr
+	GetGroup(ctx context.Context, groupId string) (*RegisteredGroup, error)
+}
+
+// Responder answers requests subscribed from the chain adapter by reports of groups
+type Responder struct {
+	Adapter ChainAdapter
+	// CheckRegistry refuses to answer by a group whose distributed public key differs from the registry
+	CheckRegistry bool
+
+	answerRequest  func(ctx context.Context, groupId, requestId, expression string) (*node.Report, error)
+	groupPublicKey func(groupId string) (kyber.Point, error)
+}
+
+func NewResponder(adapter ChainAdapter) *Responder {
+	return &Responder{
+		Adapter:        adapter,
+		answerRequest:  node.AnswerRequest,
+		groupPublicKey: node.GroupPublicKey,
+	}
+}
+
+// Run answers requests until ctx is done, a request failed to answer is subscribed again by the adapter if it retries
+func (responder *Responder) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	requests := make(chan *Request, requestBufferSize)
+	subscribeErr := make(chan error, 1)
+	go func() {
+		subscribeErr <- responder.Adapter.Subscribe(ctx, requests)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-subscribeErr:
+			if err != nil {
+				log.Error("fail to subscribe requests", "err", err)
+			}
+			return err
+		case request := <-requests:
+			_, err := responder.Respond(ctx, request)
+			if err != nil {
+				log.Error("fail to answer request", "id", request.Id, "group id", request.GroupId,
+					"expression", request.Expression, "err", err)
+			}
+		}
+	}
+}
+
+// Respond queries the expression of the request by its group, and submits the report to the chain
+func (responder *Responder) Respond(ctx context.Context, request *Request) (submissionId string, err error) {
+	if request == nil {
+		log.Error("nil request", "err", utils.NilPtrDerefErr)
+		return "", utils.NilPtrDerefErr
+	}
+	ctx, span := tracer.Start(ctx, "Responder.Respond", trace.WithAttributes(
+		attribute.String("siwa.request_id", request.Id), attribute.String("siwa.group_id", request.GroupId),
+		attribute.String("siwa.expression", request.Expression)))
+	defer func() {
+		telemetry.End(span, err)
+	}()
+	if responder.CheckRegistry {
+		err = responder.checkRegistry(ctx, request.GroupId)
+		if err != nil {
+			return "", err
+		}
+	}
+	report, err := responder.answerRequest(ctx, request.GroupId, request.Id, request.Expression)
+	if err != nil {
+		return "", err
+	}
+	submissionId, err = responder.Adapter.Submit(ctx, request, report)
+	if err != nil {
+		return "", err
+	}
+	log.Info("request answered", "id", request.Id, "group id", request.GroupId, "expression", request.Expression,
+		"submission id", submissionId)
+	return submissionId, nil
+}
+
+func (responder *Responder) checkRegistry(ctx context.Context, groupId string) error {
+	registeredGroup, err := responder.Adapter.GetGroup(ctx, groupId)
+	if err != nil {
+		log.Error("fail to read group from registry", "group id", groupId, "err", err)
+		return err
+	}
+	groupPublicKey, err := responder.groupPublicKey(groupId)
+	if err != nil {
+		return err
+	}
+	if registeredGroup.DistributedPublicKey == nil || !groupPublicKey.Equal(registeredGroup.DistributedPublicKey) {
+		log.Error("fail to check group in registry", "group id", groupId, "err", RegistryMismatchErr)
+		return RegistryMismatchErr
+	}
+	return nil
+}
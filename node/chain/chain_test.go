@@ -0,0 +1,114 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package chain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/KofClubs/siwa/node"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+)
+
+var errNotExisted = fmt.Errorf("not existed")
+
+func newTestResponder(mockAdapter *MockAdapter, groupPublicKey kyber.Point) *Responder {
+	responder := NewResponder(mockAdapter)
+	responder.answerRequest = func(_ context.Context, groupId, requestId, expression string) (*node.Report, error) {
+		if expression == "not existed" {
+			return nil, errNotExisted
+		}
+		return &node.Report{GroupId: groupId, RequestId: requestId, Expression: expression,
+			Message: expression + " answered"}, nil
+	}
+	responder.groupPublicKey = func(string) (kyber.Point, error) {
+		return groupPublicKey, nil
+	}
+	return responder
+}
+
+func TestResponder(t *testing.T) {
+	mockAdapter := NewMockAdapter()
+	responder := newTestResponder(mockAdapter, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- responder.Run(ctx)
+	}()
+
+	// a request failed to answer is skipped
+	mockAdapter.Request("group", "not existed")
+	request := mockAdapter.Request("group", "price")
+	require.Eventually(t, func() bool {
+		return len(mockAdapter.Responses()) == 1
+	}, time.Second, 10*time.Millisecond)
+	response := mockAdapter.Responses()[0]
+	assert.Equal(t, request, response.Request)
+	assert.Equal(t, "2", response.Request.Id)
+	assert.Equal(t, "price answered", response.Report.Message)
+
+	cancel()
+	select {
+	case err := <-runErr:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "responder not stopped")
+	}
+
+	// a failed submission is returned
+	mockAdapter.SubmitErr = errNotExisted
+	_, err := responder.Respond(context.Background(), request)
+	assert.Equal(t, errNotExisted, err)
+	_, err = responder.Respond(context.Background(), nil)
+	assert.NotNil(t, err)
+}
+
+func TestResponderCheckRegistry(t *testing.T) {
+	ctx := context.Background()
+	suite := crypto.GetBlsSuite()
+	groupPublicKey := key.NewKeyPair(suite).Public
+	mockAdapter := NewMockAdapter()
+	responder := newTestResponder(mockAdapter, groupPublicKey)
+	responder.CheckRegistry = true
+	request := &Request{Id: "1", GroupId: "group", Expression: "price"}
+
+	_, err := responder.Respond(ctx, request)
+	assert.Equal(t, GroupNotRegisteredErr, err)
+
+	mockAdapter.Register(&RegisteredGroup{Id: "group", DistributedPublicKey: key.NewKeyPair(suite).Public})
+	_, err = responder.Respond(ctx, request)
+	assert.Equal(t, RegistryMismatchErr, err)
+
+	mockAdapter.Register(&RegisteredGroup{Id: "group", DistributedPublicKey: groupPublicKey})
+	registeredGroup, err := mockAdapter.GetGroup(ctx, "group")
+	require.Nil(t, err)
+	assert.Equal(t, uint64(2), registeredGroup.Version)
+	submissionId, err := responder.Respond(ctx, request)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", submissionId)
+}
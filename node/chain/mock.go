@@ -0,0 +1,126 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package chain
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/KofClubs/siwa/node"
+)
+
+// Response is a report submitted to MockAdapter
+type Response struct {
+	Request *Request
+	Report  *node.Report
+}
+
+// MockAdapter is an in-memory chain, whose requests are added by Request and responses are kept in order
+type MockAdapter struct {
+	mutex     sync.Mutex
+	lastId    int
+	pending   chan *Request
+	responses []*Response
+	groups    map[string]*RegisteredGroup
+	// SubmitErr is returned by Submit if not nil
+	SubmitErr error
+}
+
+func NewMockAdapter() *MockAdapter {
+	return &MockAdapter{
+		pending: make(chan *Request, requestBufferSize),
+		groups:  make(map[string]*RegisteredGroup),
+	}
+}
+
+// Request adds a request, which is sent to the subscriber
+func (mockAdapter *MockAdapter) Request(groupId, expression string) *Request {
+	mockAdapter.mutex.Lock()
+	mockAdapter.lastId++
+	request := &Request{
+		Id:         strconv.Itoa(mockAdapter.lastId),
+		GroupId:    groupId,
+		Expression: expression,
+		Requester:  "mock",
+	}
+	mockAdapter.mutex.Unlock()
+	mockAdapter.pending <- request
+	return request
+}
+
+func (mockAdapter *MockAdapter) Subscribe(ctx context.Context, requests chan<- *Request) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case request := <-mockAdapter.pending:
+			select {
+			case <-ctx.Done():
+				return nil
+			case requests <- request:
+			}
+		}
+	}
+}
+
+func (mockAdapter *MockAdapter) Submit(_ context.Context, request *Request, report *node.Report) (string, error) {
+	mockAdapter.mutex.Lock()
+	defer mockAdapter.mutex.Unlock()
+	if mockAdapter.SubmitErr != nil {
+		return "", mockAdapter.SubmitErr
+	}
+	mockAdapter.responses = append(mockAdapter.responses, &Response{Request: request, Report: report})
+	return strconv.Itoa(len(mockAdapter.responses)), nil
+}
+
+// Responses returns submitted responses in order
+func (mockAdapter *MockAdapter) Responses() []*Response {
+	mockAdapter.mutex.Lock()
+	defer mockAdapter.mutex.Unlock()
+	responses := make([]*Response, len(mockAdapter.responses))
+	copy(responses, mockAdapter.responses)
+	return responses
+}
+
+// Register publishes the group to the registry, the version is increased
+func (mockAdapter *MockAdapter) Register(group *RegisteredGroup) {
+	mockAdapter.mutex.Lock()
+	defer mockAdapter.mutex.Unlock()
+	registeredGroup := *group
+	if existedGroup, ok := mockAdapter.groups[group.Id]; ok {
+		registeredGroup.Version = existedGroup.Version + 1
+	} else {
+		registeredGroup.Version = 1
+	}
+	mockAdapter.groups[group.Id] = &registeredGroup
+}
+
+func (mockAdapter *MockAdapter) GetGroup(_ context.Context, groupId string) (*RegisteredGroup, error) {
+	mockAdapter.mutex.Lock()
+	defer mockAdapter.mutex.Unlock()
+	registeredGroup, ok := mockAdapter.groups[groupId]
+	if !ok {
+		return nil, GroupNotRegisteredErr
+	}
+	return registeredGroup, nil
+}
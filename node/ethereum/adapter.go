@@ -0,0 +1,347 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/KofClubs/siwa/node"
+	"github.com/KofClubs/siwa/node/chain"
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	DefaultPollInterval  = time.Second
+	DefaultRetryInterval = time.Minute
+	DefaultMaxAttempts   = 5
+)
+
+var (
+	IllegalContractAddressErr = fmt.Errorf("illegal contract address")
+	IllegalRequestIdErr       = fmt.Errorf("illegal request id")
+	NoRegistryErr             = fmt.Errorf("registry contract not configured")
+)
+
+type UnmarshalledAdapter struct {
+	GroupId string `yaml:"group_id"`
+	// Url of the json-rpc endpoint
+	Url             string `yaml:"url"`
+	ContractAddress string `yaml:"contract_address"`
+	// RegistryAddress of the group registry contract, optional
+	RegistryAddress string `yaml:"registry_address"`
+	// PrivateKey in hex of the account sending fulfilment transactions, which pays for gas only, since fulfilments
+	// are verified by the signature of the group
+	PrivateKey string `yaml:"private_key"`
+	// FromBlock is the first block watched
+	FromBlock uint64 `yaml:"from_block"`
+	// Confirmations is count of blocks after a request before it is fulfilled
+	Confirmations uint64 `yaml:"confirmations"`
+	// PollInterval is DefaultPollInterval if not positive
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// RetryInterval is how long a request is waited to be fulfilled before it is sent again, DefaultRetryInterval if
+	// not positive
+	RetryInterval time.Duration `yaml:"retry_interval"`
+	// MaxAttempts is how many times a request is sent before it is dropped, DefaultMaxAttempts if not positive
+	MaxAttempts int `yaml:"max_attempts"`
+}
+
+// pendingRequest is a request sent to the responder but not seen fulfilled yet
+type pendingRequest struct {
+	oracleRequest *OracleRequest
+	attempts      int
+	sentAt        time.Time
+}
+
+// Adapter watches requests of the oracle contract of the group, and fulfils them by reports, as a chain.ChainAdapter.
+// Requests are sent again until fulfilled on the chain, at most MaxAttempts times. Pending requests are kept in
+// memory, a restarted adapter scans again from the configured first block and skips requests already fulfilled.
+type Adapter struct {
+	GroupId      string
+	Address      common.Address
+	Backend      bind.ContractBackend
+	Contract     *OracleContract
+	TransactOpts *bind.TransactOpts
+	// Registry is nil if the registry contract is not configured
+	Registry      *Registry
+	Confirmations uint64
+	PollInterval  time.Duration
+	RetryInterval time.Duration
+	MaxAttempts   int
+
+	nextBlock uint64
+	// pending requests by ids, only accessed by Poll
+	pending map[string]*pendingRequest
+	now     func() time.Time
+}
+
+var _ chain.ChainAdapter = (*Adapter)(nil)
+
+func (unmarshalledAdapter *UnmarshalledAdapter) CreateAdapter(ctx context.Context) (*Adapter, error) {
+	if !common.IsHexAddress(unmarshalledAdapter.ContractAddress) {
+		log.Error("fail to create ethereum adapter", "contract address", unmarshalledAdapter.ContractAddress,
+			"err", IllegalContractAddressErr)
+		return nil, IllegalContractAddressErr
+	}
+	if unmarshalledAdapter.RegistryAddress != "" && !common.IsHexAddress(unmarshalledAdapter.RegistryAddress) {
+		log.Error("fail to create ethereum adapter", "registry address", unmarshalledAdapter.RegistryAddress,
+			"err", IllegalContractAddressErr)
+		return nil, IllegalContractAddressErr
+	}
+	privateKey, err := crypto.HexToECDSA(unmarshalledAdapter.PrivateKey)
+	if err != nil {
+		log.Error("fail to parse private key of oracle account", "err", err)
+		return nil, err
+	}
+	client, err := ethclient.DialContext(ctx, unmarshalledAdapter.Url)
+	if err != nil {
+		log.Error("fail to dial ethereum", "url", unmarshalledAdapter.Url, "err", err)
+		return nil, err
+	}
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		log.Error("fail to get chain id", "url", unmarshalledAdapter.Url, "err", err)
+		client.Close()
+		return nil, err
+	}
+	transactOpts, err := bind.NewKeyedTransactorWithChainID(privateKey, chainId)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	adapter, err := NewAdapter(client, common.HexToAddress(unmarshalledAdapter.ContractAddress), transactOpts,
+		unmarshalledAdapter.GroupId)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	if unmarshalledAdapter.RegistryAddress != "" {
+		adapter.Registry, err = NewRegistry(client, common.HexToAddress(unmarshalledAdapter.RegistryAddress),
+			transactOpts)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	adapter.nextBlock = unmarshalledAdapter.FromBlock
+	adapter.Confirmations = unmarshalledAdapter.Confirmations
+	if unmarshalledAdapter.PollInterval > 0 {
+		adapter.PollInterval = unmarshalledAdapter.PollInterval
+	}
+	if unmarshalledAdapter.RetryInterval > 0 {
+		adapter.RetryInterval = unmarshalledAdapter.RetryInterval
+	}
+	if unmarshalledAdapter.MaxAttempts > 0 {
+		adapter.MaxAttempts = unmarshalledAdapter.MaxAttempts
+	}
+	return adapter, nil
+}
+
+func NewAdapter(backend bind.ContractBackend, address common.Address, transactOpts *bind.TransactOpts,
+	groupId string) (*Adapter, error) {
+	contract, err := NewOracleContract(address, backend)
+	if err != nil {
+		log.Error("fail to bind oracle contract", "contract address", address, "err", err)
+		return nil, err
+	}
+	return &Adapter{
+		GroupId:       groupId,
+		Address:       address,
+		Backend:       backend,
+		Contract:      contract,
+		TransactOpts:  transactOpts,
+		PollInterval:  DefaultPollInterval,
+		RetryInterval: DefaultRetryInterval,
+		MaxAttempts:   DefaultMaxAttempts,
+		pending:       make(map[string]*pendingRequest),
+		now:           time.Now,
+	}, nil
+}
+
+// Subscribe polls requests until ctx is done
+func (adapter *Adapter) Subscribe(ctx context.Context, requests chan<- *chain.Request) error {
+	ticker := time.NewTicker(adapter.PollInterval)
+	defer ticker.Stop()
+	for {
+		oracleRequests, err := adapter.Poll(ctx)
+		if err != nil {
+			log.Warn("fail to poll oracle requests", "contract address", adapter.Address, "err", err)
+		}
+		for _, oracleRequest := range oracleRequests {
+			select {
+			case <-ctx.Done():
+				return nil
+			case requests <- adapter.toRequest(oracleRequest):
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll returns requests to answer, which are new requests in blocks confirmed since the last poll, and pending
+// requests not fulfilled for RetryInterval since they were sent
+func (adapter *Adapter) Poll(ctx context.Context) ([]*OracleRequest, error) {
+	err := adapter.scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := adapter.now()
+	oracleRequests := make([]*OracleRequest, 0)
+	for id, pending := range adapter.pending {
+		if pending.attempts > 0 {
+			if now.Sub(pending.sentAt) < adapter.RetryInterval {
+				continue
+			}
+			fulfilled, err := adapter.Contract.Fulfilled(&bind.CallOpts{Context: ctx}, pending.oracleRequest.Id)
+			if err != nil {
+				log.Warn("fail to check oracle request", "id", id, "err", err)
+				continue
+			}
+			if fulfilled {
+				delete(adapter.pending, id)
+				continue
+			}
+			if pending.attempts >= adapter.MaxAttempts {
+				log.Error("oracle request dropped", "id", id, "expression", pending.oracleRequest.Expression,
+					"attempts", pending.attempts)
+				delete(adapter.pending, id)
+				continue
+			}
+			log.Warn("oracle request not fulfilled, retry", "id", id, "attempts", pending.attempts)
+		}
+		pending.attempts++
+		pending.sentAt = now
+		oracleRequests = append(oracleRequests, pending.oracleRequest)
+	}
+	sort.Slice(oracleRequests, func(i, j int) bool {
+		return oracleRequests[i].Id.Cmp(oracleRequests[j].Id) < 0
+	})
+	return oracleRequests, nil
+}
+
+// scan adds requests in blocks confirmed since the last scan to pending ones, except those already fulfilled
+func (adapter *Adapter) scan(ctx context.Context) error {
+	header, err := adapter.Backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	head := header.Number.Uint64()
+	if head < adapter.Confirmations || head-adapter.Confirmations < adapter.nextBlock {
+		return nil
+	}
+	toBlock := head - adapter.Confirmations
+
+	iterator, err := adapter.Contract.FilterOracleRequest(&bind.FilterOpts{
+		Start:   adapter.nextBlock,
+		End:     &toBlock,
+		Context: ctx,
+	}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+	for iterator.Next() {
+		oracleRequest := iterator.Event
+		if oracleRequest.Raw.Removed {
+			continue
+		}
+		id := oracleRequest.Id.String()
+		if _, ok := adapter.pending[id]; ok {
+			continue
+		}
+		// requests scanned again after a restart may be fulfilled
+		fulfilled, err := adapter.Contract.Fulfilled(&bind.CallOpts{Context: ctx}, oracleRequest.Id)
+		if err != nil {
+			return err
+		}
+		if !fulfilled {
+			adapter.pending[id] = &pendingRequest{oracleRequest: oracleRequest}
+		}
+	}
+	if err = iterator.Error(); err != nil {
+		return err
+	}
+	adapter.nextBlock = toBlock + 1
+	return nil
+}
+
+// Submit sends the agreed message with the threshold signature to the contract, which verifies the signature by the
+// distributed public key of the group, and returns the transaction hash
+func (adapter *Adapter) Submit(ctx context.Context, request *chain.Request, report *node.Report) (string, error) {
+	if request == nil || report == nil {
+		log.Error("nil request or report", "err", utils.NilPtrDerefErr)
+		return "", utils.NilPtrDerefErr
+	}
+	id, ok := new(big.Int).SetString(request.Id, 10)
+	if !ok {
+		log.Error("fail to fulfill oracle request", "id", request.Id, "err", IllegalRequestIdErr)
+		return "", IllegalRequestIdErr
+	}
+	transactOpts := *adapter.TransactOpts
+	transactOpts.Context = ctx
+	transaction, err := adapter.Contract.Fulfill(&transactOpts, id, request.Expression, []byte(report.Message),
+		report.Signature)
+	if err != nil {
+		return "", err
+	}
+	log.Info("oracle request fulfillment sent", "id", request.Id, "expression", request.Expression, "transaction",
+		transaction.Hash())
+	return transaction.Hash().Hex(), nil
+}
+
+// GetGroup reads the group from the registry contract
+func (adapter *Adapter) GetGroup(ctx context.Context, groupId string) (*chain.RegisteredGroup, error) {
+	if adapter.Registry == nil {
+		log.Error("fail to read group from registry", "group id", groupId, "err", NoRegistryErr)
+		return nil, NoRegistryErr
+	}
+	registeredGroup, err := adapter.Registry.GetGroup(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+	return &registeredGroup.RegisteredGroup, nil
+}
+
+func (adapter *Adapter) toRequest(oracleRequest *OracleRequest) *chain.Request {
+	return &chain.Request{
+		Id:         oracleRequest.Id.String(),
+		GroupId:    adapter.GroupId,
+		Expression: oracleRequest.Expression,
+		Requester:  oracleRequest.Requester.Hex(),
+		Raw:        oracleRequest,
+	}
+}
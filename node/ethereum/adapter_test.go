@@ -0,0 +1,240 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ethereum
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	siwacrypto "github.com/KofClubs/siwa/crypto"
+	"github.com/KofClubs/siwa/node"
+	"github.com/KofClubs/siwa/node/chain"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/key"
+)
+
+func genRandomPrivateKey() string {
+	privateKeyBytes, _ := key.NewKeyPair(siwacrypto.GetBlsSuite()).Private.MarshalBinary()
+	return hex.EncodeToString(privateKeyBytes)
+}
+
+// createGroup creates a group of nodes querying the same sqlite query, and runs its dkg
+func createGroup(t *testing.T, nodeCount int) *node.Group {
+	group, err := node.CreateGroup("", node.ThresholdPolicy{}, node.JoinSequenceIndex)
+	require.Nil(t, err)
+	for i := 0; i < nodeCount; i++ {
+		groupNode := (&node.UnmarshalledNode{
+			GroupId:    group.Id,
+			PrivateKey: genRandomPrivateKey(),
+			UnmarshalledQuerier: node.UnmarshalledQuerier{
+				QuerierSource: "sql",
+				QuerierConfig: map[string]interface{}{
+					"driver":  "sqlite3",
+					"dsn":     ":memory:",
+					"queries": map[string]interface{}{"price": "SELECT 1.5"},
+				},
+			},
+		}).CreateNode()
+		require.NotNil(t, groupNode)
+	}
+	require.Nil(t, node.RunDkg(group.Id))
+	return group
+}
+
+func newTransactOpts(t *testing.T) *bind.TransactOpts {
+	privateKey, err := crypto.GenerateKey()
+	require.Nil(t, err)
+	transactOpts, err := bind.NewKeyedTransactorWithChainID(privateKey, big.NewInt(1337))
+	require.Nil(t, err)
+	return transactOpts
+}
+
+func TestAdapter(t *testing.T) {
+	ctx := context.Background()
+	group, otherGroup := createGroup(t, 3), createGroup(t, 2)
+	groupPublicKey, err := node.GroupPublicKey(group.Id)
+	require.Nil(t, err)
+
+	ownerOpts, requesterOpts := newTransactOpts(t), newTransactOpts(t)
+	balance := new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		ownerOpts.From:     {Balance: balance},
+		requesterOpts.From: {Balance: balance},
+	}, 30_000_000)
+	defer backend.Close()
+
+	// 1. deploy the contract with the distributed public key of the group, and request by another account
+	address, _, err := DeployOracle(ownerOpts, backend, group.Id, groupPublicKey)
+	require.Nil(t, err)
+	backend.Commit()
+	contract, err := NewOracleContract(address, backend)
+	require.Nil(t, err)
+	owner, err := contract.Owner(nil)
+	require.Nil(t, err)
+	assert.Equal(t, ownerOpts.From, owner)
+	encodedPublicKey, err := EncodeGroupPublicKey(groupPublicKey)
+	require.Nil(t, err)
+	storedPublicKey, err := contract.GetGroupPublicKey(nil)
+	require.Nil(t, err)
+	assert.Equal(t, encodedPublicKey, storedPublicKey)
+
+	_, err = contract.Request(requesterOpts, "price")
+	require.Nil(t, err)
+	_, err = contract.Request(requesterOpts, "not existed")
+	require.Nil(t, err)
+	backend.Commit()
+
+	// 2. poll and fulfil, the request of an expression not existed fails
+	adapter, err := NewAdapter(backend, address, ownerOpts, group.Id)
+	require.Nil(t, err)
+	now := time.Now()
+	adapter.now = func() time.Time {
+		return now
+	}
+	responder := chain.NewResponder(adapter)
+	oracleRequests, err := adapter.Poll(ctx)
+	require.Nil(t, err)
+	require.Len(t, oracleRequests, 2)
+	_, err = responder.Respond(ctx, adapter.toRequest(oracleRequests[0]))
+	assert.Nil(t, err)
+	_, err = responder.Respond(ctx, adapter.toRequest(oracleRequests[1]))
+	assert.NotNil(t, err)
+	backend.Commit()
+	oracleRequests, err = adapter.Poll(ctx)
+	require.Nil(t, err)
+	assert.Empty(t, oracleRequests)
+
+	iterator, err := contract.FilterOracleFulfillment(&bind.FilterOpts{Context: ctx}, nil)
+	require.Nil(t, err)
+	require.True(t, iterator.Next())
+	fulfillment := iterator.Event
+	assert.False(t, iterator.Next())
+	require.Nil(t, iterator.Close())
+	assert.Equal(t, int64(1), fulfillment.Id.Int64())
+	assert.Equal(t, "1.5", string(fulfillment.Message))
+
+	// 3. the threshold signature is of the payload bound to the request, as the contract builds it
+	payload := siwacrypto.SignedPayload(group.Id, "1", "price", string(fulfillment.Message))
+	assert.Nil(t, bls.Verify(siwacrypto.GetBlsSuite(), groupPublicKey, []byte(payload), fulfillment.Signature))
+	contractPayload, err := contract.SignedPayload(nil, big.NewInt(1), "price", fulfillment.Message)
+	require.Nil(t, err)
+	assert.Equal(t, payload, string(contractPayload))
+
+	fulfilled, err := contract.Fulfilled(nil, big.NewInt(1))
+	require.Nil(t, err)
+	assert.True(t, fulfilled)
+	fulfilled, err = contract.Fulfilled(nil, big.NewInt(2))
+	require.Nil(t, err)
+	assert.False(t, fulfilled)
+
+	// 4. the contract only accepts a signature of the group for the request, once, by any account
+	_, err = contract.Request(requesterOpts, "price")
+	require.Nil(t, err)
+	backend.Commit()
+	request := &chain.Request{Id: "1", GroupId: group.Id, Expression: "price"}
+	_, err = responder.Respond(ctx, request)
+	assert.NotNil(t, err)
+	request.Id = "3"
+	report := &node.Report{Message: string(fulfillment.Message), Signature: fulfillment.Signature}
+	_, err = adapter.Submit(ctx, request, report)
+	assert.NotNil(t, err)
+	report, err = node.AnswerRequest(ctx, otherGroup.Id, "3", "price")
+	require.Nil(t, err)
+	_, err = adapter.Submit(ctx, request, report)
+	assert.NotNil(t, err)
+	report, err = node.AnswerRequest(ctx, group.Id, "3", "price")
+	require.Nil(t, err)
+	_, err = adapter.Submit(ctx, &chain.Request{Id: "3", GroupId: group.Id, Expression: "other"}, report)
+	assert.NotNil(t, err)
+	requesterAdapter, err := NewAdapter(backend, address, requesterOpts, group.Id)
+	require.Nil(t, err)
+	_, err = chain.NewResponder(requesterAdapter).Respond(ctx, request)
+	assert.Nil(t, err)
+	backend.Commit()
+	request.Id = "4"
+	_, err = responder.Respond(ctx, request)
+	assert.NotNil(t, err)
+	request.Id = "not a number"
+	_, err = responder.Respond(ctx, request)
+	assert.Equal(t, IllegalRequestIdErr, err)
+
+	// 5. only the owner updates the public key
+	otherPublicKey, err := node.GroupPublicKey(otherGroup.Id)
+	require.Nil(t, err)
+	encodedPublicKey, err = EncodeGroupPublicKey(otherPublicKey)
+	require.Nil(t, err)
+	_, err = contract.SetGroupPublicKey(requesterOpts, encodedPublicKey)
+	assert.NotNil(t, err)
+
+	// 6. requests not fulfilled are sent again after the retry interval, until max attempts
+	for attempt := 2; attempt <= adapter.MaxAttempts; attempt++ {
+		now = now.Add(adapter.RetryInterval)
+		oracleRequests, err = adapter.Poll(ctx)
+		require.Nil(t, err)
+		require.Len(t, oracleRequests, 1)
+		assert.Equal(t, int64(2), oracleRequests[0].Id.Int64())
+	}
+	now = now.Add(adapter.RetryInterval)
+	oracleRequests, err = adapter.Poll(ctx)
+	require.Nil(t, err)
+	assert.Empty(t, oracleRequests)
+	assert.Empty(t, adapter.pending)
+
+	// 7. an adapter scanning again after a restart skips fulfilled requests
+	restartedAdapter, err := NewAdapter(backend, address, ownerOpts, group.Id)
+	require.Nil(t, err)
+	oracleRequests, err = restartedAdapter.Poll(ctx)
+	require.Nil(t, err)
+	require.Len(t, oracleRequests, 1)
+	assert.Equal(t, int64(2), oracleRequests[0].Id.Int64())
+
+	// 8. the group is checked against the registry contract if required
+	responder.CheckRegistry = true
+	request.Id = "2"
+	_, err = responder.Respond(ctx, request)
+	assert.Equal(t, NoRegistryErr, err)
+	registryAddress, _, err := DeployRegistry(ownerOpts, backend)
+	require.Nil(t, err)
+	backend.Commit()
+	adapter.Registry, err = NewRegistry(backend, registryAddress, ownerOpts)
+	require.Nil(t, err)
+	_, err = responder.Respond(ctx, request)
+	assert.Equal(t, chain.GroupNotRegisteredErr, err)
+	_, err = adapter.Registry.Publish(ctx, group.Id)
+	require.Nil(t, err)
+	backend.Commit()
+	registeredGroup, err := adapter.GetGroup(ctx, group.Id)
+	require.Nil(t, err)
+	assert.Equal(t, group.DkgIndices, registeredGroup.NodeIds)
+	_, err = responder.Respond(ctx, request)
+	assert.NotNil(t, err)
+}
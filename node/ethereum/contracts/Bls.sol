@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+pragma solidity ^0.8.0;
+
+/// @notice Bls verifies signatures of siwa groups on alt_bn128 by the precompiles, signatures are in G1 and public
+/// keys are in G2, as marshalled by the crypto package of siwa
+library Bls {
+    /// @dev P is the field modulus of alt_bn128
+    uint256 internal constant P = 0x30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47;
+
+    /// @dev the generator of G2, imaginary parts before real ones
+    uint256 internal constant G2_X_IMAG = 0x198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2;
+    uint256 internal constant G2_X_REAL = 0x1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed;
+    uint256 internal constant G2_Y_IMAG = 0x090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b;
+    uint256 internal constant G2_Y_REAL = 0x12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa;
+
+    /// @notice verify checks e(H(message), publicKey) == e(signature, G2), the signature is x and y in 32 bytes each
+    function verify(uint256[4] memory publicKey, bytes memory message, bytes memory signature)
+        internal
+        view
+        returns (bool)
+    {
+        if (signature.length != 64) {
+            return false;
+        }
+        (uint256 signatureX, uint256 signatureY) = abi.decode(signature, (uint256, uint256));
+        if (signatureX >= P || signatureY >= P) {
+            return false;
+        }
+        (uint256 hashX, uint256 hashY) = hashToPoint(message);
+        uint256[12] memory input = [
+            signatureX,
+            (P - signatureY) % P,
+            G2_X_IMAG,
+            G2_X_REAL,
+            G2_Y_IMAG,
+            G2_Y_REAL,
+            hashX,
+            hashY,
+            publicKey[0],
+            publicKey[1],
+            publicKey[2],
+            publicKey[3]
+        ];
+        uint256[1] memory output;
+        bool success;
+        assembly {
+            success := staticcall(gas(), 0x08, input, 384, output, 32)
+        }
+        return success && output[0] == 1;
+    }
+
+    /// @notice hashToPoint maps the message to G1 by try-and-increment, x starts from sha256 of the message modulo P
+    /// and increments until x³ + 3 is a square, y is its root (x³ + 3)^((P + 1) / 4)
+    function hashToPoint(bytes memory message) internal view returns (uint256 x, uint256 y) {
+        x = uint256(sha256(message)) % P;
+        while (true) {
+            uint256 t = addmod(mulmod(mulmod(x, x, P), x, P), 3, P);
+            y = modExp(t, (P + 1) / 4);
+            if (mulmod(y, y, P) == t) {
+                return (x, y);
+            }
+            x = addmod(x, 1, P);
+        }
+    }
+
+    function modExp(uint256 base, uint256 exponent) private view returns (uint256) {
+        (bool success, bytes memory output) =
+            address(0x05).staticcall(abi.encodePacked(uint256(32), uint256(32), uint256(32), base, exponent, P));
+        require(success, "modexp failed");
+        return abi.decode(output, (uint256));
+    }
+}
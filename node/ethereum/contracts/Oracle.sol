@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+pragma solidity ^0.8.0;
+
+import "./Bls.sol";
+
+/// @notice Oracle answers requests of expressions by threshold signed reports of a siwa group. A request is fulfilled
+/// by anyone with the message and the signature of the group over the group id, the request id, the expression and
+/// the message, which is verified against the distributed public key of the group.
+contract Oracle {
+    event OracleRequest(uint256 indexed id, address indexed requester, string expression);
+    event OracleFulfillment(uint256 indexed id, bytes message, bytes signature);
+    event GroupPublicKeyChanged(uint256[4] groupPublicKey);
+
+    address public owner;
+    string public groupId;
+    uint256[4] private groupPublicKey;
+    uint256 public lastId;
+    /// @notice expressionHashes are keccak256 of expressions by request ids
+    mapping(uint256 => bytes32) public expressionHashes;
+    mapping(uint256 => bool) public fulfilled;
+
+    constructor(string memory groupId_, uint256[4] memory groupPublicKey_) {
+        owner = msg.sender;
+        groupId = groupId_;
+        _setGroupPublicKey(groupPublicKey_);
+    }
+
+    /// @notice setGroupPublicKey replaces the key after the group runs its dkg again, by the owner only
+    function setGroupPublicKey(uint256[4] memory groupPublicKey_) external {
+        require(msg.sender == owner, "not owner");
+        _setGroupPublicKey(groupPublicKey_);
+    }
+
+    function getGroupPublicKey() external view returns (uint256[4] memory) {
+        return groupPublicKey;
+    }
+
+    function request(string calldata expression) external returns (uint256 id) {
+        id = ++lastId;
+        expressionHashes[id] = keccak256(bytes(expression));
+        emit OracleRequest(id, msg.sender, expression);
+    }
+
+    function fulfill(uint256 id, string calldata expression, bytes calldata message, bytes calldata signature)
+        external
+    {
+        require(expressionHashes[id] != 0, "request not existed");
+        require(!fulfilled[id], "request fulfilled");
+        require(keccak256(bytes(expression)) == expressionHashes[id], "expression mismatched");
+        require(Bls.verify(groupPublicKey, signedPayload(id, expression, message), signature), "illegal signature");
+        fulfilled[id] = true;
+        emit OracleFulfillment(id, message, signature);
+    }
+
+    /// @notice signedPayload is crypto.SignedPayload of siwa, every field is prefixed by its length in 4 bytes, and
+    /// the request id is in decimal
+    function signedPayload(uint256 id, string calldata expression, bytes calldata message)
+        public
+        view
+        returns (bytes memory)
+    {
+        bytes memory requestId = bytes(_toString(id));
+        return abi.encodePacked(
+            uint32(bytes(groupId).length),
+            groupId,
+            uint32(requestId.length),
+            requestId,
+            uint32(bytes(expression).length),
+            expression,
+            uint32(message.length),
+            message
+        );
+    }
+
+    function _setGroupPublicKey(uint256[4] memory groupPublicKey_) private {
+        require(
+            groupPublicKey_[0] | groupPublicKey_[1] | groupPublicKey_[2] | groupPublicKey_[3] != 0,
+            "empty group public key"
+        );
+        groupPublicKey = groupPublicKey_;
+        emit GroupPublicKeyChanged(groupPublicKey_);
+    }
+
+    function _toString(uint256 value) private pure returns (string memory) {
+        if (value == 0) {
+            return "0";
+        }
+        uint256 length;
+        for (uint256 rest = value; rest != 0; rest /= 10) {
+            length++;
+        }
+        bytes memory digits = new bytes(length);
+        for (; value != 0; value /= 10) {
+            digits[--length] = bytes1(uint8(48 + (value % 10)));
+        }
+        return string(digits);
+    }
+}
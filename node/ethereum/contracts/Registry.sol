@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+pragma solidity ^0.8.0;
+
+/// @notice Registry publishes siwa groups, only by its owner. Groups are published as events, so that consumers
+/// discover them by logs, and check them against the version and the hash of the distributed public key in storage.
+contract Registry {
+    event GroupPublished(
+        bytes32 indexed groupKey,
+        uint256 indexed version,
+        string groupId,
+        uint256 threshold,
+        string[] nodeIds,
+        bytes[] publicKeys,
+        bytes distributedPublicKey
+    );
+    event GroupDissolved(bytes32 indexed groupKey, uint256 indexed version, string groupId);
+
+    address public owner;
+    /// @notice version of a group by keccak256 of its id, increased by every publication and dissolution
+    mapping(bytes32 => uint256) public version;
+    /// @notice publicKeyHash is keccak256 of the distributed public key of a group, 0 if the group is dissolved
+    mapping(bytes32 => bytes32) public publicKeyHash;
+
+    modifier onlyOwner() {
+        require(msg.sender == owner, "not owner");
+        _;
+    }
+
+    constructor() {
+        owner = msg.sender;
+    }
+
+    /// @notice publish publishes the group, public keys of nodes are by dkg indices in nodeIds
+    function publish(
+        string calldata groupId,
+        uint256 threshold,
+        string[] calldata nodeIds,
+        bytes[] calldata publicKeys,
+        bytes calldata distributedPublicKey
+    ) external onlyOwner {
+        require(nodeIds.length == publicKeys.length, "node ids mismatch public keys");
+        bytes32 groupKey = keccak256(bytes(groupId));
+        uint256 newVersion = ++version[groupKey];
+        publicKeyHash[groupKey] = keccak256(distributedPublicKey);
+        emit GroupPublished(groupKey, newVersion, groupId, threshold, nodeIds, publicKeys, distributedPublicKey);
+    }
+
+    function dissolve(string calldata groupId) external onlyOwner {
+        bytes32 groupKey = keccak256(bytes(groupId));
+        uint256 newVersion = ++version[groupKey];
+        delete publicKeyHash[groupKey];
+        emit GroupDissolved(groupKey, newVersion, groupId);
+    }
+}
@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package ethereum fulfils oracle requests on Ethereum compatible chains by threshold signed reports of groups
+package ethereum
+
+//go:generate solc --abi --bin --optimize --evm-version paris --overwrite -o contracts/build contracts/Oracle.sol
+//go:generate abigen --abi contracts/build/Oracle.abi --bin contracts/build/Oracle.bin --pkg ethereum --type OracleContract --out oracle_contract.go
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.dedis.ch/kyber/v3"
+)
+
+// groupPublicKeyLen is the length of a marshalled G2 point of alt_bn128
+const groupPublicKeyLen = 128
+
+var IllegalGroupPublicKeyErr = fmt.Errorf("illegal group public key")
+
+// OracleRequest is an OracleRequest event of the oracle contract
+type OracleRequest = OracleContractOracleRequest
+
+// EncodeGroupPublicKey encodes the distributed public key of a group as the oracle contract stores it, the
+// coordinates of the G2 point in the order of the pairing precompile
+func EncodeGroupPublicKey(groupPublicKey kyber.Point) ([4]*big.Int, error) {
+	var encoded [4]*big.Int
+	if groupPublicKey == nil {
+		log.Error("nil group public key", "err", utils.NilPtrDerefErr)
+		return encoded, utils.NilPtrDerefErr
+	}
+	data, err := groupPublicKey.MarshalBinary()
+	if err != nil || len(data) != groupPublicKeyLen {
+		log.Error("fail to encode group public key", "err", IllegalGroupPublicKeyErr)
+		return encoded, IllegalGroupPublicKeyErr
+	}
+	for i := range encoded {
+		encoded[i] = new(big.Int).SetBytes(data[32*i : 32*(i+1)])
+	}
+	return encoded, nil
+}
+
+// DeployOracle deploys the oracle contract of the group, fulfilments are verified by the distributed public key of
+// the group, and the sender of transactOpts is the owner, who updates the key after the group runs its dkg again
+func DeployOracle(transactOpts *bind.TransactOpts, backend bind.ContractBackend, groupId string,
+	groupPublicKey kyber.Point) (common.Address, *types.Transaction, error) {
+	encoded, err := EncodeGroupPublicKey(groupPublicKey)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	address, transaction, _, err := DeployOracleContract(transactOpts, backend, groupId, encoded)
+	return address, transaction, err
+}
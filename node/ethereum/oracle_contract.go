@@ -0,0 +1,917 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package ethereum
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// OracleContractMetaData contains all meta data concerning the OracleContract contract.
+var OracleContractMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[{\"internalType\":\"string\",\"name\":\"groupId_\",\"type\":\"string\"},{\"internalType\":\"uint256[4]\",\"name\":\"groupPublicKey_\",\"type\":\"uint256[4]\"}],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"internalType\":\"uint256[4]\",\"name\":\"groupPublicKey\",\"type\":\"uint256[4]\"}],\"name\":\"GroupPublicKeyChanged\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"message\",\"type\":\"bytes\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"signature\",\"type\":\"bytes\"}],\"name\":\"OracleFulfillment\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"},{\"indexed\":true,\"internalType\":\"address\",\"name\":\"requester\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"expression\",\"type\":\"string\"}],\"name\":\"OracleRequest\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"expressionHashes\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"},{\"internalType\":\"string\",\"name\":\"expression\",\"type\":\"string\"},{\"internalType\":\"bytes\",\"name\":\"message\",\"type\":\"bytes\"},{\"internalType\":\"bytes\",\"name\":\"signature\",\"type\":\"bytes\"}],\"name\":\"fulfill\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"name\":\"fulfilled\",\"outputs\":[{\"internalType\":\"bool\",\"name\":\"\",\"type\":\"bool\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"getGroupPublicKey\",\"outputs\":[{\"internalType\":\"uint256[4]\",\"name\":\"\",\"type\":\"uint256[4]\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"groupId\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"lastId\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"owner\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"expression\",\"type\":\"string\"}],\"name\":\"request\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256[4]\",\"name\":\"groupPublicKey_\",\"type\":\"uint256[4]\"}],\"name\":\"setGroupPublicKey\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"},{\"internalType\":\"string\",\"name\":\"expression\",\"type\":\"string\"},{\"internalType\":\"bytes\",\"name\":\"message\",\"type\":\"bytes\"}],\"name\":\"signedPayload\",\"outputs\":[{\"internalType\":\"bytes\",\"name\":\"\",\"type\":\"bytes\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "0x60806040523480156200001157600080fd5b506040516200171c3803806200171c833981016040819052620000349162000233565b600080546001600160a01b0319163317905560016200005483826200038c565b50620000608162000068565b50506200048b565b6060810151604082015160208301518351171717600003620000d05760405162461bcd60e51b815260206004820152601660248201527f656d7074792067726f7570207075626c6963206b657900000000000000000000604482015260640160405180910390fd5b620000df60028260046200011c565b507fb97e570ff1b7f4b89df5f950310ec712c16e1bd801d08f29da53715cf5bce95d8160405162000111919062000458565b60405180910390a150565b82600481019282156200014d579160200282015b828111156200014d57825182559160200191906001019062000130565b506200015b9291506200015f565b5090565b5b808211156200015b576000815560010162000160565b634e487b7160e01b600052604160045260246000fd5b604051601f8201601f191681016001600160401b0381118282101715620001b757620001b762000176565b604052919050565b600082601f830112620001d157600080fd5b604051608081016001600160401b0381118282101715620001f657620001f662000176565b6040528060808401858111156200020c57600080fd5b845b81811015620002285780518352602092830192016200020e565b509195945050505050565b60008060a083850312156200024757600080fd5b82516001600160401b03808211156200025f57600080fd5b818501915085601f8301126200027457600080fd5b81518181111562000289576200028962000176565b60209150620002a1601f8201601f191683016200018c565b8181528783838601011115620002b657600080fd5b60005b82811015620002d6578481018401518282018501528301620002b9565b5060008383830101528095505050620002f286828701620001bf565b925050509250929050565b600181811c908216806200031257607f821691505b6020821081036200033357634e487b7160e01b600052602260045260246000fd5b50919050565b601f8211156200038757600081815260208120601f850160051c81016020861015620003625750805b601f850160051c820191505b8181101562000383578281556001016200036e565b5050505b505050565b81516001600160401b03811115620003a857620003a862000176565b620003c081620003b98454620002fd565b8462000339565b602080601f831160018114620003f85760008415620003df5750858301515b600019600386901b1c1916600185901b17855562000383565b600085815260208120601f198616915b82811015620004295788860151825594840194600190910190840162000408565b5085821015620004485787850151600019600388901b60f8161c191681555b5050505050600190811b01905550565b60808101818360005b60048110156200048257815183526020928301929091019060010162000461565b50505092915050565b611281806200049b6000396000f3fe608060405234801561001057600080fd5b506004361061009e5760003560e01c8063860b1ad711610066578063860b1ad714610139578063893c5014146101595780638da5cb5b14610179578063a0f44c92146101a4578063c1292cc3146101ac57600080fd5b80631bc404d6146100a357806320f72101146100db5780632c199889146100f057806370b629a0146101115780637d9565a514610126575b600080fd5b6100c66100b1366004610bfa565b60086020526000908152604090205460ff1681565b60405190151581526020015b60405180910390f35b6100ee6100e9366004610c5c565b6101b5565b005b6101036100fe366004610d00565b6103e9565b6040519081526020016100d2565b61011961046c565b6040516100d29190610d42565b6100ee610134366004610d89565b6104a7565b610103610147366004610bfa565b60076020526000908152604090205481565b61016c610167366004610e15565b6104f9565b6040516100d29190610edf565b60005461018c906001600160a01b031681565b6040516001600160a01b0390911681526020016100d2565b61016c610554565b61010360065481565b600087815260076020526040812054900361020d5760405162461bcd60e51b81526020600482015260136024820152721c995c5d595cdd081b9bdd08195e1a5cdd1959606a1b60448201526064015b60405180910390fd5b60008781526008602052604090205460ff16156102605760405162461bcd60e51b81526020600482015260116024820152701c995c5d595cdd08199d5b199a5b1b1959607a1b6044820152606401610204565b600087815260076020526040908190205490516102809088908890610f01565b6040518091039020146102cd5760405162461bcd60e51b8152602060048201526015602482015274195e1c1c995cdcda5bdb881b5a5cdb585d18da1959605a1b6044820152606401610204565b6040805160808101918290526103479160029060049082845b8154815260200190600101908083116102e657505050505061030b89898989896104f9565b84848080601f0160208091040260200160405190810160405280939291908181526020018383808284376000920191909152506105e292505050565b6103875760405162461bcd60e51b8152602060048201526011602482015270696c6c6567616c207369676e617475726560781b6044820152606401610204565b60008781526008602052604090819020805460ff191660011790555187907fd99a3f274c0d23af67ac8304dc4b7b9e9210b9c3954677e11b27ad134f945c53906103d8908790879087908790610f3a565b60405180910390a250505050505050565b60006006600081546103fa90610f82565b91905081905590508282604051610412929190610f01565b60408051918290038220600084815260076020529190912055339082907f0adb5cb08122427683513087a0627389a985a4d0dde9fd4abd0661843e8840119061045e9087908790610f9b565b60405180910390a392915050565b610474610b6b565b6040805160808101918290529060029060049082845b81548152602001906001019080831161048a575050505050905090565b6000546001600160a01b031633146104ed5760405162461bcd60e51b81526020600482015260096024820152683737ba1037bbb732b960b91b6044820152606401610204565b6104f6816107b2565b50565b6060600061050687610856565b90506001805461051590610fb7565b825160405161053993506001919085908a908c9082908b908d90829060200161100d565b60405160208183030381529060405291505095945050505050565b6001805461056190610fb7565b80601f016020809104026020016040519081016040528092919081815260200182805461058d90610fb7565b80156105da5780601f106105af576101008083540402835291602001916105da565b820191906000526020600020905b8154815290600101906020018083116105bd57829003601f168201915b505050505081565b600081516040146105f5575060006107ab565b6000808380602001905181019061060c9190611141565b9150915060008051602061122c8339815191528210158061063b575060008051602061122c8339815191528110155b1561064b576000925050506107ab565b60008061065787610956565b91509150600060405180610180016040528086815260200160008051602061122c8339815191528660008051602061122c8339815191526106989190611165565b6106a2919061118e565b81527f198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c26020808301919091527f1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed6040808401919091527f090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b60608401527f12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa608084015260a0830187905260c083018690528c5160e0840152908c01516101008301528b0151610120820152610140018a6003602002015190529050610784610b89565b60006020826101808560085afa90508080156107a1575081516001145b9750505050505050505b9392505050565b606081015160408201516020830151835117171760000361080e5760405162461bcd60e51b8152602060048201526016602482015275656d7074792067726f7570207075626c6963206b657960501b6044820152606401610204565b61081b6002826004610ba7565b507fb97e570ff1b7f4b89df5f950310ec712c16e1bd801d08f29da53715cf5bce95d8160405161084b9190610d42565b60405180910390a150565b60608160000361087d5750506040805180820190915260018152600360fc1b602082015290565b6000825b80156108a7578161089181610f82565b92506108a09050600a826111b8565b9050610881565b5060008167ffffffffffffffff8111156108c3576108c3610d73565b6040519080825280601f01601f1916602001820160405280156108ed576020820181803683370190505b5090505b83156107ab57610902600a8561118e565b61090d9060306111cc565b60f81b8161091a846111df565b9350838151811061092d5761092d6111a2565b60200101906001600160f81b031916908160001a90535061094f600a856111b8565b93506108f1565b60008060008051602061122c83398151915260028460405161097891906111f6565b602060405180830381855afa158015610995573d6000803e3d6000fd5b5050506040513d601f19601f820116820180604052508101906109b89190611212565b6109c2919061118e565b91505b600060008051602061122c833981519152600360008051602061122c8339815191528560008051602061122c83398151915287880909089050610a2c816004610a1d60008051602061122c83398151915260016111cc565b610a2791906111b8565b610a67565b91508060008051602061122c83398151915283840903610a4c5750915091565b60008051602061122c833981519152600184089250506109c5565b60408051602081810181905291810182905260608101919091526080810183905260a0810182905260008051602061122c83398151915260c08201526000908190819060059060e00160408051601f1981840301815290829052610aca916111f6565b600060405180830381855afa9150503d8060008114610b05576040519150601f19603f3d011682016040523d82523d6000602084013e610b0a565b606091505b509150915081610b4c5760405162461bcd60e51b815260206004820152600d60248201526c1b5bd9195e1c0819985a5b1959609a1b6044820152606401610204565b80806020019051810190610b609190611212565b925050505b92915050565b60405180608001604052806004906020820280368337509192915050565b60405180602001604052806001906020820280368337509192915050565b8260048101928215610bd5579160200282015b82811115610bd5578251825591602001919060010190610bba565b50610be1929150610be5565b5090565b5b80821115610be15760008155600101610be6565b600060208284031215610c0c57600080fd5b5035919050565b60008083601f840112610c2557600080fd5b50813567ffffffffffffffff811115610c3d57600080fd5b602083019150836020828501011115610c5557600080fd5b9250929050565b60008060008060008060006080888a031215610c7757600080fd5b87359650602088013567ffffffffffffffff80821115610c9657600080fd5b610ca28b838c01610c13565b909850965060408a0135915080821115610cbb57600080fd5b610cc78b838c01610c13565b909650945060608a0135915080821115610ce057600080fd5b50610ced8a828b01610c13565b989b979a50959850939692959293505050565b60008060208385031215610d1357600080fd5b823567ffffffffffffffff811115610d2a57600080fd5b610d3685828601610c13565b90969095509350505050565b60808101818360005b6004811015610d6a578151835260209283019290910190600101610d4b565b50505092915050565b634e487b7160e01b600052604160045260246000fd5b600060808284031215610d9b57600080fd5b82601f830112610daa57600080fd5b6040516080810181811067ffffffffffffffff82111715610ddb57634e487b7160e01b600052604160045260246000fd5b604052806080840185811115610df057600080fd5b845b81811015610e0a578035835260209283019201610df2565b509195945050505050565b600080600080600060608688031215610e2d57600080fd5b85359450602086013567ffffffffffffffff80821115610e4c57600080fd5b610e5889838a01610c13565b90965094506040880135915080821115610e7157600080fd5b50610e7e88828901610c13565b969995985093965092949392505050565b60005b83811015610eaa578181015183820152602001610e92565b50506000910152565b60008151808452610ecb816020860160208601610e8f565b601f01601f19169290920160200192915050565b6020815260006107ab6020830184610eb3565b81818437506000910190815290565b8183823760009101908152919050565b81835281816020850137506000828201602090810191909152601f909101601f19169091010190565b604081526000610f4e604083018688610f11565b8281036020840152610f61818587610f11565b979650505050505050565b634e487b7160e01b600052601160045260246000fd5b600060018201610f9457610f94610f6c565b5060010190565b602081526000610faf602083018486610f11565b949350505050565b600181811c90821680610fcb57607f821691505b602082108103610feb57634e487b7160e01b600052602260045260246000fd5b50919050565b60008151611003818560208601610e8f565b9290920192915050565b60e08b901b6001600160e01b031916815289546000908190600181811c90821661103557607f165b60208110600183160361105657634e487b7160e01b83526022600452602483fd5b60018216801561106d5760018114611088576110be565b60ff19831660048701526004821515830287010193506110be565b60008f81526020902060005b838110156110b357815488820160040152600190910190602001611094565b505060048287010193505b5050506110d7818c60e01b6001600160e01b0319169052565b6110e4600482018b610ff1565b90506110fc818a60e01b6001600160e01b0319169052565b61110a60048201888a610ef2565b9050611122818760e01b6001600160e01b0319169052565b611130600482018587610ef2565b9d9c50505050505050505050505050565b6000806040838503121561115457600080fd5b505080516020909101519092909150565b81810381811115610b6557610b65610f6c565b634e487b7160e01b600052601260045260246000fd5b60008261119d5761119d611178565b500690565b634e487b7160e01b600052603260045260246000fd5b6000826111c7576111c7611178565b500490565b80820180821115610b6557610b65610f6c565b6000816111ee576111ee610f6c565b506000190190565b60008251611208818460208701610e8f565b9190910192915050565b60006020828403121561122457600080fd5b505191905056fe30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47a26469706673582212204d9abf0fa9465e67926ebfcedca93ebeb99ad614df689f1c084bc4863945b9f764736f6c63430008150033",
+}
+
+// OracleContractABI is the input ABI used to generate the binding from.
+// Deprecated: Use OracleContractMetaData.ABI instead.
+var OracleContractABI = OracleContractMetaData.ABI
+
+// OracleContractBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use OracleContractMetaData.Bin instead.
+var OracleContractBin = OracleContractMetaData.Bin
+
+// DeployOracleContract deploys a new Ethereum contract, binding an instance of OracleContract to it.
+func DeployOracleContract(auth *bind.TransactOpts, backend bind.ContractBackend, groupId_ string, groupPublicKey_ [4]*big.Int) (common.Address, *types.Transaction, *OracleContract, error) {
+	parsed, err := OracleContractMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(OracleContractBin), backend, groupId_, groupPublicKey_)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &OracleContract{OracleContractCaller: OracleContractCaller{contract: contract}, OracleContractTransactor: OracleContractTransactor{contract: contract}, OracleContractFilterer: OracleContractFilterer{contract: contract}}, nil
+}
+
+// OracleContract is an auto generated Go binding around an Ethereum contract.
+type OracleContract struct {
+	OracleContractCaller     // Read-only binding to the contract
+	OracleContractTransactor // Write-only binding to the contract
+	OracleContractFilterer   // Log filterer for contract events
+}
+
+// OracleContractCaller is an auto generated read-only Go binding around an Ethereum contract.
+type OracleContractCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// OracleContractTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type OracleContractTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// OracleContractFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type OracleContractFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// OracleContractSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type OracleContractSession struct {
+	Contract     *OracleContract   // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// OracleContractCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type OracleContractCallerSession struct {
+	Contract *OracleContractCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts         // Call options to use throughout this session
+}
+
+// OracleContractTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type OracleContractTransactorSession struct {
+	Contract     *OracleContractTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts         // Transaction auth options to use throughout this session
+}
+
+// OracleContractRaw is an auto generated low-level Go binding around an Ethereum contract.
+type OracleContractRaw struct {
+	Contract *OracleContract // Generic contract binding to access the raw methods on
+}
+
+// OracleContractCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type OracleContractCallerRaw struct {
+	Contract *OracleContractCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// OracleContractTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type OracleContractTransactorRaw struct {
+	Contract *OracleContractTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewOracleContract creates a new instance of OracleContract, bound to a specific deployed contract.
+func NewOracleContract(address common.Address, backend bind.ContractBackend) (*OracleContract, error) {
+	contract, err := bindOracleContract(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &OracleContract{OracleContractCaller: OracleContractCaller{contract: contract}, OracleContractTransactor: OracleContractTransactor{contract: contract}, OracleContractFilterer: OracleContractFilterer{contract: contract}}, nil
+}
+
+// NewOracleContractCaller creates a new read-only instance of OracleContract, bound to a specific deployed contract.
+func NewOracleContractCaller(address common.Address, caller bind.ContractCaller) (*OracleContractCaller, error) {
+	contract, err := bindOracleContract(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &OracleContractCaller{contract: contract}, nil
+}
+
+// NewOracleContractTransactor creates a new write-only instance of OracleContract, bound to a specific deployed contract.
+func NewOracleContractTransactor(address common.Address, transactor bind.ContractTransactor) (*OracleContractTransactor, error) {
+	contract, err := bindOracleContract(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &OracleContractTransactor{contract: contract}, nil
+}
+
+// NewOracleContractFilterer creates a new log filterer instance of OracleContract, bound to a specific deployed contract.
+func NewOracleContractFilterer(address common.Address, filterer bind.ContractFilterer) (*OracleContractFilterer, error) {
+	contract, err := bindOracleContract(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &OracleContractFilterer{contract: contract}, nil
+}
+
+// bindOracleContract binds a generic wrapper to an already deployed contract.
+func bindOracleContract(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := OracleContractMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_OracleContract *OracleContractRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _OracleContract.Contract.OracleContractCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_OracleContract *OracleContractRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _OracleContract.Contract.OracleContractTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_OracleContract *OracleContractRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _OracleContract.Contract.OracleContractTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_OracleContract *OracleContractCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _OracleContract.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_OracleContract *OracleContractTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _OracleContract.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_OracleContract *OracleContractTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _OracleContract.Contract.contract.Transact(opts, method, params...)
+}
+
+// ExpressionHashes is a free data retrieval call binding the contract method 0x860b1ad7.
+//
+// Solidity: function expressionHashes(uint256 ) view returns(bytes32)
+func (_OracleContract *OracleContractCaller) ExpressionHashes(opts *bind.CallOpts, arg0 *big.Int) ([32]byte, error) {
+	var out []interface{}
+	err := _OracleContract.contract.Call(opts, &out, "expressionHashes", arg0)
+
+	if err != nil {
+		return *new([32]byte), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([32]byte)).(*[32]byte)
+
+	return out0, err
+
+}
+
+// ExpressionHashes is a free data retrieval call binding the contract method 0x860b1ad7.
+//
+// Solidity: function expressionHashes(uint256 ) view returns(bytes32)
+func (_OracleContract *OracleContractSession) ExpressionHashes(arg0 *big.Int) ([32]byte, error) {
+	return _OracleContract.Contract.ExpressionHashes(&_OracleContract.CallOpts, arg0)
+}
+
+// ExpressionHashes is a free data retrieval call binding the contract method 0x860b1ad7.
+//
+// Solidity: function expressionHashes(uint256 ) view returns(bytes32)
+func (_OracleContract *OracleContractCallerSession) ExpressionHashes(arg0 *big.Int) ([32]byte, error) {
+	return _OracleContract.Contract.ExpressionHashes(&_OracleContract.CallOpts, arg0)
+}
+
+// Fulfilled is a free data retrieval call binding the contract method 0x1bc404d6.
+//
+// Solidity: function fulfilled(uint256 ) view returns(bool)
+func (_OracleContract *OracleContractCaller) Fulfilled(opts *bind.CallOpts, arg0 *big.Int) (bool, error) {
+	var out []interface{}
+	err := _OracleContract.contract.Call(opts, &out, "fulfilled", arg0)
+
+	if err != nil {
+		return *new(bool), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+
+	return out0, err
+
+}
+
+// Fulfilled is a free data retrieval call binding the contract method 0x1bc404d6.
+//
+// Solidity: function fulfilled(uint256 ) view returns(bool)
+func (_OracleContract *OracleContractSession) Fulfilled(arg0 *big.Int) (bool, error) {
+	return _OracleContract.Contract.Fulfilled(&_OracleContract.CallOpts, arg0)
+}
+
+// Fulfilled is a free data retrieval call binding the contract method 0x1bc404d6.
+//
+// Solidity: function fulfilled(uint256 ) view returns(bool)
+func (_OracleContract *OracleContractCallerSession) Fulfilled(arg0 *big.Int) (bool, error) {
+	return _OracleContract.Contract.Fulfilled(&_OracleContract.CallOpts, arg0)
+}
+
+// GetGroupPublicKey is a free data retrieval call binding the contract method 0x70b629a0.
+//
+// Solidity: function getGroupPublicKey() view returns(uint256[4])
+func (_OracleContract *OracleContractCaller) GetGroupPublicKey(opts *bind.CallOpts) ([4]*big.Int, error) {
+	var out []interface{}
+	err := _OracleContract.contract.Call(opts, &out, "getGroupPublicKey")
+
+	if err != nil {
+		return *new([4]*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([4]*big.Int)).(*[4]*big.Int)
+
+	return out0, err
+
+}
+
+// GetGroupPublicKey is a free data retrieval call binding the contract method 0x70b629a0.
+//
+// Solidity: function getGroupPublicKey() view returns(uint256[4])
+func (_OracleContract *OracleContractSession) GetGroupPublicKey() ([4]*big.Int, error) {
+	return _OracleContract.Contract.GetGroupPublicKey(&_OracleContract.CallOpts)
+}
+
+// GetGroupPublicKey is a free data retrieval call binding the contract method 0x70b629a0.
+//
+// Solidity: function getGroupPublicKey() view returns(uint256[4])
+func (_OracleContract *OracleContractCallerSession) GetGroupPublicKey() ([4]*big.Int, error) {
+	return _OracleContract.Contract.GetGroupPublicKey(&_OracleContract.CallOpts)
+}
+
+// GroupId is a free data retrieval call binding the contract method 0xa0f44c92.
+//
+// Solidity: function groupId() view returns(string)
+func (_OracleContract *OracleContractCaller) GroupId(opts *bind.CallOpts) (string, error) {
+	var out []interface{}
+	err := _OracleContract.contract.Call(opts, &out, "groupId")
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// GroupId is a free data retrieval call binding the contract method 0xa0f44c92.
+//
+// Solidity: function groupId() view returns(string)
+func (_OracleContract *OracleContractSession) GroupId() (string, error) {
+	return _OracleContract.Contract.GroupId(&_OracleContract.CallOpts)
+}
+
+// GroupId is a free data retrieval call binding the contract method 0xa0f44c92.
+//
+// Solidity: function groupId() view returns(string)
+func (_OracleContract *OracleContractCallerSession) GroupId() (string, error) {
+	return _OracleContract.Contract.GroupId(&_OracleContract.CallOpts)
+}
+
+// LastId is a free data retrieval call binding the contract method 0xc1292cc3.
+//
+// Solidity: function lastId() view returns(uint256)
+func (_OracleContract *OracleContractCaller) LastId(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _OracleContract.contract.Call(opts, &out, "lastId")
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// LastId is a free data retrieval call binding the contract method 0xc1292cc3.
+//
+// Solidity: function lastId() view returns(uint256)
+func (_OracleContract *OracleContractSession) LastId() (*big.Int, error) {
+	return _OracleContract.Contract.LastId(&_OracleContract.CallOpts)
+}
+
+// LastId is a free data retrieval call binding the contract method 0xc1292cc3.
+//
+// Solidity: function lastId() view returns(uint256)
+func (_OracleContract *OracleContractCallerSession) LastId() (*big.Int, error) {
+	return _OracleContract.Contract.LastId(&_OracleContract.CallOpts)
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_OracleContract *OracleContractCaller) Owner(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _OracleContract.contract.Call(opts, &out, "owner")
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_OracleContract *OracleContractSession) Owner() (common.Address, error) {
+	return _OracleContract.Contract.Owner(&_OracleContract.CallOpts)
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_OracleContract *OracleContractCallerSession) Owner() (common.Address, error) {
+	return _OracleContract.Contract.Owner(&_OracleContract.CallOpts)
+}
+
+// SignedPayload is a free data retrieval call binding the contract method 0x893c5014.
+//
+// Solidity: function signedPayload(uint256 id, string expression, bytes message) view returns(bytes)
+func (_OracleContract *OracleContractCaller) SignedPayload(opts *bind.CallOpts, id *big.Int, expression string, message []byte) ([]byte, error) {
+	var out []interface{}
+	err := _OracleContract.contract.Call(opts, &out, "signedPayload", id, expression, message)
+
+	if err != nil {
+		return *new([]byte), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([]byte)).(*[]byte)
+
+	return out0, err
+
+}
+
+// SignedPayload is a free data retrieval call binding the contract method 0x893c5014.
+//
+// Solidity: function signedPayload(uint256 id, string expression, bytes message) view returns(bytes)
+func (_OracleContract *OracleContractSession) SignedPayload(id *big.Int, expression string, message []byte) ([]byte, error) {
+	return _OracleContract.Contract.SignedPayload(&_OracleContract.CallOpts, id, expression, message)
+}
+
+// SignedPayload is a free data retrieval call binding the contract method 0x893c5014.
+//
+// Solidity: function signedPayload(uint256 id, string expression, bytes message) view returns(bytes)
+func (_OracleContract *OracleContractCallerSession) SignedPayload(id *big.Int, expression string, message []byte) ([]byte, error) {
+	return _OracleContract.Contract.SignedPayload(&_OracleContract.CallOpts, id, expression, message)
+}
+
+// Fulfill is a paid mutator transaction binding the contract method 0x20f72101.
+//
+// Solidity: function fulfill(uint256 id, string expression, bytes message, bytes signature) returns()
+func (_OracleContract *OracleContractTransactor) Fulfill(opts *bind.TransactOpts, id *big.Int, expression string, message []byte, signature []byte) (*types.Transaction, error) {
+	return _OracleContract.contract.Transact(opts, "fulfill", id, expression, message, signature)
+}
+
+// Fulfill is a paid mutator transaction binding the contract method 0x20f72101.
+//
+// Solidity: function fulfill(uint256 id, string expression, bytes message, bytes signature) returns()
+func (_OracleContract *OracleContractSession) Fulfill(id *big.Int, expression string, message []byte, signature []byte) (*types.Transaction, error) {
+	return _OracleContract.Contract.Fulfill(&_OracleContract.TransactOpts, id, expression, message, signature)
+}
+
+// Fulfill is a paid mutator transaction binding the contract method 0x20f72101.
+//
+// Solidity: function fulfill(uint256 id, string expression, bytes message, bytes signature) returns()
+func (_OracleContract *OracleContractTransactorSession) Fulfill(id *big.Int, expression string, message []byte, signature []byte) (*types.Transaction, error) {
+	return _OracleContract.Contract.Fulfill(&_OracleContract.TransactOpts, id, expression, message, signature)
+}
+
+// Request is a paid mutator transaction binding the contract method 0x2c199889.
+//
+// Solidity: function request(string expression) returns(uint256 id)
+func (_OracleContract *OracleContractTransactor) Request(opts *bind.TransactOpts, expression string) (*types.Transaction, error) {
+	return _OracleContract.contract.Transact(opts, "request", expression)
+}
+
+// Request is a paid mutator transaction binding the contract method 0x2c199889.
+//
+// Solidity: function request(string expression) returns(uint256 id)
+func (_OracleContract *OracleContractSession) Request(expression string) (*types.Transaction, error) {
+	return _OracleContract.Contract.Request(&_OracleContract.TransactOpts, expression)
+}
+
+// Request is a paid mutator transaction binding the contract method 0x2c199889.
+//
+// Solidity: function request(string expression) returns(uint256 id)
+func (_OracleContract *OracleContractTransactorSession) Request(expression string) (*types.Transaction, error) {
+	return _OracleContract.Contract.Request(&_OracleContract.TransactOpts, expression)
+}
+
+// SetGroupPublicKey is a paid mutator transaction binding the contract method 0x7d9565a5.
+//
+// Solidity: function setGroupPublicKey(uint256[4] groupPublicKey_) returns()
+func (_OracleContract *OracleContractTransactor) SetGroupPublicKey(opts *bind.TransactOpts, groupPublicKey_ [4]*big.Int) (*types.Transaction, error) {
+	return _OracleContract.contract.Transact(opts, "setGroupPublicKey", groupPublicKey_)
+}
+
+// SetGroupPublicKey is a paid mutator transaction binding the contract method 0x7d9565a5.
+//
+// Solidity: function setGroupPublicKey(uint256[4] groupPublicKey_) returns()
+func (_OracleContract *OracleContractSession) SetGroupPublicKey(groupPublicKey_ [4]*big.Int) (*types.Transaction, error) {
+	return _OracleContract.Contract.SetGroupPublicKey(&_OracleContract.TransactOpts, groupPublicKey_)
+}
+
+// SetGroupPublicKey is a paid mutator transaction binding the contract method 0x7d9565a5.
+//
+// Solidity: function setGroupPublicKey(uint256[4] groupPublicKey_) returns()
+func (_OracleContract *OracleContractTransactorSession) SetGroupPublicKey(groupPublicKey_ [4]*big.Int) (*types.Transaction, error) {
+	return _OracleContract.Contract.SetGroupPublicKey(&_OracleContract.TransactOpts, groupPublicKey_)
+}
+
+// OracleContractGroupPublicKeyChangedIterator is returned from FilterGroupPublicKeyChanged and is used to iterate over the raw logs and unpacked data for GroupPublicKeyChanged events raised by the OracleContract contract.
+type OracleContractGroupPublicKeyChangedIterator struct {
+	Event *OracleContractGroupPublicKeyChanged // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *OracleContractGroupPublicKeyChangedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(OracleContractGroupPublicKeyChanged)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(OracleContractGroupPublicKeyChanged)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *OracleContractGroupPublicKeyChangedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *OracleContractGroupPublicKeyChangedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// OracleContractGroupPublicKeyChanged represents a GroupPublicKeyChanged event raised by the OracleContract contract.
+type OracleContractGroupPublicKeyChanged struct {
+	GroupPublicKey [4]*big.Int
+	Raw            types.Log // Blockchain specific contextual infos
+}
+
+// FilterGroupPublicKeyChanged is a free log retrieval operation binding the contract event 0xb97e570ff1b7f4b89df5f950310ec712c16e1bd801d08f29da53715cf5bce95d.
+//
+// Solidity: event GroupPublicKeyChanged(uint256[4] groupPublicKey)
+func (_OracleContract *OracleContractFilterer) FilterGroupPublicKeyChanged(opts *bind.FilterOpts) (*OracleContractGroupPublicKeyChangedIterator, error) {
+
+	logs, sub, err := _OracleContract.contract.FilterLogs(opts, "GroupPublicKeyChanged")
+	if err != nil {
+		return nil, err
+	}
+	return &OracleContractGroupPublicKeyChangedIterator{contract: _OracleContract.contract, event: "GroupPublicKeyChanged", logs: logs, sub: sub}, nil
+}
+
+// WatchGroupPublicKeyChanged is a free log subscription operation binding the contract event 0xb97e570ff1b7f4b89df5f950310ec712c16e1bd801d08f29da53715cf5bce95d.
+//
+// Solidity: event GroupPublicKeyChanged(uint256[4] groupPublicKey)
+func (_OracleContract *OracleContractFilterer) WatchGroupPublicKeyChanged(opts *bind.WatchOpts, sink chan<- *OracleContractGroupPublicKeyChanged) (event.Subscription, error) {
+
+	logs, sub, err := _OracleContract.contract.WatchLogs(opts, "GroupPublicKeyChanged")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(OracleContractGroupPublicKeyChanged)
+				if err := _OracleContract.contract.UnpackLog(event, "GroupPublicKeyChanged", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseGroupPublicKeyChanged is a log parse operation binding the contract event 0xb97e570ff1b7f4b89df5f950310ec712c16e1bd801d08f29da53715cf5bce95d.
+//
+// Solidity: event GroupPublicKeyChanged(uint256[4] groupPublicKey)
+func (_OracleContract *OracleContractFilterer) ParseGroupPublicKeyChanged(log types.Log) (*OracleContractGroupPublicKeyChanged, error) {
+	event := new(OracleContractGroupPublicKeyChanged)
+	if err := _OracleContract.contract.UnpackLog(event, "GroupPublicKeyChanged", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// OracleContractOracleFulfillmentIterator is returned from FilterOracleFulfillment and is used to iterate over the raw logs and unpacked data for OracleFulfillment events raised by the OracleContract contract.
+type OracleContractOracleFulfillmentIterator struct {
+	Event *OracleContractOracleFulfillment // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *OracleContractOracleFulfillmentIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(OracleContractOracleFulfillment)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(OracleContractOracleFulfillment)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *OracleContractOracleFulfillmentIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *OracleContractOracleFulfillmentIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// OracleContractOracleFulfillment represents a OracleFulfillment event raised by the OracleContract contract.
+type OracleContractOracleFulfillment struct {
+	Id        *big.Int
+	Message   []byte
+	Signature []byte
+	Raw       types.Log // Blockchain specific contextual infos
+}
+
+// FilterOracleFulfillment is a free log retrieval operation binding the contract event 0xd99a3f274c0d23af67ac8304dc4b7b9e9210b9c3954677e11b27ad134f945c53.
+//
+// Solidity: event OracleFulfillment(uint256 indexed id, bytes message, bytes signature)
+func (_OracleContract *OracleContractFilterer) FilterOracleFulfillment(opts *bind.FilterOpts, id []*big.Int) (*OracleContractOracleFulfillmentIterator, error) {
+
+	var idRule []interface{}
+	for _, idItem := range id {
+		idRule = append(idRule, idItem)
+	}
+
+	logs, sub, err := _OracleContract.contract.FilterLogs(opts, "OracleFulfillment", idRule)
+	if err != nil {
+		return nil, err
+	}
+	return &OracleContractOracleFulfillmentIterator{contract: _OracleContract.contract, event: "OracleFulfillment", logs: logs, sub: sub}, nil
+}
+
+// WatchOracleFulfillment is a free log subscription operation binding the contract event 0xd99a3f274c0d23af67ac8304dc4b7b9e9210b9c3954677e11b27ad134f945c53.
+//
+// Solidity: event OracleFulfillment(uint256 indexed id, bytes message, bytes signature)
+func (_OracleContract *OracleContractFilterer) WatchOracleFulfillment(opts *bind.WatchOpts, sink chan<- *OracleContractOracleFulfillment, id []*big.Int) (event.Subscription, error) {
+
+	var idRule []interface{}
+	for _, idItem := range id {
+		idRule = append(idRule, idItem)
+	}
+
+	logs, sub, err := _OracleContract.contract.WatchLogs(opts, "OracleFulfillment", idRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(OracleContractOracleFulfillment)
+				if err := _OracleContract.contract.UnpackLog(event, "OracleFulfillment", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseOracleFulfillment is a log parse operation binding the contract event 0xd99a3f274c0d23af67ac8304dc4b7b9e9210b9c3954677e11b27ad134f945c53.
+//
+// Solidity: event OracleFulfillment(uint256 indexed id, bytes message, bytes signature)
+func (_OracleContract *OracleContractFilterer) ParseOracleFulfillment(log types.Log) (*OracleContractOracleFulfillment, error) {
+	event := new(OracleContractOracleFulfillment)
+	if err := _OracleContract.contract.UnpackLog(event, "OracleFulfillment", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// OracleContractOracleRequestIterator is returned from FilterOracleRequest and is used to iterate over the raw logs and unpacked data for OracleRequest events raised by the OracleContract contract.
+type OracleContractOracleRequestIterator struct {
+	Event *OracleContractOracleRequest // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *OracleContractOracleRequestIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(OracleContractOracleRequest)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(OracleContractOracleRequest)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *OracleContractOracleRequestIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *OracleContractOracleRequestIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// OracleContractOracleRequest represents a OracleRequest event raised by the OracleContract contract.
+type OracleContractOracleRequest struct {
+	Id         *big.Int
+	Requester  common.Address
+	Expression string
+	Raw        types.Log // Blockchain specific contextual infos
+}
+
+// FilterOracleRequest is a free log retrieval operation binding the contract event 0x0adb5cb08122427683513087a0627389a985a4d0dde9fd4abd0661843e884011.
+//
+// Solidity: event OracleRequest(uint256 indexed id, address indexed requester, string expression)
+func (_OracleContract *OracleContractFilterer) FilterOracleRequest(opts *bind.FilterOpts, id []*big.Int, requester []common.Address) (*OracleContractOracleRequestIterator, error) {
+
+	var idRule []interface{}
+	for _, idItem := range id {
+		idRule = append(idRule, idItem)
+	}
+	var requesterRule []interface{}
+	for _, requesterItem := range requester {
+		requesterRule = append(requesterRule, requesterItem)
+	}
+
+	logs, sub, err := _OracleContract.contract.FilterLogs(opts, "OracleRequest", idRule, requesterRule)
+	if err != nil {
+		return nil, err
+	}
+	return &OracleContractOracleRequestIterator{contract: _OracleContract.contract, event: "OracleRequest", logs: logs, sub: sub}, nil
+}
+
+// WatchOracleRequest is a free log subscription operation binding the contract event 0x0adb5cb08122427683513087a0627389a985a4d0dde9fd4abd0661843e884011.
+//
+// Solidity: event OracleRequest(uint256 indexed id, address indexed requester, string expression)
+func (_OracleContract *OracleContractFilterer) WatchOracleRequest(opts *bind.WatchOpts, sink chan<- *OracleContractOracleRequest, id []*big.Int, requester []common.Address) (event.Subscription, error) {
+
+	var idRule []interface{}
+	for _, idItem := range id {
+		idRule = append(idRule, idItem)
+	}
+	var requesterRule []interface{}
+	for _, requesterItem := range requester {
+		requesterRule = append(requesterRule, requesterItem)
+	}
+
+	logs, sub, err := _OracleContract.contract.WatchLogs(opts, "OracleRequest", idRule, requesterRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(OracleContractOracleRequest)
+				if err := _OracleContract.contract.UnpackLog(event, "OracleRequest", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseOracleRequest is a log parse operation binding the contract event 0x0adb5cb08122427683513087a0627389a985a4d0dde9fd4abd0661843e884011.
+//
+// Solidity: event OracleRequest(uint256 indexed id, address indexed requester, string expression)
+func (_OracleContract *OracleContractFilterer) ParseOracleRequest(log types.Log) (*OracleContractOracleRequest, error) {
+	event := new(OracleContractOracleRequest)
+	if err := _OracleContract.contract.UnpackLog(event, "OracleRequest", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
@@ -0,0 +1,305 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ethereum
+
+//go:generate solc --abi --bin --optimize --evm-version paris --overwrite -o contracts/build contracts/Registry.sol
+//go:generate abigen --abi contracts/build/Registry.abi --bin contracts/build/Registry.bin --pkg ethereum --type RegistryContract --out registry_contract.go
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	siwacrypto "github.com/KofClubs/siwa/crypto"
+	"github.com/KofClubs/siwa/node"
+	"github.com/KofClubs/siwa/node/chain"
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.dedis.ch/kyber/v3"
+)
+
+var RegistryMismatchErr = fmt.Errorf("published group mismatches registry storage")
+
+// RegisteredGroup is the latest publication of a group in the registry contract
+type RegisteredGroup struct {
+	chain.RegisteredGroup
+	Raw types.Log
+}
+
+// Registry reads and writes groups through the registry contract, only its owner publishes and dissolves groups.
+// Publications are cached, and every read follows events in blocks since the last read.
+type Registry struct {
+	Address      common.Address
+	Backend      bind.ContractBackend
+	Contract     *RegistryContract
+	TransactOpts *bind.TransactOpts
+
+	mutex sync.Mutex
+	// nextBlock is the first block not followed yet
+	nextBlock uint64
+	// latestGroups are latest publications by group keys, nil if the group is dissolved
+	latestGroups   map[common.Hash]*RegisteredGroup
+	latestVersions map[common.Hash]uint64
+}
+
+// DeployRegistry deploys the group registry contract, the sender of transactOpts is the owner
+func DeployRegistry(transactOpts *bind.TransactOpts, backend bind.ContractBackend) (common.Address,
+	*types.Transaction, error) {
+	address, transaction, _, err := DeployRegistryContract(transactOpts, backend)
+	return address, transaction, err
+}
+
+// NewRegistry binds the registry contract at address, transactOpts is only required to publish and dissolve
+func NewRegistry(backend bind.ContractBackend, address common.Address, transactOpts *bind.TransactOpts) (*Registry,
+	error) {
+	contract, err := NewRegistryContract(address, backend)
+	if err != nil {
+		log.Error("fail to bind registry contract", "contract address", address, "err", err)
+		return nil, err
+	}
+	return &Registry{
+		Address:        address,
+		Backend:        backend,
+		Contract:       contract,
+		TransactOpts:   transactOpts,
+		latestGroups:   make(map[common.Hash]*RegisteredGroup),
+		latestVersions: make(map[common.Hash]uint64),
+	}, nil
+}
+
+// GroupKey is the key of the group in the registry contract
+func GroupKey(groupId string) common.Hash {
+	return crypto.Keccak256Hash([]byte(groupId))
+}
+
+// Publish writes threshold, node public keys by dkg indices and the distributed public key of the local group
+func (registry *Registry) Publish(ctx context.Context, groupId string) (*types.Transaction, error) {
+	group, err := node.GetGroup(groupId)
+	if err != nil {
+		log.Error("fail to publish group", "group id", groupId, "err", err)
+		return nil, err
+	}
+	distributedPublicKey, err := node.GroupPublicKey(groupId)
+	if err != nil {
+		return nil, err
+	}
+	publicKeys := make([][]byte, 0, len(group.DkgIndices))
+	for _, nodeId := range group.DkgIndices {
+		publicKeys = append(publicKeys, siwacrypto.EncodeBlsPublicKey(group.PublicKeys[nodeId]))
+	}
+
+	transactOpts, err := registry.transactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := registry.Contract.Publish(transactOpts, groupId, big.NewInt(int64(group.Threshold)),
+		group.DkgIndices, publicKeys, siwacrypto.EncodeBlsPublicKey(distributedPublicKey))
+	if err != nil {
+		log.Error("fail to publish group", "group id", groupId, "err", err)
+		return nil, err
+	}
+	log.Info("group published", "group id", groupId, "transaction", transaction.Hash())
+	return transaction, nil
+}
+
+// Dissolve marks the group dissolved in the registry contract
+func (registry *Registry) Dissolve(ctx context.Context, groupId string) (*types.Transaction, error) {
+	transactOpts, err := registry.transactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := registry.Contract.Dissolve(transactOpts, groupId)
+	if err != nil {
+		log.Error("fail to dissolve group in registry", "group id", groupId, "err", err)
+		return nil, err
+	}
+	log.Info("group dissolved in registry", "group id", groupId, "transaction", transaction.Hash())
+	return transaction, nil
+}
+
+// GetGroup returns the latest publication of the group, which is checked against the registry storage
+func (registry *Registry) GetGroup(ctx context.Context, groupId string) (*RegisteredGroup, error) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	err := registry.follow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groupKey := GroupKey(groupId)
+	group := registry.latestGroups[groupKey]
+	if group == nil {
+		return nil, chain.GroupNotRegisteredErr
+	}
+	err = registry.check(ctx, groupKey, group)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListGroups returns latest publications of all groups not dissolved in the registry contract, sorted by id
+func (registry *Registry) ListGroups(ctx context.Context) ([]*RegisteredGroup, error) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	err := registry.follow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*RegisteredGroup, 0, len(registry.latestGroups))
+	for groupKey, group := range registry.latestGroups {
+		if group == nil {
+			continue
+		}
+		err = registry.check(ctx, groupKey, group)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Id < groups[j].Id
+	})
+	return groups, nil
+}
+
+func (registry *Registry) transactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	if registry.TransactOpts == nil {
+		log.Error("nil transact opts", "err", utils.NilPtrDerefErr)
+		return nil, utils.NilPtrDerefErr
+	}
+	transactOpts := *registry.TransactOpts
+	transactOpts.Context = ctx
+	return &transactOpts, nil
+}
+
+// follow applies events in blocks since the last follow to cached publications, events are ordered by versions of
+// groups, so an event applied twice is skipped
+func (registry *Registry) follow(ctx context.Context) error {
+	header, err := registry.Backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	head := header.Number.Uint64()
+	if head < registry.nextBlock {
+		return nil
+	}
+	filterOpts := &bind.FilterOpts{Start: registry.nextBlock, End: &head, Context: ctx}
+
+	published, err := registry.Contract.FilterGroupPublished(filterOpts, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer published.Close()
+	for published.Next() {
+		group, err := unpackGroup(published.Event)
+		if err != nil {
+			log.Error("fail to unpack published group", "transaction", published.Event.Raw.TxHash, "err", err)
+			return err
+		}
+		registry.update(published.Event.GroupKey, group.Version, group)
+	}
+	if err = published.Error(); err != nil {
+		return err
+	}
+
+	dissolved, err := registry.Contract.FilterGroupDissolved(filterOpts, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer dissolved.Close()
+	for dissolved.Next() {
+		registry.update(dissolved.Event.GroupKey, dissolved.Event.Version.Uint64(), nil)
+	}
+	if err = dissolved.Error(); err != nil {
+		return err
+	}
+	registry.nextBlock = head + 1
+	return nil
+}
+
+func (registry *Registry) update(groupKey common.Hash, version uint64, group *RegisteredGroup) {
+	if version <= registry.latestVersions[groupKey] {
+		return
+	}
+	registry.latestVersions[groupKey] = version
+	registry.latestGroups[groupKey] = group
+}
+
+func unpackGroup(published *RegistryContractGroupPublished) (*RegisteredGroup, error) {
+	if len(published.NodeIds) != len(published.PublicKeys) {
+		return nil, RegistryMismatchErr
+	}
+
+	suite := siwacrypto.GetBlsSuite()
+	group := &RegisteredGroup{
+		RegisteredGroup: chain.RegisteredGroup{
+			Id:         published.GroupId,
+			Version:    published.Version.Uint64(),
+			Threshold:  int(published.Threshold.Int64()),
+			NodeIds:    published.NodeIds,
+			PublicKeys: make(map[string]kyber.Point),
+		},
+		Raw: published.Raw,
+	}
+	var err error
+	for index, nodeId := range published.NodeIds {
+		group.PublicKeys[nodeId], err = siwacrypto.DecodeBlsPublicKey(suite, published.PublicKeys[index])
+		if err != nil {
+			return nil, err
+		}
+	}
+	group.DistributedPublicKey, err = siwacrypto.DecodeBlsPublicKey(suite, published.DistributedPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// check compares the publication with the version and the hash of the distributed public key in storage, so that
+// a publication reorganised out or shadowed is not trusted. On a mismatch the cache is dropped, and followed again
+// from the first block by the next read.
+func (registry *Registry) check(ctx context.Context, groupKey common.Hash, group *RegisteredGroup) error {
+	callOpts := &bind.CallOpts{Context: ctx}
+	version, err := registry.Contract.Version(callOpts, groupKey)
+	if err != nil {
+		return err
+	}
+	publicKeyHash, err := registry.Contract.PublicKeyHash(callOpts, groupKey)
+	if err != nil {
+		return err
+	}
+	if version.Uint64() != group.Version ||
+		common.Hash(publicKeyHash) != crypto.Keccak256Hash(siwacrypto.EncodeBlsPublicKey(group.DistributedPublicKey)) {
+		log.Error("fail to check published group", "group id", group.Id, "err", RegistryMismatchErr)
+		registry.nextBlock = 0
+		registry.latestGroups = make(map[common.Hash]*RegisteredGroup)
+		registry.latestVersions = make(map[common.Hash]uint64)
+		return RegistryMismatchErr
+	}
+	return nil
+}
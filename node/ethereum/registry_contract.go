@@ -0,0 +1,650 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package ethereum
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// RegistryContractMetaData contains all meta data concerning the RegistryContract contract.
+var RegistryContractMetaData = &bind.MetaData{
+	ABI: "[{\"inputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"groupKey\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"groupId\",\"type\":\"string\"}],\"name\":\"GroupDissolved\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"bytes32\",\"name\":\"groupKey\",\"type\":\"bytes32\"},{\"indexed\":true,\"internalType\":\"uint256\",\"name\":\"version\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"groupId\",\"type\":\"string\"},{\"indexed\":false,\"internalType\":\"uint256\",\"name\":\"threshold\",\"type\":\"uint256\"},{\"indexed\":false,\"internalType\":\"string[]\",\"name\":\"nodeIds\",\"type\":\"string[]\"},{\"indexed\":false,\"internalType\":\"bytes[]\",\"name\":\"publicKeys\",\"type\":\"bytes[]\"},{\"indexed\":false,\"internalType\":\"bytes\",\"name\":\"distributedPublicKey\",\"type\":\"bytes\"}],\"name\":\"GroupPublished\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"groupId\",\"type\":\"string\"}],\"name\":\"dissolve\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"owner\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"\",\"type\":\"address\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"name\":\"publicKeyHash\",\"outputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"groupId\",\"type\":\"string\"},{\"internalType\":\"uint256\",\"name\":\"threshold\",\"type\":\"uint256\"},{\"internalType\":\"string[]\",\"name\":\"nodeIds\",\"type\":\"string[]\"},{\"internalType\":\"bytes[]\",\"name\":\"publicKeys\",\"type\":\"bytes[]\"},{\"internalType\":\"bytes\",\"name\":\"distributedPublicKey\",\"type\":\"bytes\"}],\"name\":\"publish\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"bytes32\",\"name\":\"\",\"type\":\"bytes32\"}],\"name\":\"version\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]",
+	Bin: "0x608060405234801561001057600080fd5b50600080546001600160a01b03191633179055610701806100326000396000f3fe608060405234801561001057600080fd5b50600436106100575760003560e01c8063268e05d51461005c5780638da5cb5b1461008f5780639b6fb468146100ba578063be896369146100cf578063fde40cb6146100e2575b600080fd5b61007c61006a366004610345565b60026020526000908152604090205481565b6040519081526020015b60405180910390f35b6000546100a2906001600160a01b031681565b6040516001600160a01b039091168152602001610086565b6100cd6100c83660046103ec565b610102565b005b6100cd6100dd3660046104bc565b610261565b61007c6100f0366004610345565b60016020526000908152604090205481565b6000546001600160a01b0316331461014d5760405162461bcd60e51b81526020600482015260096024820152683737ba1037bbb732b960b91b60448201526064015b60405180910390fd5b84831461019c5760405162461bcd60e51b815260206004820152601d60248201527f6e6f646520696473206d69736d61746368207075626c6963206b6579730000006044820152606401610144565b600089896040516101ae9291906104fe565b60405180910390209050600060016000838152602001908152602001600020600081546101da9061050e565b919050819055905083836040516101f29291906104fe565b60408051918290038220600085815260026020529190912055819083907f1eb7ba4ce946a1beedf2ad5ff6fd6d91f8d68a77131e53cef7a5a9d3db49f55a9061024c908f908f908f908f908f908f908f908f908f90610601565b60405180910390a35050505050505050505050565b6000546001600160a01b031633146102a75760405162461bcd60e51b81526020600482015260096024820152683737ba1037bbb732b960b91b6044820152606401610144565b600082826040516102b99291906104fe565b60405180910390209050600060016000838152602001908152602001600020600081546102e59061050e565b9190508190559050600260008381526020019081526020016000206000905580827fbbca3abd0120044d89e31e3720e31af4528a9da1c5a88fd1ef68e3d1c2b251f986866040516103379291906106af565b60405180910390a350505050565b60006020828403121561035757600080fd5b5035919050565b60008083601f84011261037057600080fd5b50813567ffffffffffffffff81111561038857600080fd5b6020830191508360208285010111156103a057600080fd5b9250929050565b60008083601f8401126103b957600080fd5b50813567ffffffffffffffff8111156103d157600080fd5b6020830191508360208260051b85010111156103a057600080fd5b600080600080600080600080600060a08a8c03121561040a57600080fd5b893567ffffffffffffffff8082111561042257600080fd5b61042e8d838e0161035e565b909b50995060208c0135985060408c013591508082111561044e57600080fd5b61045a8d838e016103a7565b909850965060608c013591508082111561047357600080fd5b61047f8d838e016103a7565b909650945060808c013591508082111561049857600080fd5b506104a58c828d0161035e565b915080935050809150509295985092959850929598565b600080602083850312156104cf57600080fd5b823567ffffffffffffffff8111156104e657600080fd5b6104f28582860161035e565b90969095509350505050565b8183823760009101908152919050565b60006001820161052e57634e487b7160e01b600052601160045260246000fd5b5060010190565b81835281816020850137506000828201602090810191909152601f909101601f19169091010190565b6000808335601e1984360301811261057557600080fd5b830160208101925035905067ffffffffffffffff81111561059557600080fd5b8036038213156103a057600080fd5b81835260006020808501808196508560051b810191508460005b878110156105f45782840389526105d5828861055e565b6105e0868284610535565b9a87019a95505050908401906001016105be565b5091979650505050505050565b60a08152600061061560a083018b8d610535565b60208381018b9052838203604085015288825281810160058a901b830182018b60005b8c81101561067257858303601f19018452610653828f61055e565b61065e858284610535565b958701959450505090840190600101610638565b50508581036060870152610687818a8c6105a4565b9350505050828103608084015261069f818587610535565b9c9b505050505050505050505050565b6020815260006106c3602083018486610535565b94935050505056fea26469706673582212203baf0fdaf4634a012f49d5c858c9ea4d7d0fd8b06037f6b8f54be0d21199cdc964736f6c63430008150033",
+}
+
+// RegistryContractABI is the input ABI used to generate the binding from.
+// Deprecated: Use RegistryContractMetaData.ABI instead.
+var RegistryContractABI = RegistryContractMetaData.ABI
+
+// RegistryContractBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use RegistryContractMetaData.Bin instead.
+var RegistryContractBin = RegistryContractMetaData.Bin
+
+// DeployRegistryContract deploys a new Ethereum contract, binding an instance of RegistryContract to it.
+func DeployRegistryContract(auth *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, *RegistryContract, error) {
+	parsed, err := RegistryContractMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(RegistryContractBin), backend)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &RegistryContract{RegistryContractCaller: RegistryContractCaller{contract: contract}, RegistryContractTransactor: RegistryContractTransactor{contract: contract}, RegistryContractFilterer: RegistryContractFilterer{contract: contract}}, nil
+}
+
+// RegistryContract is an auto generated Go binding around an Ethereum contract.
+type RegistryContract struct {
+	RegistryContractCaller     // Read-only binding to the contract
+	RegistryContractTransactor // Write-only binding to the contract
+	RegistryContractFilterer   // Log filterer for contract events
+}
+
+// RegistryContractCaller is an auto generated read-only Go binding around an Ethereum contract.
+type RegistryContractCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// RegistryContractTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type RegistryContractTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// RegistryContractFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type RegistryContractFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// RegistryContractSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type RegistryContractSession struct {
+	Contract     *RegistryContract // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// RegistryContractCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type RegistryContractCallerSession struct {
+	Contract *RegistryContractCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts           // Call options to use throughout this session
+}
+
+// RegistryContractTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type RegistryContractTransactorSession struct {
+	Contract     *RegistryContractTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts           // Transaction auth options to use throughout this session
+}
+
+// RegistryContractRaw is an auto generated low-level Go binding around an Ethereum contract.
+type RegistryContractRaw struct {
+	Contract *RegistryContract // Generic contract binding to access the raw methods on
+}
+
+// RegistryContractCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type RegistryContractCallerRaw struct {
+	Contract *RegistryContractCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// RegistryContractTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type RegistryContractTransactorRaw struct {
+	Contract *RegistryContractTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewRegistryContract creates a new instance of RegistryContract, bound to a specific deployed contract.
+func NewRegistryContract(address common.Address, backend bind.ContractBackend) (*RegistryContract, error) {
+	contract, err := bindRegistryContract(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryContract{RegistryContractCaller: RegistryContractCaller{contract: contract}, RegistryContractTransactor: RegistryContractTransactor{contract: contract}, RegistryContractFilterer: RegistryContractFilterer{contract: contract}}, nil
+}
+
+// NewRegistryContractCaller creates a new read-only instance of RegistryContract, bound to a specific deployed contract.
+func NewRegistryContractCaller(address common.Address, caller bind.ContractCaller) (*RegistryContractCaller, error) {
+	contract, err := bindRegistryContract(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryContractCaller{contract: contract}, nil
+}
+
+// NewRegistryContractTransactor creates a new write-only instance of RegistryContract, bound to a specific deployed contract.
+func NewRegistryContractTransactor(address common.Address, transactor bind.ContractTransactor) (*RegistryContractTransactor, error) {
+	contract, err := bindRegistryContract(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryContractTransactor{contract: contract}, nil
+}
+
+// NewRegistryContractFilterer creates a new log filterer instance of RegistryContract, bound to a specific deployed contract.
+func NewRegistryContractFilterer(address common.Address, filterer bind.ContractFilterer) (*RegistryContractFilterer, error) {
+	contract, err := bindRegistryContract(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryContractFilterer{contract: contract}, nil
+}
+
+// bindRegistryContract binds a generic wrapper to an already deployed contract.
+func bindRegistryContract(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := RegistryContractMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_RegistryContract *RegistryContractRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _RegistryContract.Contract.RegistryContractCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_RegistryContract *RegistryContractRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _RegistryContract.Contract.RegistryContractTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_RegistryContract *RegistryContractRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _RegistryContract.Contract.RegistryContractTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_RegistryContract *RegistryContractCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _RegistryContract.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_RegistryContract *RegistryContractTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _RegistryContract.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_RegistryContract *RegistryContractTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _RegistryContract.Contract.contract.Transact(opts, method, params...)
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_RegistryContract *RegistryContractCaller) Owner(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	err := _RegistryContract.contract.Call(opts, &out, "owner")
+
+	if err != nil {
+		return *new(common.Address), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+
+	return out0, err
+
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_RegistryContract *RegistryContractSession) Owner() (common.Address, error) {
+	return _RegistryContract.Contract.Owner(&_RegistryContract.CallOpts)
+}
+
+// Owner is a free data retrieval call binding the contract method 0x8da5cb5b.
+//
+// Solidity: function owner() view returns(address)
+func (_RegistryContract *RegistryContractCallerSession) Owner() (common.Address, error) {
+	return _RegistryContract.Contract.Owner(&_RegistryContract.CallOpts)
+}
+
+// PublicKeyHash is a free data retrieval call binding the contract method 0x268e05d5.
+//
+// Solidity: function publicKeyHash(bytes32 ) view returns(bytes32)
+func (_RegistryContract *RegistryContractCaller) PublicKeyHash(opts *bind.CallOpts, arg0 [32]byte) ([32]byte, error) {
+	var out []interface{}
+	err := _RegistryContract.contract.Call(opts, &out, "publicKeyHash", arg0)
+
+	if err != nil {
+		return *new([32]byte), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([32]byte)).(*[32]byte)
+
+	return out0, err
+
+}
+
+// PublicKeyHash is a free data retrieval call binding the contract method 0x268e05d5.
+//
+// Solidity: function publicKeyHash(bytes32 ) view returns(bytes32)
+func (_RegistryContract *RegistryContractSession) PublicKeyHash(arg0 [32]byte) ([32]byte, error) {
+	return _RegistryContract.Contract.PublicKeyHash(&_RegistryContract.CallOpts, arg0)
+}
+
+// PublicKeyHash is a free data retrieval call binding the contract method 0x268e05d5.
+//
+// Solidity: function publicKeyHash(bytes32 ) view returns(bytes32)
+func (_RegistryContract *RegistryContractCallerSession) PublicKeyHash(arg0 [32]byte) ([32]byte, error) {
+	return _RegistryContract.Contract.PublicKeyHash(&_RegistryContract.CallOpts, arg0)
+}
+
+// Version is a free data retrieval call binding the contract method 0xfde40cb6.
+//
+// Solidity: function version(bytes32 ) view returns(uint256)
+func (_RegistryContract *RegistryContractCaller) Version(opts *bind.CallOpts, arg0 [32]byte) (*big.Int, error) {
+	var out []interface{}
+	err := _RegistryContract.contract.Call(opts, &out, "version", arg0)
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// Version is a free data retrieval call binding the contract method 0xfde40cb6.
+//
+// Solidity: function version(bytes32 ) view returns(uint256)
+func (_RegistryContract *RegistryContractSession) Version(arg0 [32]byte) (*big.Int, error) {
+	return _RegistryContract.Contract.Version(&_RegistryContract.CallOpts, arg0)
+}
+
+// Version is a free data retrieval call binding the contract method 0xfde40cb6.
+//
+// Solidity: function version(bytes32 ) view returns(uint256)
+func (_RegistryContract *RegistryContractCallerSession) Version(arg0 [32]byte) (*big.Int, error) {
+	return _RegistryContract.Contract.Version(&_RegistryContract.CallOpts, arg0)
+}
+
+// Dissolve is a paid mutator transaction binding the contract method 0xbe896369.
+//
+// Solidity: function dissolve(string groupId) returns()
+func (_RegistryContract *RegistryContractTransactor) Dissolve(opts *bind.TransactOpts, groupId string) (*types.Transaction, error) {
+	return _RegistryContract.contract.Transact(opts, "dissolve", groupId)
+}
+
+// Dissolve is a paid mutator transaction binding the contract method 0xbe896369.
+//
+// Solidity: function dissolve(string groupId) returns()
+func (_RegistryContract *RegistryContractSession) Dissolve(groupId string) (*types.Transaction, error) {
+	return _RegistryContract.Contract.Dissolve(&_RegistryContract.TransactOpts, groupId)
+}
+
+// Dissolve is a paid mutator transaction binding the contract method 0xbe896369.
+//
+// Solidity: function dissolve(string groupId) returns()
+func (_RegistryContract *RegistryContractTransactorSession) Dissolve(groupId string) (*types.Transaction, error) {
+	return _RegistryContract.Contract.Dissolve(&_RegistryContract.TransactOpts, groupId)
+}
+
+// Publish is a paid mutator transaction binding the contract method 0x9b6fb468.
+//
+// Solidity: function publish(string groupId, uint256 threshold, string[] nodeIds, bytes[] publicKeys, bytes distributedPublicKey) returns()
+func (_RegistryContract *RegistryContractTransactor) Publish(opts *bind.TransactOpts, groupId string, threshold *big.Int, nodeIds []string, publicKeys [][]byte, distributedPublicKey []byte) (*types.Transaction, error) {
+	return _RegistryContract.contract.Transact(opts, "publish", groupId, threshold, nodeIds, publicKeys, distributedPublicKey)
+}
+
+// Publish is a paid mutator transaction binding the contract method 0x9b6fb468.
+//
+// Solidity: function publish(string groupId, uint256 threshold, string[] nodeIds, bytes[] publicKeys, bytes distributedPublicKey) returns()
+func (_RegistryContract *RegistryContractSession) Publish(groupId string, threshold *big.Int, nodeIds []string, publicKeys [][]byte, distributedPublicKey []byte) (*types.Transaction, error) {
+	return _RegistryContract.Contract.Publish(&_RegistryContract.TransactOpts, groupId, threshold, nodeIds, publicKeys, distributedPublicKey)
+}
+
+// Publish is a paid mutator transaction binding the contract method 0x9b6fb468.
+//
+// Solidity: function publish(string groupId, uint256 threshold, string[] nodeIds, bytes[] publicKeys, bytes distributedPublicKey) returns()
+func (_RegistryContract *RegistryContractTransactorSession) Publish(groupId string, threshold *big.Int, nodeIds []string, publicKeys [][]byte, distributedPublicKey []byte) (*types.Transaction, error) {
+	return _RegistryContract.Contract.Publish(&_RegistryContract.TransactOpts, groupId, threshold, nodeIds, publicKeys, distributedPublicKey)
+}
+
+// RegistryContractGroupDissolvedIterator is returned from FilterGroupDissolved and is used to iterate over the raw logs and unpacked data for GroupDissolved events raised by the RegistryContract contract.
+type RegistryContractGroupDissolvedIterator struct {
+	Event *RegistryContractGroupDissolved // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *RegistryContractGroupDissolvedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(RegistryContractGroupDissolved)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(RegistryContractGroupDissolved)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *RegistryContractGroupDissolvedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *RegistryContractGroupDissolvedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// RegistryContractGroupDissolved represents a GroupDissolved event raised by the RegistryContract contract.
+type RegistryContractGroupDissolved struct {
+	GroupKey [32]byte
+	Version  *big.Int
+	GroupId  string
+	Raw      types.Log // Blockchain specific contextual infos
+}
+
+// FilterGroupDissolved is a free log retrieval operation binding the contract event 0xbbca3abd0120044d89e31e3720e31af4528a9da1c5a88fd1ef68e3d1c2b251f9.
+//
+// Solidity: event GroupDissolved(bytes32 indexed groupKey, uint256 indexed version, string groupId)
+func (_RegistryContract *RegistryContractFilterer) FilterGroupDissolved(opts *bind.FilterOpts, groupKey [][32]byte, version []*big.Int) (*RegistryContractGroupDissolvedIterator, error) {
+
+	var groupKeyRule []interface{}
+	for _, groupKeyItem := range groupKey {
+		groupKeyRule = append(groupKeyRule, groupKeyItem)
+	}
+	var versionRule []interface{}
+	for _, versionItem := range version {
+		versionRule = append(versionRule, versionItem)
+	}
+
+	logs, sub, err := _RegistryContract.contract.FilterLogs(opts, "GroupDissolved", groupKeyRule, versionRule)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryContractGroupDissolvedIterator{contract: _RegistryContract.contract, event: "GroupDissolved", logs: logs, sub: sub}, nil
+}
+
+// WatchGroupDissolved is a free log subscription operation binding the contract event 0xbbca3abd0120044d89e31e3720e31af4528a9da1c5a88fd1ef68e3d1c2b251f9.
+//
+// Solidity: event GroupDissolved(bytes32 indexed groupKey, uint256 indexed version, string groupId)
+func (_RegistryContract *RegistryContractFilterer) WatchGroupDissolved(opts *bind.WatchOpts, sink chan<- *RegistryContractGroupDissolved, groupKey [][32]byte, version []*big.Int) (event.Subscription, error) {
+
+	var groupKeyRule []interface{}
+	for _, groupKeyItem := range groupKey {
+		groupKeyRule = append(groupKeyRule, groupKeyItem)
+	}
+	var versionRule []interface{}
+	for _, versionItem := range version {
+		versionRule = append(versionRule, versionItem)
+	}
+
+	logs, sub, err := _RegistryContract.contract.WatchLogs(opts, "GroupDissolved", groupKeyRule, versionRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(RegistryContractGroupDissolved)
+				if err := _RegistryContract.contract.UnpackLog(event, "GroupDissolved", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseGroupDissolved is a log parse operation binding the contract event 0xbbca3abd0120044d89e31e3720e31af4528a9da1c5a88fd1ef68e3d1c2b251f9.
+//
+// Solidity: event GroupDissolved(bytes32 indexed groupKey, uint256 indexed version, string groupId)
+func (_RegistryContract *RegistryContractFilterer) ParseGroupDissolved(log types.Log) (*RegistryContractGroupDissolved, error) {
+	event := new(RegistryContractGroupDissolved)
+	if err := _RegistryContract.contract.UnpackLog(event, "GroupDissolved", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// RegistryContractGroupPublishedIterator is returned from FilterGroupPublished and is used to iterate over the raw logs and unpacked data for GroupPublished events raised by the RegistryContract contract.
+type RegistryContractGroupPublishedIterator struct {
+	Event *RegistryContractGroupPublished // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *RegistryContractGroupPublishedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(RegistryContractGroupPublished)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(RegistryContractGroupPublished)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *RegistryContractGroupPublishedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *RegistryContractGroupPublishedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// RegistryContractGroupPublished represents a GroupPublished event raised by the RegistryContract contract.
+type RegistryContractGroupPublished struct {
+	GroupKey             [32]byte
+	Version              *big.Int
+	GroupId              string
+	Threshold            *big.Int
+	NodeIds              []string
+	PublicKeys           [][]byte
+	DistributedPublicKey []byte
+	Raw                  types.Log // Blockchain specific contextual infos
+}
+
+// FilterGroupPublished is a free log retrieval operation binding the contract event 0x1eb7ba4ce946a1beedf2ad5ff6fd6d91f8d68a77131e53cef7a5a9d3db49f55a.
+//
+// Solidity: event GroupPublished(bytes32 indexed groupKey, uint256 indexed version, string groupId, uint256 threshold, string[] nodeIds, bytes[] publicKeys, bytes distributedPublicKey)
+func (_RegistryContract *RegistryContractFilterer) FilterGroupPublished(opts *bind.FilterOpts, groupKey [][32]byte, version []*big.Int) (*RegistryContractGroupPublishedIterator, error) {
+
+	var groupKeyRule []interface{}
+	for _, groupKeyItem := range groupKey {
+		groupKeyRule = append(groupKeyRule, groupKeyItem)
+	}
+	var versionRule []interface{}
+	for _, versionItem := range version {
+		versionRule = append(versionRule, versionItem)
+	}
+
+	logs, sub, err := _RegistryContract.contract.FilterLogs(opts, "GroupPublished", groupKeyRule, versionRule)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryContractGroupPublishedIterator{contract: _RegistryContract.contract, event: "GroupPublished", logs: logs, sub: sub}, nil
+}
+
+// WatchGroupPublished is a free log subscription operation binding the contract event 0x1eb7ba4ce946a1beedf2ad5ff6fd6d91f8d68a77131e53cef7a5a9d3db49f55a.
+//
+// Solidity: event GroupPublished(bytes32 indexed groupKey, uint256 indexed version, string groupId, uint256 threshold, string[] nodeIds, bytes[] publicKeys, bytes distributedPublicKey)
+func (_RegistryContract *RegistryContractFilterer) WatchGroupPublished(opts *bind.WatchOpts, sink chan<- *RegistryContractGroupPublished, groupKey [][32]byte, version []*big.Int) (event.Subscription, error) {
+
+	var groupKeyRule []interface{}
+	for _, groupKeyItem := range groupKey {
+		groupKeyRule = append(groupKeyRule, groupKeyItem)
+	}
+	var versionRule []interface{}
+	for _, versionItem := range version {
+		versionRule = append(versionRule, versionItem)
+	}
+
+	logs, sub, err := _RegistryContract.contract.WatchLogs(opts, "GroupPublished", groupKeyRule, versionRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(RegistryContractGroupPublished)
+				if err := _RegistryContract.contract.UnpackLog(event, "GroupPublished", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseGroupPublished is a log parse operation binding the contract event 0x1eb7ba4ce946a1beedf2ad5ff6fd6d91f8d68a77131e53cef7a5a9d3db49f55a.
+//
+// Solidity: event GroupPublished(bytes32 indexed groupKey, uint256 indexed version, string groupId, uint256 threshold, string[] nodeIds, bytes[] publicKeys, bytes distributedPublicKey)
+func (_RegistryContract *RegistryContractFilterer) ParseGroupPublished(log types.Log) (*RegistryContractGroupPublished, error) {
+	event := new(RegistryContractGroupPublished)
+	if err := _RegistryContract.contract.UnpackLog(event, "GroupPublished", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
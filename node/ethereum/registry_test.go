@@ -0,0 +1,140 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/KofClubs/siwa/node"
+	"github.com/KofClubs/siwa/node/chain"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	group, otherGroup := createGroup(t, 3), createGroup(t, 2)
+
+	ownerOpts, consumerOpts := newTransactOpts(t), newTransactOpts(t)
+	balance := new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		ownerOpts.From:    {Balance: balance},
+		consumerOpts.From: {Balance: balance},
+	}, 30_000_000)
+	defer backend.Close()
+
+	// 1. deploy the contract, and publish groups by the owner only
+	address, _, err := DeployRegistry(ownerOpts, backend)
+	require.Nil(t, err)
+	backend.Commit()
+	registry, err := NewRegistry(backend, address, ownerOpts)
+	require.Nil(t, err)
+	owner, err := registry.Contract.Owner(nil)
+	require.Nil(t, err)
+	assert.Equal(t, ownerOpts.From, owner)
+
+	_, err = registry.GetGroup(ctx, group.Id)
+	assert.Equal(t, chain.GroupNotRegisteredErr, err)
+	_, err = registry.Publish(ctx, group.Id)
+	require.Nil(t, err)
+	_, err = registry.Publish(ctx, otherGroup.Id)
+	require.Nil(t, err)
+	_, err = registry.Publish(ctx, "not existed")
+	assert.Equal(t, node.GroupNotExistedErr, err)
+	consumer, err := NewRegistry(backend, address, consumerOpts)
+	require.Nil(t, err)
+	_, err = consumer.Publish(ctx, group.Id)
+	assert.NotNil(t, err)
+	_, err = consumer.Dissolve(ctx, group.Id)
+	assert.NotNil(t, err)
+	backend.Commit()
+
+	// 2. consumers discover groups, with node public keys by dkg indices and the distributed public key
+	registeredGroup, err := consumer.GetGroup(ctx, group.Id)
+	require.Nil(t, err)
+	header, err := backend.HeaderByNumber(ctx, nil)
+	require.Nil(t, err)
+	assert.Equal(t, header.Number.Uint64()+1, consumer.nextBlock)
+	assert.Equal(t, group.Id, registeredGroup.Id)
+	assert.Equal(t, uint64(1), registeredGroup.Version)
+	assert.Equal(t, group.Threshold, registeredGroup.Threshold)
+	assert.Equal(t, group.DkgIndices, registeredGroup.NodeIds)
+	for _, nodeId := range group.DkgIndices {
+		assert.True(t, group.PublicKeys[nodeId].Equal(registeredGroup.PublicKeys[nodeId]), nodeId)
+	}
+	groupPublicKey, err := node.GroupPublicKey(group.Id)
+	require.Nil(t, err)
+	assert.True(t, groupPublicKey.Equal(registeredGroup.DistributedPublicKey))
+
+	registeredGroups, err := consumer.ListGroups(ctx)
+	require.Nil(t, err)
+	require.Len(t, registeredGroups, 2)
+
+	// 3. the latest publication wins, and a dissolved group is not listed
+	_, err = registry.Publish(ctx, group.Id)
+	require.Nil(t, err)
+	_, err = registry.Dissolve(ctx, otherGroup.Id)
+	require.Nil(t, err)
+	backend.Commit()
+
+	registeredGroup, err = consumer.GetGroup(ctx, group.Id)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(2), registeredGroup.Version)
+	_, err = consumer.GetGroup(ctx, otherGroup.Id)
+	assert.Equal(t, chain.GroupNotRegisteredErr, err)
+	registeredGroups, err = consumer.ListGroups(ctx)
+	require.Nil(t, err)
+	require.Len(t, registeredGroups, 1)
+	assert.Equal(t, group.Id, registeredGroups[0].Id)
+
+	publicKeyHash, err := consumer.Contract.PublicKeyHash(nil, GroupKey(otherGroup.Id))
+	require.Nil(t, err)
+	assert.Equal(t, [32]byte{}, publicKeyHash)
+	version, err := consumer.Contract.Version(&bind.CallOpts{}, GroupKey(otherGroup.Id))
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), version.Int64())
+
+	// 4. a group published again after dissolution is discovered
+	_, err = registry.Publish(ctx, otherGroup.Id)
+	require.Nil(t, err)
+	backend.Commit()
+	registeredGroup, err = consumer.GetGroup(ctx, otherGroup.Id)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(3), registeredGroup.Version)
+	otherPublicKey, err := node.GroupPublicKey(otherGroup.Id)
+	require.Nil(t, err)
+	assert.True(t, otherPublicKey.Equal(registeredGroup.DistributedPublicKey))
+
+	// 5. a cached publication mismatching the storage is dropped, and followed again by the next read
+	consumer.latestGroups[GroupKey(otherGroup.Id)].Version = 1
+	_, err = consumer.GetGroup(ctx, otherGroup.Id)
+	assert.Equal(t, RegistryMismatchErr, err)
+	assert.Zero(t, consumer.nextBlock)
+	registeredGroup, err = consumer.GetGroup(ctx, otherGroup.Id)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(3), registeredGroup.Version)
+}
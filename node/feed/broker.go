@@ -0,0 +1,256 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MonteCarloClub/log"
+)
+
+const (
+	DefaultHistorySize = 1024
+	// subscriberBufferSize is count of events buffered for a subscriber, a slower subscriber is disconnected, and
+	// resumes from its last event id
+	subscriberBufferSize = 64
+	keepAliveInterval    = 15 * time.Second
+)
+
+type brokerEvent struct {
+	id           uint64
+	signedReport *SignedReport
+}
+
+type subscriber struct {
+	feed       string
+	expression string
+	events     chan brokerEvent
+}
+
+func (subscriber *subscriber) accepts(signedReport *SignedReport) bool {
+	return (subscriber.feed == "" || subscriber.feed == signedReport.Feed) &&
+		(subscriber.expression == "" || subscriber.expression == signedReport.Expression)
+}
+
+// eventId is the epoch of the broker and the sequence of the event in the epoch, formatted as epoch-sequence
+type eventId struct {
+	epoch    int64
+	sequence uint64
+}
+
+func (eventId eventId) String() string {
+	return fmt.Sprintf("%v-%v", eventId.epoch, eventId.sequence)
+}
+
+// parseEventId parses epoch-sequence, or a bare sequence of an unknown epoch
+func parseEventId(text string) (eventId, error) {
+	epochText, sequenceText, ok := strings.Cut(text, "-")
+	if !ok {
+		epochText, sequenceText = "0", text
+	}
+	epoch, err := strconv.ParseInt(epochText, 10, 64)
+	if err != nil {
+		return eventId{}, err
+	}
+	sequence, err := strconv.ParseUint(sequenceText, 10, 64)
+	if err != nil {
+		return eventId{}, err
+	}
+	return eventId{epoch: epoch, sequence: sequence}, nil
+}
+
+// Broker is a sink streaming reports to subscribers in server-sent events, events have ids increasing across feeds,
+// and recent ones are kept for subscribers to resume from. Event ids are prefixed by the epoch of the broker, since
+// they restart from 1 after a restart.
+type Broker struct {
+	HistorySize int
+	// Epoch is the unix time in milliseconds when the broker was created
+	Epoch int64
+
+	mutex       sync.Mutex
+	lastId      uint64
+	history     []brokerEvent
+	subscribers map[*subscriber]struct{}
+}
+
+// NewBroker keeps historySize recent events, DefaultHistorySize if not positive
+func NewBroker(historySize int) *Broker {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &Broker{
+		HistorySize: historySize,
+		Epoch:       time.Now().UnixMilli(),
+		history:     make([]brokerEvent, 0, historySize),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+func (broker *Broker) Send(ctx context.Context, signedReport *SignedReport) error {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.lastId++
+	event := brokerEvent{
+		id:           broker.lastId,
+		signedReport: signedReport,
+	}
+	if len(broker.history) == broker.HistorySize {
+		broker.history = append(broker.history[:0], broker.history[1:]...)
+	}
+	broker.history = append(broker.history, event)
+
+	for subscriber := range broker.subscribers {
+		if !subscriber.accepts(signedReport) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			log.Warn("subscriber too slow, disconnect it", "feed", subscriber.feed, "expression",
+				subscriber.expression, "event id", event.id)
+			delete(broker.subscribers, subscriber)
+			close(subscriber.events)
+		}
+	}
+	return nil
+}
+
+// subscribe returns events after the last event id to replay, and whether some of them are no longer kept. All kept
+// events are replayed after an event id of another epoch or beyond the last one, since reports may be missed.
+func (broker *Broker) subscribe(subscriber *subscriber, lastEventId *eventId) ([]brokerEvent, bool) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.subscribers[subscriber] = struct{}{}
+	if lastEventId == nil {
+		return nil, false
+	}
+	lastSequence := lastEventId.sequence
+	missed := false
+	if lastEventId.epoch != broker.Epoch || lastSequence > broker.lastId {
+		lastSequence, missed = 0, true
+	}
+	replay := make([]brokerEvent, 0)
+	for _, event := range broker.history {
+		if event.id > lastSequence && subscriber.accepts(event.signedReport) {
+			replay = append(replay, event)
+		}
+	}
+	if lastSequence < broker.lastId && (len(broker.history) == 0 || broker.history[0].id > lastSequence+1) {
+		missed = true
+	}
+	return replay, missed
+}
+
+func (broker *Broker) unsubscribe(subscriber *subscriber) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	if _, ok := broker.subscribers[subscriber]; ok {
+		delete(broker.subscribers, subscriber)
+		close(subscriber.events)
+	}
+}
+
+// ServeHTTP streams reports of the feed and the expression in query parameters, or all reports if neither, clients
+// resume after the Last-Event-ID header, or the last_event_id query parameter
+func (broker *Broker) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		http.Error(writer, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	var lastEventId *eventId
+	lastEventIdText := request.Header.Get("Last-Event-ID")
+	if lastEventIdText == "" {
+		lastEventIdText = request.URL.Query().Get("last_event_id")
+	}
+	if lastEventIdText != "" {
+		id, err := parseEventId(lastEventIdText)
+		if err != nil {
+			http.Error(writer, "illegal last event id", http.StatusBadRequest)
+			return
+		}
+		lastEventId = &id
+	}
+
+	subscriber := &subscriber{
+		feed:       request.URL.Query().Get("feed"),
+		expression: request.URL.Query().Get("expression"),
+		events:     make(chan brokerEvent, subscriberBufferSize),
+	}
+	replay, missed := broker.subscribe(subscriber, lastEventId)
+	defer broker.unsubscribe(subscriber)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	if missed {
+		// reports between the last event id and the first replayed one are no longer kept, or lost in a restart
+		_, _ = fmt.Fprintf(writer, "event: gap\ndata: {}\n\n")
+	}
+	for _, event := range replay {
+		if broker.writeEvent(writer, event) != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err := fmt.Fprint(writer, ": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+		case event, ok := <-subscriber.events:
+			if !ok || broker.writeEvent(writer, event) != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (broker *Broker) writeEvent(writer http.ResponseWriter, event brokerEvent) error {
+	data, err := json.Marshal(event.signedReport)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "id: %v\nevent: report\ndata: %s\n\n", eventId{epoch: broker.Epoch,
+		sequence: event.id}, data)
+	return err
+}
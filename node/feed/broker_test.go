@@ -0,0 +1,183 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package feed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamEvent struct {
+	id           string
+	event        string
+	signedReport SignedReport
+}
+
+// readEvents reads count events from a server-sent event stream
+func readEvents(t *testing.T, reader *bufio.Reader, count int) []streamEvent {
+	events := make([]streamEvent, 0, count)
+	event := streamEvent{}
+	for len(events) < count {
+		line, err := reader.ReadString('\n')
+		require.Nil(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			events = append(events, event)
+			event = streamEvent{}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event.event == "report":
+			require.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.signedReport))
+		}
+	}
+	return events
+}
+
+func subscribe(t *testing.T, url string, lastEventId string) (*bufio.Reader, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.Nil(t, err)
+	if lastEventId != "" {
+		request.Header.Set("Last-Event-ID", lastEventId)
+	}
+	response, err := http.DefaultClient.Do(request)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	return bufio.NewReader(response.Body), func() {
+		cancel()
+		_ = response.Body.Close()
+	}
+}
+
+// waitSubscribers waits until the broker has count subscribers
+func waitSubscribers(broker *Broker, count int) {
+	for i := 0; i < 100; i++ {
+		broker.mutex.Lock()
+		subscribed := len(broker.subscribers) == count
+		broker.mutex.Unlock()
+		if subscribed {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func idOf(broker *Broker, sequence uint64) string {
+	return eventId{epoch: broker.Epoch, sequence: sequence}.String()
+}
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker(0)
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	for _, feed := range []string{"a", "b", "a"} {
+		require.Nil(t, broker.Send(ctx, &SignedReport{Feed: feed, Expression: "e." + feed}))
+	}
+
+	// resume after event 1 of feed a, then receive new reports
+	reader, unsubscribe := subscribe(t, server.URL+"?feed=a", idOf(broker, 1))
+	defer unsubscribe()
+	events := readEvents(t, reader, 1)
+	assert.Equal(t, idOf(broker, 3), events[0].id)
+	assert.Equal(t, "a", events[0].signedReport.Feed)
+
+	allReader, unsubscribeAll := subscribe(t, server.URL+"?expression=e.b", "")
+	defer unsubscribeAll()
+	waitSubscribers(broker, 2)
+	for _, feed := range []string{"b", "a"} {
+		require.Nil(t, broker.Send(ctx, &SignedReport{Feed: feed, Expression: "e." + feed}))
+	}
+	events = readEvents(t, reader, 1)
+	assert.Equal(t, idOf(broker, 5), events[0].id)
+	events = readEvents(t, allReader, 1)
+	assert.Equal(t, idOf(broker, 4), events[0].id)
+	assert.Equal(t, "report", events[0].event)
+
+	response, err := http.Get(server.URL + "?last_event_id=x")
+	require.Nil(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
+
+func TestBrokerHistory(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker(2)
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	for i := 0; i < 3; i++ {
+		require.Nil(t, broker.Send(ctx, &SignedReport{Feed: "a"}))
+	}
+
+	// event 1 is no longer kept
+	reader, unsubscribe := subscribe(t, server.URL+"?last_event_id="+idOf(broker, 0), "")
+	defer unsubscribe()
+	events := readEvents(t, reader, 3)
+	assert.Equal(t, "gap", events[0].event)
+	assert.Equal(t, idOf(broker, 2), events[1].id)
+	assert.Equal(t, idOf(broker, 3), events[2].id)
+
+	// slow subscribers are disconnected
+	subscriber := &subscriber{events: make(chan brokerEvent)}
+	_, missed := broker.subscribe(subscriber, nil)
+	assert.False(t, missed)
+	require.Nil(t, broker.Send(ctx, &SignedReport{Feed: "a"}))
+	_, ok := <-subscriber.events
+	assert.False(t, ok)
+}
+
+func TestBrokerRestart(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker(0)
+	require.Nil(t, broker.Send(ctx, &SignedReport{Feed: "a"}))
+
+	// event ids of another epoch, or beyond the last one, replay all kept events after a gap
+	for _, lastEventId := range []string{"1-5", "5", idOf(broker, 5)} {
+		id, err := parseEventId(lastEventId)
+		require.Nil(t, err)
+		replay, missed := broker.subscribe(&subscriber{events: make(chan brokerEvent, 1)}, &id)
+		assert.True(t, missed, lastEventId)
+		assert.Len(t, replay, 1, lastEventId)
+	}
+	id, err := parseEventId(idOf(broker, 1))
+	require.Nil(t, err)
+	replay, missed := broker.subscribe(&subscriber{events: make(chan brokerEvent, 1)}, &id)
+	assert.False(t, missed)
+	assert.Empty(t, replay)
+
+	_, err = parseEventId("1-x")
+	assert.NotNil(t, err)
+}
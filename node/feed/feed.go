@@ -0,0 +1,251 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package feed publishes signed reports of groups on schedules, by heartbeat and deviation triggers
+package feed
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KofClubs/siwa/node"
+	"github.com/MonteCarloClub/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DefaultPollInterval = time.Second
+
+var IllegalFeedErr = fmt.Errorf("illegal feed, either heartbeat or deviation should be positive")
+
+var tracer = otel.Tracer("github.com/KofClubs/siwa/node/feed")
+
+type UnmarshalledFeed struct {
+	Name       string `yaml:"name"`
+	GroupId    string `yaml:"group_id"`
+	Expression string `yaml:"expression"`
+	// Heartbeat publishes if no report was published for this long, 0 for deviation triggers only
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// Deviation publishes if the value moves more than this fraction from the last published one, 0 for heartbeat
+	// triggers only
+	Deviation float64 `yaml:"deviation"`
+	// PollInterval is how often the value is queried for deviation triggers, DefaultPollInterval if not positive
+	PollInterval time.Duration      `yaml:"poll_interval"`
+	Sinks        []UnmarshalledSink `yaml:"sinks"`
+}
+
+// SignedReport is a report of a feed sent to sinks, the message and the signature are hex encoded, since messages
+// may be binary, like uint256 encoded values. The signature is of crypto.SignedPayload of the group id, an
+// empty request id, the expression and the message.
+type SignedReport struct {
+	Feed string `json:"feed"`
+	// Epoch is the unix time in milliseconds when the feed was created, sequences restart from 1 in a new epoch, so
+	// reports of a feed are ordered by epoch and then sequence without duplicates or gaps
+	Epoch      int64     `json:"epoch"`
+	Sequence   uint64    `json:"sequence"`
+	GroupId    string    `json:"group_id"`
+	Expression string    `json:"expression"`
+	Message    string    `json:"message"`
+	Signature  string    `json:"signature"`
+	Signers    []string  `json:"signers"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+type Feed struct {
+	Name         string
+	GroupId      string
+	Expression   string
+	Heartbeat    time.Duration
+	Deviation    float64
+	PollInterval time.Duration
+	Sinks        []Sink
+	// Epoch identifies this run of the feed, see SignedReport
+	Epoch int64
+
+	sequence        uint64
+	lastMessage     string
+	lastPublishedAt time.Time
+}
+
+func (unmarshalledFeed *UnmarshalledFeed) CreateFeed() (*Feed, error) {
+	if unmarshalledFeed.Heartbeat <= 0 && unmarshalledFeed.Deviation <= 0 {
+		log.Error("fail to create feed", "name", unmarshalledFeed.Name, "err", IllegalFeedErr)
+		return nil, IllegalFeedErr
+	}
+
+	feed := &Feed{
+		Name:         unmarshalledFeed.Name,
+		GroupId:      unmarshalledFeed.GroupId,
+		Expression:   unmarshalledFeed.Expression,
+		Heartbeat:    unmarshalledFeed.Heartbeat,
+		Deviation:    unmarshalledFeed.Deviation,
+		PollInterval: unmarshalledFeed.PollInterval,
+		Sinks:        make([]Sink, 0, len(unmarshalledFeed.Sinks)),
+		Epoch:        time.Now().UnixMilli(),
+	}
+	if feed.Name == "" {
+		feed.Name = fmt.Sprintf("%v/%v", feed.GroupId, feed.Expression)
+	}
+	if feed.PollInterval <= 0 {
+		feed.PollInterval = DefaultPollInterval
+	}
+	for _, unmarshalledSink := range unmarshalledFeed.Sinks {
+		sink, err := unmarshalledSink.CreateSink()
+		if err != nil {
+			return nil, err
+		}
+		feed.Sinks = append(feed.Sinks, sink)
+	}
+	return feed, nil
+}
+
+// interval is how often the feed is queried
+func (feed *Feed) interval() time.Duration {
+	if feed.Deviation > 0 {
+		return feed.PollInterval
+	}
+	return feed.Heartbeat
+}
+
+// Scheduler runs feeds, each feed is queried by its group on its own schedule
+type Scheduler struct {
+	Feeds []*Feed
+
+	queryGroup func(ctx context.Context, groupId, expression string) (*node.Report, error)
+	now        func() time.Time
+}
+
+func NewScheduler(feeds []*Feed) *Scheduler {
+	return &Scheduler{
+		Feeds:      feeds,
+		queryGroup: node.QueryGroup,
+		now:        time.Now,
+	}
+}
+
+// Run runs all feeds until ctx is done
+func (scheduler *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, feed := range scheduler.Feeds {
+		wg.Add(1)
+		go func(feed *Feed) {
+			defer wg.Done()
+			ticker := time.NewTicker(feed.interval())
+			defer ticker.Stop()
+			for {
+				scheduler.tick(ctx, feed)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(feed)
+	}
+	wg.Wait()
+}
+
+// tick queries the feed, and publishes the report if triggered, it returns whether published
+func (scheduler *Scheduler) tick(ctx context.Context, feed *Feed) bool {
+	now := scheduler.now()
+	// sinks continue the trace of the tick
+	ctx, span := tracer.Start(ctx, "Feed.Tick", trace.WithAttributes(attribute.String("siwa.feed", feed.Name)))
+	defer span.End()
+	report, err := scheduler.queryGroup(ctx, feed.GroupId, feed.Expression)
+	if err != nil {
+		log.Warn("fail to query feed", "feed", feed.Name, "err", err)
+		span.RecordError(err)
+		return false
+	}
+
+	trigger := ""
+	switch {
+	case feed.lastPublishedAt.IsZero():
+		trigger = "first"
+	// the tick nearest to the heartbeat, since ticks jitter
+	case feed.Heartbeat > 0 && now.Sub(feed.lastPublishedAt)+feed.interval()/2 > feed.Heartbeat:
+		trigger = "heartbeat"
+	case feed.Deviation > 0 && deviates(feed.lastMessage, report.Message, feed.Deviation):
+		trigger = "deviation"
+	default:
+		return false
+	}
+
+	feed.sequence++
+	span.SetAttributes(attribute.String("siwa.trigger", trigger), attribute.Int64("siwa.epoch", feed.Epoch),
+		attribute.Int64("siwa.sequence", int64(feed.sequence)))
+	signedReport := &SignedReport{
+		Feed:       feed.Name,
+		Epoch:      feed.Epoch,
+		Sequence:   feed.sequence,
+		GroupId:    report.GroupId,
+		Expression: report.Expression,
+		Message:    hex.EncodeToString([]byte(report.Message)),
+		Signature:  hex.EncodeToString(report.Signature),
+		Signers:    report.Signers,
+		Timestamp:  report.Timestamp,
+	}
+	for _, sink := range feed.Sinks {
+		err = sink.Send(ctx, signedReport)
+		if err != nil {
+			log.Error("fail to send report", "feed", feed.Name, "sequence", feed.sequence, "err", err)
+		}
+	}
+	log.Info("feed published", "feed", feed.Name, "sequence", feed.sequence, "trigger", trigger)
+	feed.lastMessage = report.Message
+	feed.lastPublishedAt = now
+	return true
+}
+
+// valueOfMessage parses a decimal message, or an uint256 encoded one, nil if neither
+func valueOfMessage(message string) *big.Rat {
+	if value, ok := new(big.Rat).SetString(strings.TrimSpace(message)); ok && !strings.Contains(message, "/") {
+		return value
+	}
+	if len(message) == 32 {
+		return new(big.Rat).SetInt(new(big.Int).SetBytes([]byte(message)))
+	}
+	return nil
+}
+
+// deviates reports whether the value of the message moved more than the deviation fraction from the last one,
+// messages not numeric deviate if changed
+func deviates(lastMessage, message string, deviation float64) bool {
+	lastValue, value := valueOfMessage(lastMessage), valueOfMessage(message)
+	if lastValue == nil || value == nil {
+		return lastMessage != message
+	}
+	if lastValue.Sign() == 0 {
+		return value.Sign() != 0
+	}
+	change := new(big.Rat).Sub(value, lastValue)
+	change.Quo(change, lastValue).Abs(change)
+	threshold := new(big.Rat)
+	threshold.SetFloat64(deviation)
+	return change.Cmp(threshold) > 0
+}
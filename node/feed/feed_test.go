@@ -0,0 +1,180 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package feed
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KofClubs/siwa/node"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink keeps reports sent to it
+type recordingSink struct {
+	signedReports []*SignedReport
+}
+
+func (recordingSink *recordingSink) Send(ctx context.Context, signedReport *SignedReport) error {
+	recordingSink.signedReports = append(recordingSink.signedReports, signedReport)
+	return nil
+}
+
+func newTestScheduler(feed *Feed, messages *string, now *time.Time) *Scheduler {
+	scheduler := NewScheduler([]*Feed{feed})
+	scheduler.queryGroup = func(ctx context.Context, groupId, expression string) (*node.Report, error) {
+		if *messages == "" {
+			return nil, node.NoAgreementErr
+		}
+		return &node.Report{GroupId: groupId, Expression: expression, Message: *messages, Signature: []byte{1}}, nil
+	}
+	scheduler.now = func() time.Time { return *now }
+	return scheduler
+}
+
+func TestFeedTriggers(t *testing.T) {
+	feed, err := (&UnmarshalledFeed{
+		GroupId:    "g",
+		Expression: "price",
+		Heartbeat:  time.Minute,
+		Deviation:  0.01,
+	}).CreateFeed()
+	require.Nil(t, err)
+	assert.Equal(t, "g/price", feed.Name)
+	assert.Equal(t, DefaultPollInterval, feed.interval())
+	sink := &recordingSink{}
+	feed.Sinks = append(feed.Sinks, sink)
+
+	message, now := "100", time.Unix(0, 0)
+	scheduler := newTestScheduler(feed, &message, &now)
+	ctx := context.Background()
+	assert.True(t, scheduler.tick(ctx, feed))
+
+	// within deviation
+	message, now = "100.9", now.Add(time.Second)
+	assert.False(t, scheduler.tick(ctx, feed))
+	// beyond deviation from the last published value
+	message, now = "98.9", now.Add(time.Second)
+	assert.True(t, scheduler.tick(ctx, feed))
+	// heartbeat
+	now = now.Add(time.Minute)
+	assert.True(t, scheduler.tick(ctx, feed))
+	// no agreement
+	message, now = "", now.Add(time.Minute)
+	assert.False(t, scheduler.tick(ctx, feed))
+
+	require.Len(t, sink.signedReports, 3)
+	assert.Equal(t, uint64(3), sink.signedReports[2].Sequence)
+	assert.NotZero(t, sink.signedReports[0].Epoch)
+	assert.Equal(t, sink.signedReports[0].Epoch, sink.signedReports[2].Epoch)
+	assert.Equal(t, hex.EncodeToString([]byte("98.9")), sink.signedReports[1].Message)
+	assert.Equal(t, "01", sink.signedReports[1].Signature)
+
+	_, err = (&UnmarshalledFeed{GroupId: "g"}).CreateFeed()
+	assert.Equal(t, IllegalFeedErr, err)
+}
+
+func TestDeviates(t *testing.T) {
+	uint256Of := func(value byte) string {
+		return string(append(make([]byte, 31), value))
+	}
+	assert.True(t, deviates(uint256Of(100), uint256Of(102), 0.01))
+	assert.False(t, deviates(uint256Of(100), uint256Of(101), 0.01))
+	assert.True(t, deviates("0", "0.001", 0.5))
+	assert.True(t, deviates("up", "down", 0.5))
+	assert.False(t, deviates("up", "up", 0.5))
+}
+
+func TestSchedulerRun(t *testing.T) {
+	feed, err := (&UnmarshalledFeed{GroupId: "g", Heartbeat: 10 * time.Millisecond}).CreateFeed()
+	require.Nil(t, err)
+	sink := &recordingSink{}
+	feed.Sinks = append(feed.Sinks, sink)
+	message := "1"
+	scheduler := NewScheduler([]*Feed{feed})
+	scheduler.queryGroup = func(ctx context.Context, groupId, expression string) (*node.Report, error) {
+		return &node.Report{Message: message}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	scheduler.Run(ctx)
+	assert.GreaterOrEqual(t, len(sink.signedReports), 3)
+}
+
+func TestSinks(t *testing.T) {
+	ctx := context.Background()
+	signedReport := &SignedReport{Feed: "f", Epoch: 1, Sequence: 1, Message: "31"}
+
+	received := make(chan SignedReport, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "token", request.Header.Get("Authorization"))
+		var body SignedReport
+		assert.Nil(t, json.NewDecoder(request.Body).Decode(&body))
+		received <- body
+	}))
+	defer server.Close()
+	httpSink, err := (&UnmarshalledSink{Type: "http", Url: server.URL,
+		Headers: map[string]string{"Authorization": "token"}}).CreateSink()
+	require.Nil(t, err)
+	require.Nil(t, httpSink.Send(ctx, signedReport))
+	assert.Equal(t, *signedReport, <-received)
+
+	// a hung endpoint times out
+	hungServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer hungServer.Close()
+	httpSink, err = (&UnmarshalledSink{Type: "http", Url: hungServer.URL, Timeout: 20 * time.Millisecond}).CreateSink()
+	require.Nil(t, err)
+	start := time.Now()
+	assert.NotNil(t, httpSink.Send(ctx, signedReport))
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "reports.jsonl")
+	fileSink, err := (&UnmarshalledSink{Type: "file", Path: path}).CreateSink()
+	require.Nil(t, err)
+	require.Nil(t, fileSink.Send(ctx, signedReport))
+	require.Nil(t, fileSink.Send(ctx, signedReport))
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); lines++ {
+	}
+	assert.Equal(t, 2, lines)
+
+	for _, unmarshalledSink := range []UnmarshalledSink{{Type: "kafka"}, {Type: "http"}, {Type: "file"}} {
+		_, err = unmarshalledSink.CreateSink()
+		assert.Equal(t, IllegalSinkErr, err)
+	}
+}
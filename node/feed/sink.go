@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/KofClubs/siwa/node/telemetry"
+	"github.com/MonteCarloClub/log"
+)
+
+const DefaultSinkTimeout = 10 * time.Second
+
+var (
+	IllegalSinkErr      = fmt.Errorf("illegal sink")
+	UnexpectedStatusErr = fmt.Errorf("unexpected status of sink")
+)
+
+// Sink receives signed reports of feeds
+type Sink interface {
+	Send(ctx context.Context, signedReport *SignedReport) error
+}
+
+type UnmarshalledSink struct {
+	// Type is log, http or file
+	Type string `yaml:"type"`
+	// Url and Headers of http sinks, which post reports in json
+	Url     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// Timeout limits a post of http sinks, DefaultSinkTimeout if not positive
+	Timeout time.Duration `yaml:"timeout"`
+	// Path of file sinks, which append reports in json lines
+	Path string `yaml:"path"`
+}
+
+func (unmarshalledSink *UnmarshalledSink) CreateSink() (Sink, error) {
+	switch unmarshalledSink.Type {
+	case "log":
+		return &LogSink{}, nil
+	case "http":
+		if unmarshalledSink.Url == "" {
+			return nil, IllegalSinkErr
+		}
+		timeout := unmarshalledSink.Timeout
+		if timeout <= 0 {
+			timeout = DefaultSinkTimeout
+		}
+		return &HttpSink{
+			Url:        unmarshalledSink.Url,
+			Headers:    unmarshalledSink.Headers,
+			HttpClient: &http.Client{Timeout: timeout},
+		}, nil
+	case "file":
+		if unmarshalledSink.Path == "" {
+			return nil, IllegalSinkErr
+		}
+		return &FileSink{Path: unmarshalledSink.Path}, nil
+	default:
+		log.Error("fail to create sink", "type", unmarshalledSink.Type, "err", IllegalSinkErr)
+		return nil, IllegalSinkErr
+	}
+}
+
+type LogSink struct{}
+
+func (logSink *LogSink) Send(ctx context.Context, signedReport *SignedReport) error {
+	log.Info("report published", "feed", signedReport.Feed, "epoch", signedReport.Epoch,
+		"sequence", signedReport.Sequence,
+		"group id", signedReport.GroupId, "message", signedReport.Message, "signature", signedReport.Signature)
+	return nil
+}
+
+type HttpSink struct {
+	Url        string
+	Headers    map[string]string
+	HttpClient *http.Client
+}
+
+func (httpSink *HttpSink) Send(ctx context.Context, signedReport *SignedReport) error {
+	body, err := json.Marshal(signedReport)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, httpSink.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range httpSink.Headers {
+		request.Header.Set(name, value)
+	}
+	telemetry.Inject(ctx, request.Header)
+	response, err := httpSink.HttpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		log.Error("fail to send report", "url", httpSink.Url, "status", response.Status)
+		return UnexpectedStatusErr
+	}
+	return nil
+}
+
+type FileSink struct {
+	Path  string
+	mutex sync.Mutex
+}
+
+func (fileSink *FileSink) Send(ctx context.Context, signedReport *SignedReport) error {
+	line, err := json.Marshal(signedReport)
+	if err != nil {
+		return err
+	}
+	fileSink.mutex.Lock()
+	defer fileSink.mutex.Unlock()
+
+	file, err := os.OpenFile(fileSink.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
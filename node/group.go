@@ -23,57 +23,443 @@ THE SOFTWARE.
 package node
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/KofClubs/siwa/crypto"
 	"github.com/MonteCarloClub/log"
 	"github.com/MonteCarloClub/utils"
+	"go.dedis.ch/kyber/v3"
+)
+
+var (
+	GroupExistedErr           = fmt.Errorf("group existed")
+	GroupNotExistedErr        = fmt.Errorf("group not existed")
+	IllegalIndexPolicyErr     = fmt.Errorf("illegal index policy")
+	IllegalThresholdPolicyErr = fmt.Errorf("illegal threshold policy")
+	ThresholdTooLowErr        = fmt.Errorf("threshold should not be less than 2")
+	UnsafeThresholdErr        = fmt.Errorf("threshold would fall below the safe threshold")
+)
+
+type ThresholdPolicyType int
+
+const (
+	// MajorityThreshold requires n/2+1 of n nodes
+	MajorityThreshold ThresholdPolicyType = iota
+	// AbsoluteThreshold requires a fixed number of nodes, at most n
+	AbsoluteThreshold
+	// FractionThreshold requires a fraction of nodes, rounded up
+	FractionThreshold
+	// MinimumThreshold requires 2 nodes, the minimum threshold of distributed key generators
+	MinimumThreshold
+)
+
+type ThresholdPolicy struct {
+	Type     ThresholdPolicyType
+	Absolute int
+	Fraction float64
+}
+
+func ParseThresholdPolicy(policyType string, threshold float64) (ThresholdPolicy, error) {
+	var thresholdPolicy ThresholdPolicy
+	switch policyType {
+	case "", "majority":
+		thresholdPolicy = ThresholdPolicy{Type: MajorityThreshold}
+	case "absolute":
+		thresholdPolicy = ThresholdPolicy{Type: AbsoluteThreshold, Absolute: int(threshold)}
+	case "fraction":
+		thresholdPolicy = ThresholdPolicy{Type: FractionThreshold, Fraction: threshold}
+	case "minimum":
+		thresholdPolicy = ThresholdPolicy{Type: MinimumThreshold}
+	default:
+		log.Error("unknown threshold policy", "threshold policy", policyType)
+		return ThresholdPolicy{}, IllegalThresholdPolicyErr
+	}
+	if !thresholdPolicy.isLegal() {
+		log.Error("illegal threshold policy", "threshold policy", policyType, "threshold", threshold)
+		return ThresholdPolicy{}, IllegalThresholdPolicyErr
+	}
+	return thresholdPolicy, nil
+}
+
+func (thresholdPolicy ThresholdPolicy) String() string {
+	switch thresholdPolicy.Type {
+	case AbsoluteThreshold:
+		return fmt.Sprintf("absolute(%v)", thresholdPolicy.Absolute)
+	case FractionThreshold:
+		return fmt.Sprintf("fraction(%v)", thresholdPolicy.Fraction)
+	case MinimumThreshold:
+		return "minimum"
+	default:
+		return "majority"
+	}
+}
+
+func (thresholdPolicy ThresholdPolicy) isLegal() bool {
+	switch thresholdPolicy.Type {
+	case MajorityThreshold, MinimumThreshold:
+		return true
+	case AbsoluteThreshold:
+		return thresholdPolicy.Absolute >= 2
+	case FractionThreshold:
+		return thresholdPolicy.Fraction > 0 && thresholdPolicy.Fraction <= 1
+	default:
+		return false
+	}
+}
+
+// threshold returns the threshold of nodeCount nodes, which is never greater than nodeCount
+func (thresholdPolicy ThresholdPolicy) threshold(nodeCount int) int {
+	var threshold int
+	switch thresholdPolicy.Type {
+	case AbsoluteThreshold:
+		threshold = thresholdPolicy.Absolute
+	case FractionThreshold:
+		threshold = int(math.Ceil(thresholdPolicy.Fraction * float64(nodeCount)))
+	case MinimumThreshold:
+		threshold = 2
+	default:
+		threshold = nodeCount/2 + 1
+	}
+	if threshold > nodeCount {
+		threshold = nodeCount
+	}
+	return threshold
+}
+
+// ThresholdOf returns the threshold of nodeCount nodes, like the threshold of a group of nodeCount nodes
+func (thresholdPolicy ThresholdPolicy) ThresholdOf(nodeCount int) int {
+	return thresholdPolicy.threshold(nodeCount)
+}
+
+// IndexPolicy decides dkg indices of nodes in a group, which are 0, 1, ..., n-1
+type IndexPolicy int
+
+const (
+	// JoinSequenceIndex orders nodes by the sequence they join the group
+	JoinSequenceIndex IndexPolicy = iota
+	// PublicKeyIndex orders nodes by their encoded public keys
+	PublicKeyIndex
 )
 
+func ParseIndexPolicy(indexPolicy string) (IndexPolicy, error) {
+	switch indexPolicy {
+	case "", "join_sequence":
+		return JoinSequenceIndex, nil
+	case "public_key":
+		return PublicKeyIndex, nil
+	default:
+		log.Error("unknown index policy", "index policy", indexPolicy)
+		return JoinSequenceIndex, IllegalIndexPolicyErr
+	}
+}
+
+func (indexPolicy IndexPolicy) String() string {
+	if indexPolicy == PublicKeyIndex {
+		return "public_key"
+	}
+	return "join_sequence"
+}
+
+type UnmarshalledGroup struct {
+	Id              string  `yaml:"id"`
+	ThresholdPolicy string  `yaml:"threshold_policy"`
+	Threshold       float64 `yaml:"threshold"`
+	IndexPolicy     string  `yaml:"index_policy"`
+}
+
+func (unmarshalledGroup *UnmarshalledGroup) CreateGroup() (*Group, error) {
+	if unmarshalledGroup == nil {
+		log.Error("nil unmarshalled group", "err", utils.NilPtrDerefErr)
+		return nil, utils.NilPtrDerefErr
+	}
+
+	thresholdPolicy, err := ParseThresholdPolicy(unmarshalledGroup.ThresholdPolicy, unmarshalledGroup.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	indexPolicy, err := ParseIndexPolicy(unmarshalledGroup.IndexPolicy)
+	if err != nil {
+		return nil, err
+	}
+	return CreateGroup(unmarshalledGroup.Id, thresholdPolicy, indexPolicy)
+}
+
 // Group is not an entity, but information shared by a group of nodes
-// todo: implement Group at on-chain registry
+// and published to the on-chain registry by ethereum.Registry
 type Group struct {
-	Id        string
-	NodeIds   map[string]struct{}
-	Threshold int
+	Id              string
+	NodeIds         map[string]struct{}
+	Threshold       int
+	ThresholdPolicy ThresholdPolicy
+	IndexPolicy     IndexPolicy
+	// DkgIndices lists node ids by dkg indices, DkgIndices[index] is the id of the node at index
+	DkgIndices []string
+	PublicKeys map[string]kyber.Point
+}
+
+// CreateGroup creates an empty group, and generates its id if not specified
+func CreateGroup(groupId string, thresholdPolicy ThresholdPolicy, indexPolicy IndexPolicy) (*Group, error) {
+	if !thresholdPolicy.isLegal() {
+		log.Error("illegal threshold policy", "group id", groupId, "threshold policy", thresholdPolicy.String())
+		return nil, IllegalThresholdPolicyErr
+	}
+	if groupId == "" {
+		groupId = generateGroupId()
+	}
+	if getGroup(groupId) != nil {
+		log.Error("fail to create group", "group id", groupId, "err", GroupExistedErr)
+		return nil, GroupExistedErr
+	}
+
+	group := &Group{
+		Id:              groupId,
+		NodeIds:         make(map[string]struct{}),
+		ThresholdPolicy: thresholdPolicy,
+		IndexPolicy:     indexPolicy,
+		PublicKeys:      make(map[string]kyber.Point),
+	}
+	setGroup(group)
+	log.Info("group created", "group id", groupId, "threshold policy", thresholdPolicy.String(),
+		"index policy", indexPolicy.String())
+	return group, nil
+}
+
+// ListGroups returns all groups sorted by id
+func ListGroups() []*Group {
+	return getGroups()
+}
+
+// GetGroup returns the group, or GroupNotExistedErr
+func GetGroup(groupId string) (*Group, error) {
+	group := getGroup(groupId)
+	if group == nil {
+		return nil, GroupNotExistedErr
+	}
+	return group, nil
 }
 
-func (group *Group) addNode(newNodeId string) ([]string, int, error) {
+// DissolveGroup deletes the group, and deletes its nodes which belong to no other group
+func DissolveGroup(groupId string) error {
+	group := getGroup(groupId)
+	if group == nil {
+		log.Error("fail to dissolve group", "group id", groupId, "err", GroupNotExistedErr)
+		return GroupNotExistedErr
+	}
+
+	for nodeId := range group.NodeIds {
+		node := getNode(nodeId)
+		if node == nil {
+			continue
+		}
+		if len(node.GroupIds()) > 1 {
+			delete(node.Dkgs, groupId)
+			continue
+		}
+		if node.Querier != nil {
+			node.Querier.Close()
+		}
+		unsetNode(nodeId)
+	}
+	unsetDkgIndices(groupId)
+	unsetGroup(groupId)
+	delete(joinSequenceTable, groupId)
+	saveDkgState()
+	log.Info("group dissolved", "group id", groupId)
+	return nil
+}
+
+// SetThreshold changes the threshold policy of the group, and reshares if the threshold changes
+func SetThreshold(groupId string, thresholdPolicy ThresholdPolicy) error {
+	group := getGroup(groupId)
+	if group == nil {
+		log.Error("fail to set threshold", "group id", groupId, "err", GroupNotExistedErr)
+		return GroupNotExistedErr
+	}
+	if !thresholdPolicy.isLegal() {
+		log.Error("illegal threshold policy", "group id", groupId, "threshold policy", thresholdPolicy.String())
+		return IllegalThresholdPolicyErr
+	}
+
+	nodeCount := len(group.NodeIds)
+	updatedThreshold := thresholdPolicy.threshold(nodeCount)
+	if nodeCount > 1 && updatedThreshold < 2 {
+		log.Error("fail to set threshold", "group id", groupId, "threshold policy", thresholdPolicy.String(),
+			"err", ThresholdTooLowErr)
+		return ThresholdTooLowErr
+	}
+
+	group.ThresholdPolicy = thresholdPolicy
+	if updatedThreshold == group.Threshold {
+		return nil
+	}
+	group.Threshold = updatedThreshold
+	log.Info("threshold changed, reshare group", "group id", groupId, "threshold", updatedThreshold)
+	return reshare(groupId, group.getNodeIds(), updatedThreshold)
+}
+
+func (group *Group) ToUnmarshalledGroup() *UnmarshalledGroup {
+	if group == nil {
+		log.Error("nil group", "err", utils.NilPtrDerefErr)
+		return nil
+	}
+
+	unmarshalledGroup := &UnmarshalledGroup{
+		Id:          group.Id,
+		IndexPolicy: group.IndexPolicy.String(),
+	}
+	switch group.ThresholdPolicy.Type {
+	case AbsoluteThreshold:
+		unmarshalledGroup.ThresholdPolicy = "absolute"
+		unmarshalledGroup.Threshold = float64(group.ThresholdPolicy.Absolute)
+	case FractionThreshold:
+		unmarshalledGroup.ThresholdPolicy = "fraction"
+		unmarshalledGroup.Threshold = group.ThresholdPolicy.Fraction
+	case MinimumThreshold:
+		unmarshalledGroup.ThresholdPolicy = "minimum"
+	default:
+		unmarshalledGroup.ThresholdPolicy = "majority"
+	}
+	return unmarshalledGroup
+}
+
+// getNodeIds returns node ids ordered by dkg indices
+func (group *Group) getNodeIds() []string {
+	nodeIds := make([]string, len(group.DkgIndices))
+	copy(nodeIds, group.DkgIndices)
+	return nodeIds
+}
+
+// getDkgIndex returns the dkg index of the node, or -1 if the node is not in the group
+func (group *Group) getDkgIndex(nodeId string) int {
+	if group == nil {
+		return -1
+	}
+	for index, indexedNodeId := range group.DkgIndices {
+		if indexedNodeId == nodeId {
+			return index
+		}
+	}
+	return -1
+}
+
+// addNode adds the node, and returns node ids ordered by dkg indices and the updated threshold
+func (group *Group) addNode(newNodeId string, publicKey kyber.Point) ([]string, int, error) {
 	if group == nil || group.NodeIds == nil {
 		log.Error("nil group or node ids", "err", utils.NilPtrDeref)
 		return nil, 0, utils.NilPtrDerefErr
 	}
 
-	updatedThreshold := group.Threshold
-	updatedNodeCount := len(group.NodeIds)
 	if _, ok := group.NodeIds[newNodeId]; !ok {
 		group.NodeIds[newNodeId] = struct{}{}
-		updatedNodeCount++
+		if group.PublicKeys == nil {
+			group.PublicKeys = make(map[string]kyber.Point)
+		}
+		group.PublicKeys[newNodeId] = publicKey
+		group.DkgIndices = append(group.DkgIndices, newNodeId)
+		if group.IndexPolicy == PublicKeyIndex {
+			group.sortDkgIndicesByPublicKey()
+		} else {
+			group.sortDkgIndicesByJoinSequence(publicKey)
+		}
 	}
-	if updatedThreshold < updatedNodeCount/2+1 {
-		updatedThreshold = updatedNodeCount/2 + 1
+	group.Threshold = group.ThresholdPolicy.threshold(len(group.NodeIds))
+	return group.getNodeIds(), group.Threshold, nil
+}
+
+func (group *Group) sortDkgIndicesByPublicKey() {
+	encodedPublicKeys := make(map[string][]byte)
+	for _, nodeId := range group.DkgIndices {
+		encodedPublicKeys[nodeId] = crypto.EncodeBlsPublicKey(group.PublicKeys[nodeId])
 	}
+	sort.SliceStable(group.DkgIndices, func(i, j int) bool {
+		return bytes.Compare(encodedPublicKeys[group.DkgIndices[i]], encodedPublicKeys[group.DkgIndices[j]]) < 0
+	})
+}
 
-	nodeIds := make([]string, 0)
-	for nodeId := range group.NodeIds {
-		nodeIds = append(nodeIds, nodeId)
+// sortDkgIndicesByJoinSequence orders nodes by the sequence they first joined the group, which survives restarts
+// if the dkg state is loaded, the new node is appended to the sequence unless it joined before
+func (group *Group) sortDkgIndicesByJoinSequence(newPublicKey kyber.Point) {
+	joinSequence := joinSequenceTable[group.Id]
+	if indexOf(joinSequence, encodePublicKey(newPublicKey)) < 0 {
+		joinSequence = append(joinSequence, encodePublicKey(newPublicKey))
+		joinSequenceTable[group.Id] = joinSequence
+		saveDkgState()
 	}
-	group.Threshold = updatedThreshold
-	return nodeIds, group.Threshold, nil
+	sequences := make(map[string]int)
+	for _, nodeId := range group.DkgIndices {
+		sequences[nodeId] = indexOf(joinSequence, encodePublicKey(group.PublicKeys[nodeId]))
+	}
+	sort.SliceStable(group.DkgIndices, func(i, j int) bool {
+		return sequences[group.DkgIndices[i]] < sequences[group.DkgIndices[j]]
+	})
+}
+
+// checkNodeDeletion checks that the rest of nodes still reach a threshold of at least 2 under the threshold
+// policy, unless no node is left
+func (group *Group) checkNodeDeletion(nodeId string) error {
+	if group == nil || group.NodeIds == nil {
+		log.Error("nil group or node ids", "err", utils.NilPtrDeref)
+		return utils.NilPtrDerefErr
+	}
+	if _, ok := group.NodeIds[nodeId]; !ok {
+		log.Error("node not existed in group", "group id", group.Id, "node id", nodeId)
+		return NodeNotExistedErr
+	}
+
+	updatedNodeCount := len(group.NodeIds) - 1
+	if updatedNodeCount == 0 {
+		return nil
+	}
+	updatedThreshold := group.ThresholdPolicy.threshold(updatedNodeCount)
+	if updatedThreshold < 2 || (group.ThresholdPolicy.Type == AbsoluteThreshold &&
+		updatedNodeCount < group.ThresholdPolicy.Absolute) {
+		log.Error("fail to delete node", "group id", group.Id, "node id", nodeId,
+			"threshold policy", group.ThresholdPolicy.String(), "updated node count", updatedNodeCount,
+			"err", UnsafeThresholdErr)
+		return UnsafeThresholdErr
+	}
+	return nil
 }
 
 func (group *Group) deleteNode(nodeId string) {
 	if group == nil || group.NodeIds == nil {
 		log.Warn("nil group or node ids")
+		return
 	}
 
 	if _, ok := group.NodeIds[nodeId]; !ok {
 		log.Warn("node not existed", "node id", nodeId)
 	}
 
-	updatedThreshold := group.Threshold
-	updatedNodeCount := len(group.NodeIds) - 1
-	if updatedThreshold > updatedNodeCount/2+1 {
-		updatedThreshold = updatedNodeCount/2 + 1
+	if publicKey, ok := group.PublicKeys[nodeId]; ok && group.IndexPolicy == JoinSequenceIndex {
+		joinSequence := joinSequenceTable[group.Id]
+		if sequence := indexOf(joinSequence, encodePublicKey(publicKey)); sequence >= 0 {
+			joinSequenceTable[group.Id] = append(joinSequence[:sequence:sequence], joinSequence[sequence+1:]...)
+			saveDkgState()
+		}
 	}
-
 	delete(group.NodeIds, nodeId)
-	group.Threshold = updatedThreshold
+	delete(group.PublicKeys, nodeId)
+	if index := group.getDkgIndex(nodeId); index >= 0 {
+		group.DkgIndices = append(group.DkgIndices[:index], group.DkgIndices[index+1:]...)
+	}
+	group.Threshold = group.ThresholdPolicy.threshold(len(group.NodeIds))
+}
+
+func encodePublicKey(publicKey kyber.Point) string {
+	return hex.EncodeToString(crypto.EncodeBlsPublicKey(publicKey))
+}
+
+// indexOf returns the index of the first element equal to value, or -1
+func indexOf(values []string, value string) int {
+	for index, element := range values {
+		if element == value {
+			return index
+		}
+	}
+	return -1
 }
@@ -0,0 +1,243 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package node
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThresholdPolicy(t *testing.T) {
+	majority, err := ParseThresholdPolicy("majority", 0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, majority.threshold(1))
+	assert.Equal(t, 4, majority.threshold(6))
+
+	absolute, err := ParseThresholdPolicy("absolute", 3)
+	require.Nil(t, err)
+	assert.Equal(t, 2, absolute.threshold(2))
+	assert.Equal(t, 3, absolute.threshold(6))
+
+	fraction, err := ParseThresholdPolicy("fraction", 0.67)
+	require.Nil(t, err)
+	assert.Equal(t, 5, fraction.threshold(6))
+
+	minimum, err := ParseThresholdPolicy("minimum", 0)
+	require.Nil(t, err)
+	assert.Equal(t, 2, minimum.threshold(6))
+
+	_, err = ParseThresholdPolicy("absolute", 1)
+	assert.Equal(t, IllegalThresholdPolicyErr, err)
+	_, err = ParseThresholdPolicy("fraction", 1.5)
+	assert.Equal(t, IllegalThresholdPolicyErr, err)
+	_, err = ParseThresholdPolicy("unknown", 0)
+	assert.Equal(t, IllegalThresholdPolicyErr, err)
+}
+
+func TestGroupLifecycle(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	group, err := CreateGroup("g", ThresholdPolicy{Type: MinimumThreshold}, JoinSequenceIndex)
+	require.Nil(t, err)
+	_, err = CreateGroup("g", ThresholdPolicy{}, JoinSequenceIndex)
+	assert.Equal(t, GroupExistedErr, err)
+	_, err = CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(ListGroups()))
+
+	for i := 0; i < 4; i++ {
+		createRedisNode(t, group.Id)
+	}
+	assert.Equal(t, 2, group.Threshold)
+
+	dkgs := make(map[string]interface{})
+	for nodeId := range group.NodeIds {
+		dkgs[nodeId] = getNode(nodeId).GetDkg(group.Id)
+	}
+	err = SetThreshold(group.Id, ThresholdPolicy{Type: AbsoluteThreshold, Absolute: 3})
+	require.Nil(t, err)
+	assert.Equal(t, 3, group.Threshold)
+	for nodeId := range group.NodeIds {
+		assert.NotSame(t, dkgs[nodeId], getNode(nodeId).GetDkg(group.Id))
+	}
+	err = SetThreshold(group.Id, ThresholdPolicy{Type: AbsoluteThreshold, Absolute: 1})
+	assert.Equal(t, IllegalThresholdPolicyErr, err)
+
+	nodeIds := group.getNodeIds()
+	err = DissolveGroup(group.Id)
+	require.Nil(t, err)
+	assert.Nil(t, getGroup(group.Id))
+	for _, nodeId := range nodeIds {
+		assert.Nil(t, getNode(nodeId))
+	}
+	assert.Equal(t, GroupNotExistedErr, DissolveGroup(group.Id))
+}
+
+func TestLeaveAndEvict(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	group, err := CreateGroup("g", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	groupNodes := make([]*Node, 0)
+	for i := 0; i < 4; i++ {
+		node := createRedisNode(t, group.Id)
+		groupNodes = append(groupNodes, node)
+	}
+	assert.Equal(t, 3, group.Threshold)
+
+	err = EvictNode(groupNodes[0].Id)
+	require.Nil(t, err)
+	assert.Nil(t, getNode(groupNodes[0].Id))
+	assert.Equal(t, 2, group.Threshold)
+	assert.Equal(t, NodeNotExistedErr, EvictNode(groupNodes[0].Id))
+
+	err = groupNodes[1].Leave()
+	require.Nil(t, err)
+	assert.Nil(t, getNode(groupNodes[1].Id))
+	assert.Equal(t, 2, len(group.NodeIds))
+	assert.Equal(t, 2, group.Threshold)
+
+	restNodes := groupNodes[2:]
+	for _, node := range restNodes {
+		assert.Equal(t, node, getNodeByDkgIndex(group.Id, node.GetDkg(group.Id).GetIndex()))
+		assert.False(t, node.ReadyToQuery(group.Id))
+	}
+	fullCommunicate(group.Id, restNodes)
+	for _, node := range restNodes {
+		assert.True(t, node.ReadyToQuery(group.Id))
+	}
+
+	assert.Equal(t, UnsafeThresholdErr, restNodes[0].Leave())
+	assert.Equal(t, 2, len(group.NodeIds))
+}
+
+func TestLeaveWithFailedReshare(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	groups := make([]*Group, 0)
+	for _, groupId := range []string{"g1", "g2"} {
+		group, err := CreateGroup(groupId, ThresholdPolicy{}, JoinSequenceIndex)
+		require.Nil(t, err)
+		for i := 0; i < 2; i++ {
+			createRedisNode(t, group.Id)
+		}
+		groups = append(groups, group)
+	}
+	node := createRedisNode(t, groups[0].Id)
+	require.Nil(t, node.JoinGroup(groups[1].Id))
+	// a node missing from the registry fails resharing of g1
+	groups[0].NodeIds["missing"] = struct{}{}
+	groups[0].DkgIndices = append(groups[0].DkgIndices, "missing")
+
+	err := node.Leave()
+	assert.ErrorIs(t, err, ReshareErr)
+	assert.Contains(t, err.Error(), "g1")
+	assert.NotContains(t, err.Error(), "g2")
+	assert.Nil(t, getNode(node.Id))
+	for _, group := range groups {
+		assert.Equal(t, -1, group.getDkgIndex(node.Id))
+	}
+}
+
+func TestDkgIndices(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	for _, indexPolicy := range []IndexPolicy{JoinSequenceIndex, PublicKeyIndex} {
+		group, err := CreateGroup("", ThresholdPolicy{}, indexPolicy)
+		require.Nil(t, err)
+		groupNodes := make([]*Node, 0)
+		for i := 0; i < 4; i++ {
+			node := createRedisNode(t, group.Id)
+			groupNodes = append(groupNodes, node)
+		}
+		require.Nil(t, groupNodes[1].Leave())
+
+		require.Equal(t, 3, len(group.DkgIndices))
+		for index, nodeId := range group.DkgIndices {
+			assert.Equal(t, index, getNode(nodeId).GetDkg(group.Id).GetIndex())
+		}
+		if indexPolicy == JoinSequenceIndex {
+			assert.Equal(t, []string{groupNodes[0].Id, groupNodes[2].Id, groupNodes[3].Id}, group.DkgIndices)
+			continue
+		}
+		for index := 1; index < len(group.DkgIndices); index++ {
+			previousPublicKey := crypto.EncodeBlsPublicKey(group.PublicKeys[group.DkgIndices[index-1]])
+			publicKey := crypto.EncodeBlsPublicKey(group.PublicKeys[group.DkgIndices[index]])
+			assert.Equal(t, -1, bytes.Compare(previousPublicKey, publicKey))
+		}
+	}
+}
+
+func TestDkgState(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	require.Nil(t, LoadDkgState(stateFile))
+
+	group, err := CreateGroup("g", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	privateKeys := make([]string, 0)
+	for i := 0; i < 4; i++ {
+		privateKeys = append(privateKeys, genRandomPrivateKey())
+		createNodeOfPrivateKey(t, group.Id, privateKeys[i])
+	}
+	require.Nil(t, getNode(group.DkgIndices[1]).Leave())
+	publicKeys := make([]string, 0)
+	for _, nodeId := range group.DkgIndices {
+		publicKeys = append(publicKeys, encodePublicKey(group.PublicKeys[nodeId]))
+	}
+
+	// nodes are created again in reverse order after a restart, the node which left is not
+	resetRegistry()
+	require.Nil(t, LoadDkgState(stateFile))
+	group, err = CreateGroup("g", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	for _, i := range []int{3, 2, 0} {
+		createNodeOfPrivateKey(t, group.Id, privateKeys[i])
+	}
+	require.Equal(t, len(publicKeys), len(group.DkgIndices))
+	for index, nodeId := range group.DkgIndices {
+		assert.Equal(t, publicKeys[index], encodePublicKey(group.PublicKeys[nodeId]))
+		assert.Equal(t, index, getNode(nodeId).GetDkg(group.Id).GetIndex())
+	}
+
+	require.Nil(t, DissolveGroup(group.Id))
+	require.Nil(t, LoadDkgState(stateFile))
+	assert.Empty(t, joinSequenceTable)
+}
+
+func createNodeOfPrivateKey(t *testing.T, groupId, privateKey string) {
+	unmarshalledNode := &UnmarshalledNode{GroupId: groupId, PrivateKey: privateKey}
+	unmarshalledNode.QuerierSource = "redis"
+	unmarshalledNode.RedisAddress = RedisAddress
+	require.NotNil(t, unmarshalledNode.CreateNode())
+}
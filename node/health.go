@@ -0,0 +1,197 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/KofClubs/siwa/node/querier"
+	"github.com/MonteCarloClub/log"
+)
+
+// DefaultPingTimeout bounds each ping of a querier in health checks
+const DefaultPingTimeout = 2 * time.Second
+
+// redacted errors of queriers in statuses, since errors of queriers may carry dsns, urls or credentials
+const (
+	QuerierTimeout     = "timeout"
+	QuerierUnreachable = "unreachable"
+)
+
+// Health is the result of a check, Problems are described by node id or group id
+type Health struct {
+	Ok       bool              `json:"ok"`
+	Problems map[string]string `json:"problems,omitempty"`
+}
+
+// NodeStatus is the state of a node in one of its groups
+type NodeStatus struct {
+	Id            string `json:"id"`
+	GroupId       string `json:"group_id"`
+	DkgIndex      int    `json:"dkg_index"`
+	Certified     bool   `json:"certified"`
+	QuerierSource string `json:"querier_source"`
+	// QuerierErr is empty if the querier is connected, else QuerierTimeout, QuerierUnreachable or
+	// NodeNotExistedErr, details are only logged
+	QuerierErr string `json:"querier_error,omitempty"`
+	// PublicKeyFingerprint is the first 8 bytes of sha256 of the distributed public key of the group in hex, empty
+	// if the dkg is not certified
+	PublicKeyFingerprint string `json:"public_key_fingerprint,omitempty"`
+}
+
+// CheckHealth checks the process, a node of a group not loaded is a problem. Queriers are only checked by
+// CheckReadiness, so that an outage of a data source takes nodes out of service instead of restarting them.
+func CheckHealth(context.Context) Health {
+	health := Health{Ok: true, Problems: make(map[string]string)}
+	for _, group := range ListGroups() {
+		for _, nodeId := range group.getNodeIds() {
+			if getNode(nodeId) == nil {
+				health.Ok = false
+				health.Problems[nodeId] = NodeNotExistedErr.Error()
+			}
+		}
+	}
+	return health
+}
+
+// CheckReadiness requires every group to have at least threshold nodes certified and connected to their queriers
+func CheckReadiness(ctx context.Context) Health {
+	health := Health{Ok: true, Problems: make(map[string]string)}
+	pingErrs := pingNodes(ctx)
+	for _, group := range ListGroups() {
+		if group.Threshold <= 0 {
+			health.Ok = false
+			health.Problems[group.Id] = "dkg not run, too few nodes"
+			continue
+		}
+		readyCount := 0
+		for _, nodeId := range group.getNodeIds() {
+			if certified(getNode(nodeId), group.Id) && pingErrs[nodeId] == nil {
+				readyCount++
+			}
+		}
+		if readyCount < group.Threshold {
+			health.Ok = false
+			health.Problems[group.Id] = NoAgreementErr.Error()
+		}
+	}
+	return health
+}
+
+// Statuses returns states of nodes in their groups, ordered by group id and dkg index
+func Statuses(ctx context.Context) []*NodeStatus {
+	pingErrs := pingNodes(ctx)
+	statuses := make([]*NodeStatus, 0)
+	for _, group := range ListGroups() {
+		fingerprint := ""
+		publicKey, err := GroupPublicKey(group.Id)
+		if err == nil {
+			digest := sha256.Sum256(crypto.EncodeBlsPublicKey(publicKey))
+			fingerprint = hex.EncodeToString(digest[:8])
+		}
+		for index, nodeId := range group.getNodeIds() {
+			node := getNode(nodeId)
+			if node == nil {
+				continue
+			}
+			status := &NodeStatus{
+				Id:            nodeId,
+				GroupId:       group.Id,
+				DkgIndex:      index,
+				Certified:     certified(node, group.Id),
+				QuerierSource: node.QuerierSource,
+				QuerierErr:    redactQuerierErr(pingErrs[nodeId]),
+			}
+			if pingErrs[nodeId] != nil {
+				log.Warn("querier not connected", "node id", nodeId, "querier source", node.QuerierSource,
+					"err", pingErrs[nodeId])
+			}
+			if status.Certified {
+				status.PublicKeyFingerprint = fingerprint
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+func redactQuerierErr(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, NodeNotExistedErr):
+		return NodeNotExistedErr.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return QuerierTimeout
+	default:
+		return QuerierUnreachable
+	}
+}
+
+func certified(node *Node, groupId string) bool {
+	if node == nil {
+		return false
+	}
+	dkg := node.GetDkg(groupId)
+	return dkg != nil && dkg.PedersenDkg != nil && dkg.PedersenDkg.Certified()
+}
+
+// pingNodes pings queriers of all nodes in parallel, the error is NodeNotExistedErr for a node of a group but not
+// in the registry
+func pingNodes(ctx context.Context) map[string]error {
+	pingErrs := make(map[string]error)
+	nodes := make([]*Node, 0)
+	for _, group := range ListGroups() {
+		for _, nodeId := range group.getNodeIds() {
+			if _, ok := pingErrs[nodeId]; ok {
+				continue
+			}
+			pingErrs[nodeId] = NodeNotExistedErr
+			if node := getNode(nodeId); node != nil {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+
+	errs := make([]error, len(nodes))
+	var waitGroup sync.WaitGroup
+	for i, node := range nodes {
+		waitGroup.Add(1)
+		go func(i int, node *Node) {
+			defer waitGroup.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, DefaultPingTimeout)
+			defer cancel()
+			errs[i] = querier.Ping(pingCtx, node.Querier)
+		}(i, node)
+	}
+	waitGroup.Wait()
+	for i, node := range nodes {
+		pingErrs[node.Id] = errs[i]
+	}
+	return pingErrs
+}
@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/KofClubs/siwa/node/querier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+	ctx := context.Background()
+
+	group, err := CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	groupNodes := createFormattedNodes(t, group.Id, querier.NormalizationConfig{}, "2", "2", "2")
+
+	// healthy but not ready before running dkg
+	assert.True(t, CheckHealth(ctx).Ok)
+	readiness := CheckReadiness(ctx)
+	assert.False(t, readiness.Ok)
+	assert.Contains(t, readiness.Problems, group.Id)
+	for _, status := range Statuses(ctx) {
+		assert.False(t, status.Certified)
+		assert.Empty(t, status.PublicKeyFingerprint)
+	}
+
+	require.Nil(t, RunDkg(group.Id))
+	assert.True(t, CheckReadiness(ctx).Ok)
+	statuses := Statuses(ctx)
+	require.Len(t, statuses, 3)
+	for index, status := range statuses {
+		assert.Equal(t, groupNodes[index].Id, status.Id)
+		assert.Equal(t, group.Id, status.GroupId)
+		assert.Equal(t, index, status.DkgIndex)
+		assert.True(t, status.Certified)
+		assert.Equal(t, "formatted", status.QuerierSource)
+		assert.Len(t, status.PublicKeyFingerprint, 16)
+		assert.Equal(t, statuses[0].PublicKeyFingerprint, status.PublicKeyFingerprint)
+		assert.Empty(t, status.QuerierErr)
+	}
+
+	// a node whose querier is not connected is still healthy, the group is ready while threshold nodes are, and
+	// the error is redacted in statuses
+	pingErr := fmt.Errorf("dial tcp user:password@10.0.0.1:5432: connection refused")
+	groupNodes[0].Querier.(*formattedQuerier).pingErr = pingErr
+	assert.True(t, CheckHealth(ctx).Ok)
+	assert.True(t, CheckReadiness(ctx).Ok)
+	statuses = Statuses(ctx)
+	assert.Equal(t, QuerierUnreachable, statuses[0].QuerierErr)
+	assert.Empty(t, statuses[1].QuerierErr)
+	groupNodes[0].Querier.(*formattedQuerier).pingErr = fmt.Errorf("ping: %w", context.DeadlineExceeded)
+	assert.Equal(t, QuerierTimeout, Statuses(ctx)[0].QuerierErr)
+	groupNodes[0].Querier.(*formattedQuerier).pingErr = pingErr
+
+	groupNodes[1].Querier.(*formattedQuerier).pingErr = pingErr
+	readiness = CheckReadiness(ctx)
+	assert.False(t, readiness.Ok)
+	assert.Equal(t, map[string]string{group.Id: NoAgreementErr.Error()}, readiness.Problems)
+
+	// a node of the group not loaded is unhealthy
+	delete(nodeTable, groupNodes[2].Id)
+	health := CheckHealth(ctx)
+	assert.False(t, health.Ok)
+	assert.Equal(t, map[string]string{groupNodes[2].Id: NodeNotExistedErr.Error()}, health.Problems)
+}
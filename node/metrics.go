@@ -0,0 +1,140 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"time"
+
+	"github.com/KofClubs/siwa/node/querier"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const (
+	metricsNamespace = "siwa"
+
+	okResult    = "ok"
+	errorResult = "error"
+)
+
+var (
+	// MetricsRegistry registers metrics of nodes and groups in this process, labelled by node id and group id
+	MetricsRegistry = prometheus.NewRegistry()
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "query_duration_seconds",
+		Help:      "Duration of queries by querier source, result is ok or error",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"node_id", "group_id", "querier_source", "result"})
+	signaturesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "signatures_total",
+		Help:      "Count of partial signatures signed, result is ok or error",
+	}, []string{"node_id", "group_id", "result"})
+	recoveriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "recoveries_total",
+		Help:      "Count of threshold signatures recovered by the node, result is ok or error",
+	}, []string{"node_id", "group_id", "result"})
+	partialSignaturesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "partial_signatures_total",
+		Help:      "Count of partial signatures of the signer node verified before recovery, by validity",
+	}, []string{"node_id", "group_id", "valid"})
+	partialSignatureValidity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "partial_signature_validity_ratio",
+		Help:      "Ratio of valid partial signatures in the last recovery of the group by the node",
+	}, []string{"node_id", "group_id"})
+	dkgPhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "dkg_phase_duration_seconds",
+		Help:      "Duration of the node in dkg phases, phase is deal, response or process_response",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"node_id", "group_id", "phase"})
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		queryDuration,
+		signaturesTotal,
+		recoveriesTotal,
+		partialSignaturesTotal,
+		partialSignatureValidity,
+		dkgPhaseDuration,
+		querier.CacheRequestsTotal,
+		groupCollector{},
+	)
+}
+
+var (
+	groupSizeDesc = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "group_size"),
+		"Count of nodes in the group", []string{"group_id"}, nil)
+	groupThresholdDesc = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "group_threshold"),
+		"Threshold of the group", []string{"group_id"}, nil)
+	dkgCertifiedDesc = prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "dkg_certified"),
+		"1 if the dkg of the node in the group is certified, 0 otherwise", []string{"node_id", "group_id"}, nil)
+)
+
+// groupCollector reads sizes, thresholds and dkg certification of groups when scraped
+type groupCollector struct{}
+
+func (groupCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- groupSizeDesc
+	descs <- groupThresholdDesc
+	descs <- dkgCertifiedDesc
+}
+
+func (groupCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, group := range ListGroups() {
+		metrics <- prometheus.MustNewConstMetric(groupSizeDesc, prometheus.GaugeValue, float64(len(group.NodeIds)),
+			group.Id)
+		metrics <- prometheus.MustNewConstMetric(groupThresholdDesc, prometheus.GaugeValue,
+			float64(group.Threshold), group.Id)
+		for _, nodeId := range group.getNodeIds() {
+			certified := 0.
+			node := getNode(nodeId)
+			if node != nil {
+				dkg := node.GetDkg(group.Id)
+				if dkg != nil && dkg.PedersenDkg != nil && dkg.PedersenDkg.Certified() {
+					certified = 1
+				}
+			}
+			metrics <- prometheus.MustNewConstMetric(dkgCertifiedDesc, prometheus.GaugeValue, certified, nodeId,
+				group.Id)
+		}
+	}
+}
+
+func resultOf(err error) string {
+	if err != nil {
+		return errorResult
+	}
+	return okResult
+}
+
+func observeDuration(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,100 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/KofClubs/siwa/node/querier"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	metric := &dto.Metric{}
+	require.Nil(t, observer.(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+// resetMetrics clears series of other tests, which share node ids and group ids as the registry is reset
+func resetMetrics() {
+	for _, vec := range []interface{ Reset() }{queryDuration, signaturesTotal, recoveriesTotal,
+		partialSignaturesTotal, partialSignatureValidity, dkgPhaseDuration} {
+		vec.Reset()
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+	resetMetrics()
+
+	group, err := CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	groupNodes := createFormattedNodes(t, group.Id, querier.NormalizationConfig{}, "2", "2", "2")
+	require.Nil(t, RunDkg(group.Id))
+	_, err = QueryGroup(context.Background(), group.Id, "")
+	require.Nil(t, err)
+
+	// queries, signing and dkg phases of every node, labelled by node id and group id
+	for _, node := range groupNodes {
+		assert.Equal(t, uint64(1), sampleCount(t, queryDuration.WithLabelValues(node.Id, group.Id, "formatted",
+			okResult)))
+		assert.Equal(t, 1., testutil.ToFloat64(signaturesTotal.WithLabelValues(node.Id, group.Id, okResult)))
+		assert.Equal(t, 1., testutil.ToFloat64(partialSignaturesTotal.WithLabelValues(node.Id, group.Id, "true")))
+		for _, phase := range []string{"deal", "response", "process_response"} {
+			assert.Equal(t, uint64(1), sampleCount(t, dkgPhaseDuration.WithLabelValues(node.Id, group.Id, phase)))
+		}
+	}
+
+	// the first signer recovers
+	recoverer := groupNodes[0].Id
+	assert.Equal(t, 1., testutil.ToFloat64(recoveriesTotal.WithLabelValues(recoverer, group.Id, okResult)))
+	assert.Equal(t, 1., testutil.ToFloat64(partialSignatureValidity.WithLabelValues(recoverer, group.Id)))
+	assert.False(t, groupNodes[0].Verify(group.Id, "", "", "2", []byte("not a signature")))
+	_, ok := groupNodes[0].Recover(group.Id, "", "", "2", [][]byte{[]byte("not a signature")})
+	assert.False(t, ok)
+	assert.Equal(t, 1., testutil.ToFloat64(recoveriesTotal.WithLabelValues(recoverer, group.Id, errorResult)))
+
+	// sizes, thresholds and certification are read when scraped
+	expected := fmt.Sprintf(`
+# HELP siwa_dkg_certified 1 if the dkg of the node in the group is certified, 0 otherwise
+# TYPE siwa_dkg_certified gauge
+siwa_dkg_certified{group_id="%[1]s",node_id="%[2]s"} 1
+siwa_dkg_certified{group_id="%[1]s",node_id="%[3]s"} 1
+siwa_dkg_certified{group_id="%[1]s",node_id="%[4]s"} 1
+# HELP siwa_group_size Count of nodes in the group
+# TYPE siwa_group_size gauge
+siwa_group_size{group_id="%[1]s"} 3
+# HELP siwa_group_threshold Threshold of the group
+# TYPE siwa_group_threshold gauge
+siwa_group_threshold{group_id="%[1]s"} 2
+`, group.Id, groupNodes[0].Id, groupNodes[1].Id, groupNodes[2].Id)
+	assert.Nil(t, testutil.GatherAndCompare(MetricsRegistry, strings.NewReader(expected), "siwa_dkg_certified",
+		"siwa_group_size", "siwa_group_threshold"))
+}
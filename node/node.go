@@ -23,31 +23,57 @@ THE SOFTWARE.
 package node
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/KofClubs/siwa/crypto"
 	"github.com/KofClubs/siwa/node/querier"
+	"github.com/KofClubs/siwa/node/telemetry"
 	"github.com/MonteCarloClub/log"
 	"github.com/MonteCarloClub/utils"
 	"go.dedis.ch/kyber/v3"
-	"go.dedis.ch/kyber/v3/pairing/bn256"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultQueryTimeout is the query timeout of a node if not specified
+const DefaultQueryTimeout = 5 * time.Second
+
+var (
+	NodeNotExistedErr = fmt.Errorf("node not existed")
+	ReshareErr        = fmt.Errorf("fail to reshare")
+	SignErr           = fmt.Errorf("fail to sign message")
+)
+
+var tracer = otel.Tracer("github.com/KofClubs/siwa/node")
+
 type UnmarshalledNode struct {
-	GroupId       string `yaml:"group_id"`
-	PrivateKey    string `yaml:"private_key"`
-	QuerierSource string `yaml:"querier_source"`
-	RedisAddress  string `yaml:"redis_address"`
+	GroupId             string `yaml:"group_id"`
+	PrivateKey          string `yaml:"private_key"`
+	UnmarshalledQuerier `yaml:",inline"`
+	Region              string `yaml:"region"`
+	// QueryTimeout is DefaultQueryTimeout if not positive
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// Normalization formats results before signing
+	Normalization querier.NormalizationConfig `yaml:"normalization"`
 }
 
+// Node may belong to several groups, with a distributed key generator in each group
 type Node struct {
-	Id, GroupId string
-	Rank        int
-	Suite       *bn256.Suite
-	privateKey  kyber.Scalar
-	PublicKey   kyber.Point
-	Dkg         *crypto.DistributedKeyGenerator
-	Querier     querier.Querier
+	// Id is generated by GroupId, the group where the node is created
+	Id, GroupId   string
+	Rank          int
+	Region        string
+	Suite         *crypto.BlsSuite
+	privateKey    kyber.Scalar
+	PublicKey     kyber.Point
+	Dkgs          map[string]*crypto.DistributedKeyGenerator
+	QuerierSource string
+	Querier       querier.Querier
+	QueryTimeout  time.Duration
+	Normalizer    *querier.Normalizer
 }
 
 func (unmarshalledNode *UnmarshalledNode) CreateNode() *Node {
@@ -60,16 +86,15 @@ func (unmarshalledNode *UnmarshalledNode) CreateNode() *Node {
 	if groupId == "" {
 		log.Info("group not specified, select one for this node",
 			"private key", unmarshalledNode.PrivateKey)
-		// todo: call the scheduling algorithm to assign it to an group
+		groupId = scheduleGroup(unmarshalledNode)
 		log.Info("group selected for this node", "private key", unmarshalledNode.PrivateKey,
 			"group id", groupId)
 	}
-	id := generateNodeId(groupId)
-	group := getGroup(groupId)
-	if group == nil {
+	if getGroup(groupId) == nil {
 		log.Error("nil group", "err", utils.NilPtrDeref)
 		return nil
 	}
+	id := generateNodeId(groupId)
 
 	suite := crypto.GetBlsSuite()
 	privateKey, err := crypto.GetBlsPrivateKey(suite, unmarshalledNode.PrivateKey)
@@ -85,80 +110,228 @@ func (unmarshalledNode *UnmarshalledNode) CreateNode() *Node {
 		return nil
 	}
 
-	var querierOfNode querier.Querier
-	switch unmarshalledNode.QuerierSource {
-	case "redis":
-		redisQuerier := &querier.RedisQuerier{}
-		redisQuerier.Init(unmarshalledNode.RedisAddress)
-		querierOfNode = querier.Querier(redisQuerier)
-	default:
-		log.Error("fail to init querier of node", "err", fmt.Errorf("illegal querier_source"))
+	normalizer, err := querier.NewNormalizer(unmarshalledNode.Normalization)
+	if err != nil {
+		log.Error("fail to create normalizer of node", "private key", unmarshalledNode.PrivateKey, "err", err)
+		return nil
+	}
+	querierOfNode, err := unmarshalledNode.CreateQuerier()
+	if err != nil {
+		log.Error("fail to init querier of node", "private key", unmarshalledNode.PrivateKey, "err", err)
 		return nil
 	}
 
-	nodeIds, threshold, err := group.addNode(id)
+	node := &Node{
+		Id:            id,
+		GroupId:       groupId,
+		Region:        unmarshalledNode.Region,
+		Suite:         suite,
+		privateKey:    privateKey,
+		PublicKey:     publicKey,
+		Dkgs:          make(map[string]*crypto.DistributedKeyGenerator),
+		QuerierSource: unmarshalledNode.QuerierSource,
+		Querier:       querierOfNode,
+		QueryTimeout:  unmarshalledNode.QueryTimeout,
+		Normalizer:    normalizer,
+	}
+	if node.QueryTimeout <= 0 {
+		node.QueryTimeout = DefaultQueryTimeout
+	}
+	setNode(node)
+	err = node.JoinGroup(groupId)
 	if err != nil {
-		log.Error("fail to add node", "private key", unmarshalledNode.PrivateKey, "err", err)
+		log.Error("fail to join group when creating node", "private key", unmarshalledNode.PrivateKey,
+			"group id", groupId, "err", err)
+		unsetNode(id)
+		querierOfNode.Close()
+		log.Info("creating a node rolled back", "private key", unmarshalledNode.PrivateKey)
 		return nil
 	}
-	// nodeIds[index] == id, for 0<=i<len(nodes): nodes[i].PublicKey == publicKeys[i]
-	var index int
-	nodes := make([]*Node, 0)
-	publicKeys := make([]kyber.Point, 0)
-	for i, nodeId := range nodeIds {
-		if nodeId == id {
-			index = i
-			nodes = append(nodes, &Node{})
-			publicKeys = append(publicKeys, publicKey)
-			continue
+	return node
+}
+
+// JoinGroup adds the node to the group, and reshares among nodes of the group
+func (node *Node) JoinGroup(groupId string) error {
+	if node == nil {
+		log.Error("nil node", "err", utils.NilPtrDeref)
+		return utils.NilPtrDerefErr
+	}
+	group := getGroup(groupId)
+	if group == nil {
+		log.Error("fail to join group", "node id", node.Id, "group id", groupId, "err", GroupNotExistedErr)
+		return GroupNotExistedErr
+	}
+	if _, ok := group.NodeIds[node.Id]; ok {
+		log.Warn("node already in group", "node id", node.Id, "group id", groupId)
+		return nil
+	}
+
+	nodeIds, threshold, err := group.addNode(node.Id, node.PublicKey)
+	if err != nil {
+		log.Error("fail to add node", "node id", node.Id, "group id", groupId, "err", err)
+		return err
+	}
+	err = reshare(groupId, nodeIds, threshold)
+	if err != nil {
+		log.Error("fail to update distributed key generators when joining group", "node id", node.Id,
+			"group id", groupId, "err", err)
+		group.deleteNode(node.Id)
+		delete(node.Dkgs, groupId)
+		return err
+	}
+	log.Info("node joined group", "node id", node.Id, "group id", groupId)
+	return nil
+}
+
+// GroupIds returns ids of groups the node belongs to, sorted
+func (node *Node) GroupIds() []string {
+	groupIds := make([]string, 0)
+	if node == nil {
+		return groupIds
+	}
+	for _, group := range getGroups() {
+		if _, ok := group.NodeIds[node.Id]; ok {
+			groupIds = append(groupIds, group.Id)
 		}
-		peerNode := getNode(nodeId)
-		nodes = append(nodes, peerNode)
-		publicKeys = append(publicKeys, peerNode.PublicKey)
 	}
-	var dkg *crypto.DistributedKeyGenerator
-	if threshold < 2 {
-		log.Warn("distributed key generators not updated, threshold should not be less than 2",
-			"private key", unmarshalledNode.PrivateKey)
-	} else {
-		dkg, err = crypto.CreateDistributedKeyGenerator(suite, privateKey, publicKeys, threshold)
+	return groupIds
+}
+
+func (node *Node) GetDkg(groupId string) *crypto.DistributedKeyGenerator {
+	if node == nil || node.Dkgs == nil {
+		return nil
+	}
+	return node.Dkgs[groupId]
+}
+
+// LeaveGroup removes the node from the group, and reshares among the rest of nodes of the group
+func (node *Node) LeaveGroup(groupId string) error {
+	if node == nil {
+		log.Error("nil node", "err", utils.NilPtrDeref)
+		return utils.NilPtrDerefErr
+	}
+	group := getGroup(groupId)
+	if group == nil {
+		log.Error("fail to leave group", "node id", node.Id, "group id", groupId, "err", GroupNotExistedErr)
+		return GroupNotExistedErr
+	}
+	err := group.checkNodeDeletion(node.Id)
+	if err != nil {
+		log.Error("fail to leave group", "node id", node.Id, "group id", groupId, "err", err)
+		return err
+	}
+
+	err = node.deleteFromGroup(group)
+	if err != nil {
+		log.Error("fail to reshare when leaving group", "node id", node.Id, "group id", groupId, "err", err)
+		return err
+	}
+	log.Info("node left group", "node id", node.Id, "group id", groupId)
+	return nil
+}
+
+// Leave removes the node from all its groups and the registry, and reshares among the rest of nodes
+func (node *Node) Leave() error {
+	if node == nil {
+		log.Error("nil node", "err", utils.NilPtrDeref)
+		return utils.NilPtrDerefErr
+	}
+
+	err := removeNode(node.Id)
+	if err != nil {
+		log.Error("fail to leave", "node id", node.Id, "err", err)
+		return err
+	}
+	log.Info("node left", "node id", node.Id)
+	return nil
+}
+
+// EvictNode removes a node from all its groups and the registry, and reshares among the rest of nodes
+func EvictNode(nodeId string) error {
+	err := removeNode(nodeId)
+	if err != nil {
+		log.Error("fail to evict node", "node id", nodeId, "err", err)
+		return err
+	}
+	log.Info("node evicted", "node id", nodeId)
+	return nil
+}
+
+// removeNode refuses to remove the node if any of its groups would fall below the safe threshold. Otherwise the
+// node is removed from all its groups even if some of them fail to reshare, which are reported by ReshareErr
+func removeNode(nodeId string) error {
+	node := getNode(nodeId)
+	if node == nil {
+		return NodeNotExistedErr
+	}
+	groups := make([]*Group, 0)
+	for _, groupId := range node.GroupIds() {
+		group := getGroup(groupId)
+		err := group.checkNodeDeletion(nodeId)
 		if err != nil {
-			log.Error("fail to update distributed key generator of node when creating node",
-				"private key", unmarshalledNode.PrivateKey, "err", err)
-			group.deleteNode(id)
-			log.Info("creating a node rolled back", "private key", unmarshalledNode.PrivateKey)
-			return nil
-		}
-		dkg.SetIndex(index)
-		// assert: len(publicKeys) > 1
-		for i := range publicKeys {
-			if i == index {
-				continue
-			}
-			peerNode := nodes[i]
-			err = peerNode.updateDkg(publicKeys, threshold, i)
-			if err != nil {
-				log.Error("fail to update distributed key generator of peer node when creating node",
-					"err", err)
-				return nil
-			}
+			return err
 		}
+		groups = append(groups, group)
 	}
 
-	node := &Node{
-		Id:         id,
-		GroupId:    groupId,
-		Suite:      suite,
-		privateKey: privateKey,
-		PublicKey:  publicKey,
-		Dkg:        dkg,
-		Querier:    querierOfNode,
+	failedGroupIds := make([]string, 0)
+	for _, group := range groups {
+		err := node.deleteFromGroup(group)
+		if err != nil {
+			log.Error("fail to reshare after removing node", "node id", nodeId, "group id", group.Id, "err", err)
+			failedGroupIds = append(failedGroupIds, group.Id)
+		}
+	}
+	unsetNode(nodeId)
+	if node.Querier != nil {
+		node.Querier.Close()
 	}
+	if len(failedGroupIds) > 0 {
+		return fmt.Errorf("%w in groups %v", ReshareErr, failedGroupIds)
+	}
+	return nil
+}
+
+func (node *Node) deleteFromGroup(group *Group) error {
+	group.deleteNode(node.Id)
+	delete(node.Dkgs, group.Id)
 	setNode(node)
-	return node
+	return reshare(group.Id, group.getNodeIds(), group.Threshold)
+}
+
+// reshare recreates distributed key generators of nodes in the group, the dkg index of a node is its position in
+// nodeIds
+func reshare(groupId string, nodeIds []string, threshold int) error {
+	if len(nodeIds) < 2 || threshold < 2 {
+		log.Warn("distributed key generators not updated, threshold should not be less than 2",
+			"group id", groupId, "node count", len(nodeIds), "threshold", threshold)
+		return nil
+	}
+
+	nodes := make([]*Node, 0, len(nodeIds))
+	publicKeys := make([]kyber.Point, 0, len(nodeIds))
+	for _, nodeId := range nodeIds {
+		node := getNode(nodeId)
+		if node == nil {
+			log.Error("fail to get node when resharing", "node id", nodeId, "err", utils.NilPtrDeref)
+			return utils.NilPtrDerefErr
+		}
+		nodes = append(nodes, node)
+		publicKeys = append(publicKeys, node.PublicKey)
+	}
+	unsetDkgIndices(groupId)
+	for i, node := range nodes {
+		err := node.updateDkg(groupId, publicKeys, threshold, i)
+		if err != nil {
+			log.Error("fail to update distributed key generator of node when resharing",
+				"node id", node.Id, "group id", groupId, "err", err)
+			return err
+		}
+	}
+	return nil
 }
 
-func (node *Node) updateDkg(publicKeys []kyber.Point, threshold int, index int) error {
+func (node *Node) updateDkg(groupId string, publicKeys []kyber.Point, threshold int, index int) error {
 	if node == nil {
 		log.Error("nil node", "err", utils.NilPtrDeref)
 		return utils.NilPtrDerefErr
@@ -172,51 +345,113 @@ func (node *Node) updateDkg(publicKeys []kyber.Point, threshold int, index int)
 		log.Error("nil distributed key generator when updated", "err", utils.NilPtrDerefErr)
 		return utils.NilPtrDerefErr
 	}
-	node.Dkg = updatedDkg
-	node.Dkg.SetIndex(index)
+	if node.Dkgs == nil {
+		node.Dkgs = make(map[string]*crypto.DistributedKeyGenerator)
+	}
+	updatedDkg.SetIndex(index)
+	node.Dkgs[groupId] = updatedDkg
 	setNode(node)
 	return nil
 }
 
-func (node *Node) ReadyToQuery() bool {
-	if node == nil || node.Dkg == nil || node.Dkg.PedersenDkg == nil {
-		log.Error("nil node or dkg")
+func (node *Node) ReadyToQuery(groupId string) bool {
+	dkg := node.GetDkg(groupId)
+	if dkg == nil || dkg.PedersenDkg == nil {
+		log.Error("nil node or dkg", "group id", groupId)
 		return false
 	}
-	return node.Dkg.PedersenDkg.Certified()
+	return dkg.PedersenDkg.Certified()
+}
+
+// Query queries the expression within the query timeout and signs the result in the group, it refuses to sign if
+// the querier fails
+func (node *Node) Query(ctx context.Context, groupId, expression string) (message string, signature []byte,
+	err error) {
+	_, message, signature, err = node.query(ctx, groupId, "", expression)
+	return message, signature, err
 }
 
-func (node *Node) Query(expression string) (string, []byte) {
+// query also returns the result of the querier before normalization, the signature is bound to the request id if
+// not empty
+func (node *Node) query(ctx context.Context, groupId, requestId, expression string) (result querier.Result,
+	message string, signature []byte, err error) {
 	if node == nil || node.Querier == nil {
-		log.Error("nil node or querier")
-		return "", nil
+		log.Error("nil node or querier", "err", utils.NilPtrDerefErr)
+		return querier.Result{}, "", nil, utils.NilPtrDerefErr
 	}
+	ctx, span := tracer.Start(ctx, "Node.Query", telemetry.NodeAttributes(node.Id, groupId),
+		trace.WithAttributes(attribute.String("siwa.expression", expression)))
+	defer func() {
+		telemetry.End(span, err)
+	}()
 
-	message := node.Querier.Do(expression)
-	signature := crypto.Sign(node.Suite, node.Dkg, message)
-	return message, signature
+	queryCtx, cancel := context.WithTimeout(ctx, node.QueryTimeout)
+	defer cancel()
+	queryCtx, querySpan := tracer.Start(queryCtx, "Querier.Do",
+		trace.WithAttributes(attribute.String("siwa.querier_source", node.QuerierSource)))
+	start := time.Now()
+	result, err = node.Querier.Do(queryCtx, expression)
+	observeDuration(queryDuration.WithLabelValues(node.Id, groupId, node.QuerierSource, resultOf(err)), start)
+	telemetry.End(querySpan, err)
+	if err != nil {
+		log.Error("fail to query, refuse to sign", "node id", node.Id, "group id", groupId,
+			"expression", expression, "err", err)
+		return result, "", nil, err
+	}
+	message = result.Value
+	if node.Normalizer != nil {
+		message, err = node.Normalizer.Normalize(result)
+		if err != nil {
+			log.Error("fail to normalize result, refuse to sign", "node id", node.Id, "group id", groupId,
+				"expression", expression, "result", result, "err", err)
+			return result, "", nil, err
+		}
+	}
+	_, signSpan := tracer.Start(ctx, "crypto.Sign")
+	signature = crypto.Sign(node.Suite, node.GetDkg(groupId), crypto.SignedPayload(groupId, requestId, expression,
+		message))
+	if signature == nil {
+		log.Error("fail to sign", "node id", node.Id, "group id", groupId, "expression", expression)
+		signaturesTotal.WithLabelValues(node.Id, groupId, errorResult).Inc()
+		telemetry.End(signSpan, SignErr)
+		return result, "", nil, SignErr
+	}
+	signaturesTotal.WithLabelValues(node.Id, groupId, okResult).Inc()
+	signSpan.End()
+	return result, message, signature, nil
 }
 
-func (node *Node) Verify(message string, signature []byte) bool {
+// Verify checks the partial signature of the message answering the expression of the request in the group, the
+// request id is empty if the message answers no request
+func (node *Node) Verify(groupId, requestId, expression, message string, signature []byte) bool {
 	if node == nil {
 		log.Error("nil node")
 		return false
 	}
 
-	return crypto.Verify(node.Suite, node.Dkg, message, signature)
+	return crypto.Verify(node.Suite, node.GetDkg(groupId), crypto.SignedPayload(groupId, requestId, expression,
+		message), signature)
 }
 
-func (node *Node) Recover(message string, signatures [][]byte) ([]byte, bool) {
+// Recover recovers the threshold signature of the message answering the expression of the request in the group
+func (node *Node) Recover(groupId, requestId, expression, message string, signatures [][]byte) ([]byte, bool) {
 	if node == nil {
 		log.Error("nil node")
 		return nil, false
 	}
 
-	group := getGroup(node.GroupId)
+	group := getGroup(groupId)
 	if group == nil {
-		log.Error("fail to get group", "node id", node.Id, "group id", node.GroupId)
+		log.Error("fail to get group", "node id", node.Id, "group id", groupId)
+		return nil, false
 	}
 
-	return crypto.Recover(node.Suite, node.Dkg, group.Threshold, len(group.NodeIds),
-		message, signatures)
+	signature, ok := crypto.Recover(node.Suite, node.GetDkg(groupId), group.Threshold, len(group.NodeIds),
+		crypto.SignedPayload(groupId, requestId, expression, message), signatures)
+	if ok {
+		recoveriesTotal.WithLabelValues(node.Id, groupId, okResult).Inc()
+	} else {
+		recoveriesTotal.WithLabelValues(node.Id, groupId, errorResult).Inc()
+	}
+	return signature, ok
 }
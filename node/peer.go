@@ -0,0 +1,53 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"github.com/KofClubs/siwa/node/peer"
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	"go.dedis.ch/kyber/v3"
+)
+
+// GroupMembership authenticates peers by public keys of nodes in groups, a node out of the group is rejected
+type GroupMembership struct{}
+
+func (GroupMembership) PublicKey(groupId, nodeId string) (kyber.Point, bool) {
+	group := getGroup(groupId)
+	if group == nil {
+		return nil, false
+	}
+	if _, ok := group.NodeIds[nodeId]; !ok {
+		return nil, false
+	}
+	publicKey, ok := group.PublicKeys[nodeId]
+	return publicKey, ok && publicKey != nil
+}
+
+// PeerIdentity is the identity of the node on channels of package peer, which authenticate peers by GroupMembership
+func (node *Node) PeerIdentity() (*peer.Identity, error) {
+	if node == nil {
+		log.Error("nil node", "err", utils.NilPtrDerefErr)
+		return nil, utils.NilPtrDerefErr
+	}
+	return &peer.Identity{NodeId: node.Id, PrivateKey: node.privateKey}, nil
+}
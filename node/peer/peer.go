@@ -0,0 +1,452 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package peer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	"github.com/flynn/noise"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/bls"
+)
+
+const (
+	// DefaultHandshakeTimeout bounds the handshake and the exchange of bindings
+	DefaultHandshakeTimeout = 10 * time.Second
+
+	// maxMessageSize is the max size of a noise message, which carries a payload and its tag
+	maxMessageSize = 65535
+	maxPayloadSize = maxMessageSize - 16
+	bindingPrefix  = "siwa peer binding"
+)
+
+var (
+	NotMemberErr      = fmt.Errorf("peer not a member of the group")
+	AuthenticationErr = fmt.Errorf("fail to authenticate peer")
+	UnexpectedPeerErr = fmt.Errorf("unexpected peer")
+)
+
+var cipherSuite = noise.NewCipherSuite(noise.DH25519, noise.CipherChaChaPoly, noise.HashSHA256)
+
+// Identity is a node, which authenticates itself by its bls private key
+type Identity struct {
+	NodeId     string
+	PrivateKey kyber.Scalar
+}
+
+// Membership looks up public keys of nodes registered in groups
+type Membership interface {
+	// PublicKey returns the public key of the node, or false if the node is not in the group
+	PublicKey(groupId, nodeId string) (kyber.Point, bool)
+}
+
+// binding proves that the node of the bls key owns its end of the channel, by signing the handshake hash
+type binding struct {
+	GroupId   string `json:"group_id"`
+	NodeId    string `json:"node_id"`
+	Signature []byte `json:"signature"`
+}
+
+// bindingMessage is what a binding signs, the role keeps a binding from being reflected to its signer
+func bindingMessage(channelBinding []byte, initiator bool, groupId, nodeId string) []byte {
+	role := "responder"
+	if initiator {
+		role = "initiator"
+	}
+	message := append([]byte(bindingPrefix), 0)
+	message = append(message, channelBinding...)
+	for _, field := range []string{role, groupId, nodeId} {
+		message = binary.BigEndian.AppendUint32(message, uint32(len(field)))
+		message = append(message, field...)
+	}
+	return message
+}
+
+// Conn is an encrypted channel with an authenticated node of the group, membership of both ends is checked again on
+// every message, and the channel is closed once either end left the group or the key of the peer changed
+type Conn struct {
+	GroupId, PeerId string
+
+	nodeId        string
+	membership    Membership
+	peerPublicKey kyber.Point
+	conn          net.Conn
+	encrypt       *noise.CipherState
+	decrypt       *noise.CipherState
+	readMutex     sync.Mutex
+	writeMutex    sync.Mutex
+	readBuffer    []byte
+}
+
+// Dial opens a channel to the node of the group at address, both of which must be members of the group
+func Dial(ctx context.Context, address string, identity *Identity, membership Membership, groupId,
+	peerId string) (*Conn, error) {
+	dialer := &net.Dialer{}
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		log.Error("fail to dial peer", "address", address, "err", err)
+		return nil, err
+	}
+	conn, err := handshake(ctx, netConn, identity, membership, true, groupId)
+	if err != nil {
+		netConn.Close()
+		log.Error("fail to authenticate peer", "address", address, "group id", groupId, "peer id", peerId,
+			"err", err)
+		return nil, err
+	}
+	if conn.PeerId != peerId {
+		netConn.Close()
+		log.Error("fail to authenticate peer", "address", address, "group id", groupId, "peer id", peerId,
+			"authenticated id", conn.PeerId, "err", UnexpectedPeerErr)
+		return nil, UnexpectedPeerErr
+	}
+	return conn, nil
+}
+
+// Listener accepts channels from nodes of groups which the identity belongs to
+type Listener struct {
+	HandshakeTimeout time.Duration
+
+	listener   net.Listener
+	identity   *Identity
+	membership Membership
+}
+
+func Listen(address string, identity *Identity, membership Membership) (*Listener, error) {
+	if identity == nil || membership == nil {
+		log.Error("nil identity or membership", "err", utils.NilPtrDerefErr)
+		return nil, utils.NilPtrDerefErr
+	}
+	netListener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Error("fail to listen", "address", address, "err", err)
+		return nil, err
+	}
+	return &Listener{
+		HandshakeTimeout: DefaultHandshakeTimeout,
+		listener:         netListener,
+		identity:         identity,
+		membership:       membership,
+	}, nil
+}
+
+func (listener *Listener) Addr() net.Addr {
+	return listener.listener.Addr()
+}
+
+func (listener *Listener) Close() error {
+	return listener.listener.Close()
+}
+
+// Serve accepts channels until the context is done, handles those of authenticated peers concurrently, and closes
+// the others
+func (listener *Listener) Serve(ctx context.Context, handle func(conn *Conn)) error {
+	go func() {
+		<-ctx.Done()
+		_ = listener.listener.Close()
+	}()
+	for {
+		netConn, err := listener.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Error("fail to accept", "address", listener.Addr(), "err", err)
+			return err
+		}
+		go func() {
+			handshakeCtx, cancel := context.WithTimeout(ctx, listener.HandshakeTimeout)
+			conn, err := handshake(handshakeCtx, netConn, listener.identity, listener.membership, false, "")
+			cancel()
+			if err != nil {
+				netConn.Close()
+				log.Warn("fail to authenticate peer, connection rejected", "node id", listener.identity.NodeId,
+					"remote address", netConn.RemoteAddr(), "err", err)
+				return
+			}
+			handle(conn)
+		}()
+	}
+}
+
+// handshake runs the noise XX handshake, after which the initiator and then the responder send their bindings,
+// the responder learns the group from the binding of the initiator
+func handshake(ctx context.Context, netConn net.Conn, identity *Identity, membership Membership, initiator bool,
+	groupId string) (*Conn, error) {
+	if identity == nil || membership == nil {
+		log.Error("nil identity or membership", "err", utils.NilPtrDerefErr)
+		return nil, utils.NilPtrDerefErr
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultHandshakeTimeout)
+	}
+	err := netConn.SetDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+	// the static key only lives in the channel, peers are identified by their bls keys
+	staticKeypair, err := cipherSuite.GenerateKeypair(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	handshakeState, err := noise.NewHandshakeState(noise.Config{
+		CipherSuite:   cipherSuite,
+		Random:        rand.Reader,
+		Pattern:       noise.HandshakeXX,
+		Initiator:     initiator,
+		StaticKeypair: staticKeypair,
+	})
+	if err != nil {
+		return nil, err
+	}
+	conn := &Conn{conn: netConn}
+	// XX takes 3 messages, the first of which is written by the initiator
+	for i := 0; i < 3; i++ {
+		var cipherStates [2]*noise.CipherState
+		if (i%2 == 0) == initiator {
+			var message []byte
+			message, cipherStates[0], cipherStates[1], err = handshakeState.WriteMessage(nil, nil)
+			if err == nil {
+				err = writeFrame(netConn, message)
+			}
+		} else {
+			var message []byte
+			message, err = readFrame(netConn)
+			if err == nil {
+				_, cipherStates[0], cipherStates[1], err = handshakeState.ReadMessage(nil, message)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if cipherStates[0] != nil {
+			// the first cipher state encrypts messages from the initiator
+			conn.encrypt, conn.decrypt = cipherStates[0], cipherStates[1]
+			if !initiator {
+				conn.encrypt, conn.decrypt = cipherStates[1], cipherStates[0]
+			}
+		}
+	}
+	channelBinding := handshakeState.ChannelBinding()
+
+	if initiator {
+		err = conn.sendBinding(identity, channelBinding, true, groupId)
+		if err != nil {
+			return nil, err
+		}
+		_, err = conn.receiveBinding(identity, membership, channelBinding, false, groupId)
+	} else {
+		groupId, err = conn.receiveBinding(identity, membership, channelBinding, true, "")
+		if err != nil {
+			return nil, err
+		}
+		err = conn.sendBinding(identity, channelBinding, false, groupId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = netConn.SetDeadline(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	conn.nodeId, conn.membership = identity.NodeId, membership
+	return conn, nil
+}
+
+func (conn *Conn) sendBinding(identity *Identity, channelBinding []byte, initiator bool, groupId string) error {
+	signature, err := bls.Sign(crypto.GetBlsSuite(), identity.PrivateKey,
+		bindingMessage(channelBinding, initiator, groupId, identity.NodeId))
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&binding{GroupId: groupId, NodeId: identity.NodeId, Signature: signature})
+	if err != nil {
+		return err
+	}
+	_, err = conn.writeMessages(data)
+	return err
+}
+
+// receiveBinding authenticates the peer, and returns the group of the binding, which must be groupId if specified
+func (conn *Conn) receiveBinding(identity *Identity, membership Membership, channelBinding []byte, initiator bool,
+	groupId string) (string, error) {
+	message, err := conn.readMessage()
+	if err != nil {
+		return "", err
+	}
+	peerBinding := &binding{}
+	err = json.Unmarshal(message, peerBinding)
+	if err != nil {
+		return "", AuthenticationErr
+	}
+	if groupId != "" && peerBinding.GroupId != groupId {
+		return "", UnexpectedPeerErr
+	}
+	if _, ok := membership.PublicKey(peerBinding.GroupId, identity.NodeId); !ok {
+		return "", NotMemberErr
+	}
+	publicKey, ok := membership.PublicKey(peerBinding.GroupId, peerBinding.NodeId)
+	if !ok || peerBinding.NodeId == identity.NodeId {
+		return "", NotMemberErr
+	}
+	err = bls.Verify(crypto.GetBlsSuite(), publicKey,
+		bindingMessage(channelBinding, initiator, peerBinding.GroupId, peerBinding.NodeId), peerBinding.Signature)
+	if err != nil {
+		return "", AuthenticationErr
+	}
+	conn.GroupId, conn.PeerId, conn.peerPublicKey = peerBinding.GroupId, peerBinding.NodeId, publicKey
+	return peerBinding.GroupId, nil
+}
+
+// Read reads decrypted data, messages of the peer may be split among reads
+func (conn *Conn) Read(p []byte) (int, error) {
+	conn.readMutex.Lock()
+	defer conn.readMutex.Unlock()
+
+	if len(conn.readBuffer) == 0 {
+		message, err := conn.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		err = conn.checkMembership()
+		if err != nil {
+			return 0, err
+		}
+		conn.readBuffer = message
+	}
+	n := copy(p, conn.readBuffer)
+	conn.readBuffer = conn.readBuffer[n:]
+	return n, nil
+}
+
+// readMessage reads and decrypts a noise message
+func (conn *Conn) readMessage() ([]byte, error) {
+	frame, err := readFrame(conn.conn)
+	if err != nil {
+		return nil, err
+	}
+	return conn.decrypt.Decrypt(nil, nil, frame)
+}
+
+// Write encrypts and writes data in noise messages
+func (conn *Conn) Write(p []byte) (int, error) {
+	err := conn.checkMembership()
+	if err != nil {
+		return 0, err
+	}
+	return conn.writeMessages(p)
+}
+
+// checkMembership closes the channel if either end is no longer a member of the group, or the peer is registered
+// with another key than the authenticated one
+func (conn *Conn) checkMembership() error {
+	if _, ok := conn.membership.PublicKey(conn.GroupId, conn.nodeId); ok {
+		publicKey, ok := conn.membership.PublicKey(conn.GroupId, conn.PeerId)
+		if ok && publicKey.Equal(conn.peerPublicKey) {
+			return nil
+		}
+	}
+	log.Warn("peer or node left the group, connection closed", "group id", conn.GroupId, "node id", conn.nodeId,
+		"peer id", conn.PeerId, "err", NotMemberErr)
+	_ = conn.conn.Close()
+	return NotMemberErr
+}
+
+func (conn *Conn) writeMessages(p []byte) (int, error) {
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+
+	written := 0
+	for written < len(p) {
+		end := written + maxPayloadSize
+		if end > len(p) {
+			end = len(p)
+		}
+		message, err := conn.encrypt.Encrypt(nil, nil, p[written:end])
+		if err != nil {
+			return written, err
+		}
+		err = writeFrame(conn.conn, message)
+		if err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+func (conn *Conn) Close() error {
+	return conn.conn.Close()
+}
+
+func (conn *Conn) LocalAddr() net.Addr {
+	return conn.conn.LocalAddr()
+}
+
+func (conn *Conn) RemoteAddr() net.Addr {
+	return conn.conn.RemoteAddr()
+}
+
+func (conn *Conn) SetDeadline(t time.Time) error {
+	return conn.conn.SetDeadline(t)
+}
+
+func (conn *Conn) SetReadDeadline(t time.Time) error {
+	return conn.conn.SetReadDeadline(t)
+}
+
+func (conn *Conn) SetWriteDeadline(t time.Time) error {
+	return conn.conn.SetWriteDeadline(t)
+}
+
+// writeFrame writes the message prefixed by its length in 2 bytes
+func writeFrame(writer io.Writer, message []byte) error {
+	frame := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(message)), uint16(len(message)))
+	_, err := writer.Write(append(frame, message...))
+	return err
+}
+
+func readFrame(reader io.Reader) ([]byte, error) {
+	length := make([]byte, 2)
+	_, err := io.ReadFull(reader, length)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, binary.BigEndian.Uint16(length))
+	_, err = io.ReadFull(reader, message)
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
@@ -0,0 +1,194 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package peer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/util/key"
+)
+
+// mapMembership maps group ids to public keys of their nodes
+type mapMembership map[string]map[string]kyber.Point
+
+func (membership mapMembership) PublicKey(groupId, nodeId string) (kyber.Point, bool) {
+	publicKey, ok := membership[groupId][nodeId]
+	return publicKey, ok
+}
+
+func newIdentity(nodeId string) (*Identity, kyber.Point) {
+	keyPair := key.NewKeyPair(crypto.GetBlsSuite())
+	return &Identity{NodeId: nodeId, PrivateKey: keyPair.Private}, keyPair.Public
+}
+
+// serveEcho serves the identity, echoing on authenticated channels, which are sent to the returned channel
+func serveEcho(t *testing.T, ctx context.Context, identity *Identity, membership Membership) (string,
+	chan *Conn) {
+	listener, err := Listen("127.0.0.1:0", identity, membership)
+	require.Nil(t, err)
+	conns := make(chan *Conn, 4)
+	go func() {
+		_ = listener.Serve(ctx, func(conn *Conn) {
+			conns <- conn
+			defer conn.Close()
+			_, _ = io.Copy(conn, conn)
+		})
+	}()
+	return listener.Addr().String(), conns
+}
+
+func TestConn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, aPublicKey := newIdentity("a")
+	b, bPublicKey := newIdentity("b")
+	c, cPublicKey := newIdentity("c")
+	membership := mapMembership{
+		"group":       {"a": aPublicKey, "b": bPublicKey},
+		"other group": {"a": aPublicKey, "c": cPublicKey},
+	}
+	address, conns := serveEcho(t, ctx, a, membership)
+
+	conn, err := Dial(ctx, address, b, membership, "group", "a")
+	require.Nil(t, err)
+	defer conn.Close()
+	assert.Equal(t, "group", conn.GroupId)
+	assert.Equal(t, "a", conn.PeerId)
+	accepted := <-conns
+	assert.Equal(t, "group", accepted.GroupId)
+	assert.Equal(t, "b", accepted.PeerId)
+
+	// data larger than a noise message is split
+	data := bytes.Repeat([]byte("siwa"), maxMessageSize)
+	go func() {
+		_, _ = conn.Write(data)
+	}()
+	echo := make([]byte, len(data))
+	_, err = io.ReadFull(conn, echo)
+	require.Nil(t, err)
+	assert.Equal(t, data, echo)
+
+	// the peer is not the expected node
+	_, err = Dial(ctx, address, b, membership, "group", "c")
+	assert.Equal(t, UnexpectedPeerErr, err)
+	// it is up to b to reject, a has authenticated b
+	assert.Equal(t, "b", (<-conns).PeerId)
+	// c is in another group of a
+	conn, err = Dial(ctx, address, c, membership, "other group", "a")
+	require.Nil(t, err)
+	conn.Close()
+	assert.Equal(t, "c", (<-conns).PeerId)
+}
+
+func TestConnRejected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, aPublicKey := newIdentity("a")
+	b, bPublicKey := newIdentity("b")
+	c, cPublicKey := newIdentity("c")
+	membership := mapMembership{"group": {"a": aPublicKey, "b": bPublicKey}}
+	address, conns := serveEcho(t, ctx, a, membership)
+
+	// c is not a member known by a
+	_, err := Dial(ctx, address, c, mapMembership{"group": {"a": aPublicKey, "c": cPublicKey}}, "group", "a")
+	assert.NotNil(t, err)
+	// c claims to be b without the private key of b
+	impostor := &Identity{NodeId: "b", PrivateKey: c.PrivateKey}
+	_, err = Dial(ctx, address, impostor, membership, "group", "a")
+	assert.NotNil(t, err)
+	// a is not in the group
+	_, err = Dial(ctx, address, c, mapMembership{"other group": {"a": aPublicKey, "c": cPublicKey}}, "other group",
+		"a")
+	assert.NotNil(t, err)
+	// a client which never handshakes
+	listener, err := Listen("127.0.0.1:0", a, membership)
+	require.Nil(t, err)
+	listener.HandshakeTimeout = 100 * time.Millisecond
+	go func() {
+		_ = listener.Serve(ctx, func(conn *Conn) {
+			conns <- conn
+		})
+	}()
+	netConn, err := net.Dial("tcp", listener.Addr().String())
+	require.Nil(t, err)
+	defer netConn.Close()
+	_, err = netConn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+
+	// the responder is authenticated as well
+	address, _ = serveEcho(t, ctx, &Identity{NodeId: "a", PrivateKey: c.PrivateKey}, membership)
+	_, err = Dial(ctx, address, b, membership, "group", "a")
+	assert.Equal(t, AuthenticationErr, err)
+
+	select {
+	case conn := <-conns:
+		assert.Fail(t, "rejected peer handled", conn.PeerId)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestConnLeft(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, aPublicKey := newIdentity("a")
+	b, bPublicKey := newIdentity("b")
+	membershipOfA := mapMembership{"group": {"a": aPublicKey, "b": bPublicKey}}
+	membershipOfB := mapMembership{"group": {"a": aPublicKey, "b": bPublicKey}}
+	address, conns := serveEcho(t, ctx, a, membershipOfA)
+	conn, err := Dial(ctx, address, b, membershipOfB, "group", "a")
+	require.Nil(t, err)
+	defer conn.Close()
+	<-conns
+
+	// a message from b is rejected once b left the group known by a, which closes the channel
+	delete(membershipOfA["group"], "b")
+	_, err = conn.Write([]byte("siwa"))
+	require.Nil(t, err)
+	_, err = conn.Read(make([]byte, 4))
+	assert.NotNil(t, err)
+
+	// b is rejected until it joins again
+	_, err = Dial(ctx, address, b, membershipOfB, "group", "a")
+	assert.NotNil(t, err)
+	membershipOfA["group"]["b"] = bPublicKey
+	conn, err = Dial(ctx, address, b, membershipOfB, "group", "a")
+	require.Nil(t, err)
+	defer conn.Close()
+	<-conns
+
+	// b stops writing once a is registered with another key
+	_, otherPublicKey := newIdentity("a")
+	membershipOfB["group"]["a"] = otherPublicKey
+	_, err = conn.Write([]byte("siwa"))
+	assert.Equal(t, NotMemberErr, err)
+	_, err = conn.Read(make([]byte, 4))
+	assert.NotNil(t, err)
+}
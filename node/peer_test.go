@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KofClubs/siwa/node/peer"
+	"github.com/KofClubs/siwa/node/querier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeer(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group, err := CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	groupNodes := createFormattedNodes(t, group.Id, querier.NormalizationConfig{}, "2", "2")
+	otherGroup, err := CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	otherNode := createFormattedNodes(t, otherGroup.Id, querier.NormalizationConfig{}, "2")[0]
+
+	identity, err := groupNodes[0].PeerIdentity()
+	require.Nil(t, err)
+	listener, err := peer.Listen("127.0.0.1:0", identity, GroupMembership{})
+	require.Nil(t, err)
+	address := listener.Addr().String()
+	conns := make(chan *peer.Conn, 1)
+	go func() {
+		_ = listener.Serve(ctx, func(conn *peer.Conn) {
+			conns <- conn
+		})
+	}()
+	dial := func(dialer *Node, groupId string) (*peer.Conn, error) {
+		identity, err := dialer.PeerIdentity()
+		require.Nil(t, err)
+		return peer.Dial(ctx, address, identity, GroupMembership{}, groupId, groupNodes[0].Id)
+	}
+
+	conn, err := dial(groupNodes[1], group.Id)
+	require.Nil(t, err)
+	conn.Close()
+	accepted := <-conns
+	assert.Equal(t, groupNodes[1].Id, accepted.PeerId)
+	accepted.Close()
+	// nodes out of the group are rejected
+	_, err = dial(otherNode, group.Id)
+	assert.NotNil(t, err)
+	_, err = dial(otherNode, otherGroup.Id)
+	assert.NotNil(t, err)
+
+	// a node is rejected after leaving the group, and its open channel is closed on the next message
+	require.Nil(t, otherNode.JoinGroup(group.Id))
+	conn, err = dial(otherNode, group.Id)
+	require.Nil(t, err)
+	defer conn.Close()
+	accepted = <-conns
+	defer accepted.Close()
+	assert.Equal(t, otherNode.Id, accepted.PeerId)
+	getGroup(group.Id).deleteNode(otherNode.Id)
+	_, err = conn.Write([]byte("price"))
+	assert.Equal(t, peer.NotMemberErr, err)
+	_, err = accepted.Write([]byte("price"))
+	assert.Equal(t, peer.NotMemberErr, err)
+	_, err = dial(otherNode, group.Id)
+	assert.NotNil(t, err)
+}
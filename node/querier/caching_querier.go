@@ -0,0 +1,299 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	DefaultCacheTtl         = time.Second
+	DefaultCacheMaxEntries  = 1024
+	DefaultCacheCallTimeout = 10 * time.Second
+)
+
+type CacheConfig struct {
+	// Ttl is how long a result is fresh, DefaultCacheTtl if not positive
+	Ttl time.Duration `yaml:"ttl"`
+	// Ttls overrides Ttl by expression
+	Ttls map[string]time.Duration `yaml:"ttls"`
+	// MaxStaleness is how long an expired result is still served if the backend fails, 0 for never
+	MaxStaleness time.Duration `yaml:"max_staleness"`
+	// MaxEntries limits count of cached expressions, DefaultCacheMaxEntries if not positive
+	MaxEntries int `yaml:"max_entries"`
+	// CallTimeout limits a backend call, which is shared by identical requests and outlives their contexts,
+	// DefaultCacheCallTimeout if not positive
+	CallTimeout time.Duration `yaml:"call_timeout"`
+	// QuerierSource and QuerierConfig create the backend by the registry
+	QuerierSource string      `yaml:"querier_source"`
+	QuerierConfig interface{} `yaml:"querier_config"`
+}
+
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// StaleHits counts expired results served since the backend failed, they are not counted in Hits
+	StaleHits uint64
+	// Coalesced counts misses which shared the backend call of an identical concurrent request
+	Coalesced uint64
+}
+
+type cacheEntry struct {
+	result   Result
+	storedAt time.Time
+}
+
+// CachingQuerier decorates a backend querier with a cache of results by expression, identical concurrent misses
+// are merged into one backend call
+type CachingQuerier struct {
+	Querier      Querier
+	Ttl          time.Duration
+	Ttls         map[string]time.Duration
+	MaxStaleness time.Duration
+	MaxEntries   int
+	CallTimeout  time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]cacheEntry
+	calls   singleflight.Group
+	now     func() time.Time
+
+	hits      uint64
+	misses    uint64
+	staleHits uint64
+	coalesced uint64
+}
+
+func init() {
+	_ = Register("cache", Factory{
+		NewConfig: func() interface{} {
+			return &CacheConfig{}
+		},
+		Create: func(config interface{}) (Querier, error) {
+			cacheConfig, ok := config.(*CacheConfig)
+			if !ok || cacheConfig == nil {
+				return nil, IllegalArgsErr
+			}
+			backend, err := Create(cacheConfig.QuerierSource, cacheConfig.QuerierConfig)
+			if err != nil {
+				return nil, err
+			}
+			cachingQuerier := &CachingQuerier{}
+			err = cachingQuerier.Init(*cacheConfig, backend)
+			if err != nil {
+				backend.Close()
+				return nil, err
+			}
+			return cachingQuerier, nil
+		},
+	})
+}
+
+// Init accepts a CacheConfig and the backend Querier
+func (cachingQuerier *CachingQuerier) Init(args ...interface{}) error {
+	if cachingQuerier == nil {
+		log.Error("nil caching querier", "err", utils.NilPtrDerefErr)
+		return utils.NilPtrDerefErr
+	}
+
+	if len(args) < 2 {
+		log.Error("fail to init caching querier", "argc", len(args))
+		return IllegalArgsErr
+	}
+	cacheConfig, ok := args[0].(CacheConfig)
+	if !ok {
+		log.Error("wrong arg type of config to init caching querier", "arg", args[0])
+		return IllegalArgsErr
+	}
+	backend, ok := args[1].(Querier)
+	if !ok || backend == nil {
+		log.Error("wrong arg type of backend to init caching querier", "arg", args[1])
+		return IllegalArgsErr
+	}
+
+	cachingQuerier.Querier = backend
+	cachingQuerier.Ttl = cacheConfig.Ttl
+	if cachingQuerier.Ttl <= 0 {
+		cachingQuerier.Ttl = DefaultCacheTtl
+	}
+	cachingQuerier.Ttls = cacheConfig.Ttls
+	cachingQuerier.MaxStaleness = cacheConfig.MaxStaleness
+	cachingQuerier.MaxEntries = cacheConfig.MaxEntries
+	if cachingQuerier.MaxEntries <= 0 {
+		cachingQuerier.MaxEntries = DefaultCacheMaxEntries
+	}
+	cachingQuerier.CallTimeout = cacheConfig.CallTimeout
+	if cachingQuerier.CallTimeout <= 0 {
+		cachingQuerier.CallTimeout = DefaultCacheCallTimeout
+	}
+	cachingQuerier.entries = make(map[string]cacheEntry)
+	if cachingQuerier.now == nil {
+		cachingQuerier.now = time.Now
+	}
+	return nil
+}
+
+// Do returns the cached result if fresh, or queries the backend, the backend call merged from identical requests
+// keeps values of the context of the first one but not its cancellation, and is limited by CallTimeout instead
+func (cachingQuerier *CachingQuerier) Do(ctx context.Context, expression string) (Result, error) {
+	if cachingQuerier == nil || cachingQuerier.Querier == nil {
+		log.Error("caching querier not initialized", "err", NotInitializedErr)
+		return Result{}, NotInitializedErr
+	}
+
+	entry, cached := cachingQuerier.load(expression)
+	ttl := cachingQuerier.ttl(expression)
+	if cached && cachingQuerier.now().Sub(entry.storedAt) < ttl {
+		atomic.AddUint64(&cachingQuerier.hits, 1)
+		CacheRequestsTotal.WithLabelValues(cacheHit).Inc()
+		return entry.result, nil
+	}
+	atomic.AddUint64(&cachingQuerier.misses, 1)
+	CacheRequestsTotal.WithLabelValues(cacheMiss).Inc()
+
+	var leading bool
+	calls := cachingQuerier.calls.DoChan(expression, func() (interface{}, error) {
+		leading = true
+		callCtx, cancel := context.WithTimeout(detachedContext{parent: ctx}, cachingQuerier.CallTimeout)
+		defer cancel()
+		result, err := cachingQuerier.Querier.Do(callCtx, expression)
+		if err == nil {
+			cachingQuerier.store(expression, result)
+		}
+		return result, err
+	})
+	var err error
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case call := <-calls:
+		if !leading {
+			atomic.AddUint64(&cachingQuerier.coalesced, 1)
+			CacheRequestsTotal.WithLabelValues(cacheCoalesced).Inc()
+		}
+		if call.Err == nil {
+			return call.Val.(Result), nil
+		}
+		err = call.Err
+	}
+
+	if cached && cachingQuerier.now().Sub(entry.storedAt) < ttl+cachingQuerier.MaxStaleness {
+		log.Warn("serve stale result", "expression", expression, "stored at", entry.storedAt, "err", err)
+		atomic.AddUint64(&cachingQuerier.staleHits, 1)
+		CacheRequestsTotal.WithLabelValues(cacheStaleHit).Inc()
+		return entry.result, nil
+	}
+	return Result{}, err
+}
+
+// detachedContext keeps values of its parent, like the trace span, but not its deadline or cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
+
+func (cachingQuerier *CachingQuerier) Ping(ctx context.Context) error {
+	if cachingQuerier == nil {
+		return NotInitializedErr
+	}
+	return Ping(ctx, cachingQuerier.Querier)
+}
+
+func (cachingQuerier *CachingQuerier) Close() {
+	if cachingQuerier == nil || cachingQuerier.Querier == nil {
+		return
+	}
+	cachingQuerier.Querier.Close()
+	cachingQuerier.Querier = nil
+	cachingQuerier.mutex.Lock()
+	cachingQuerier.entries = make(map[string]cacheEntry)
+	cachingQuerier.mutex.Unlock()
+}
+
+func (cachingQuerier *CachingQuerier) Stats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&cachingQuerier.hits),
+		Misses:    atomic.LoadUint64(&cachingQuerier.misses),
+		StaleHits: atomic.LoadUint64(&cachingQuerier.staleHits),
+		Coalesced: atomic.LoadUint64(&cachingQuerier.coalesced),
+	}
+}
+
+func (cachingQuerier *CachingQuerier) ttl(expression string) time.Duration {
+	if ttl, ok := cachingQuerier.Ttls[expression]; ok && ttl > 0 {
+		return ttl
+	}
+	return cachingQuerier.Ttl
+}
+
+func (cachingQuerier *CachingQuerier) load(expression string) (cacheEntry, bool) {
+	cachingQuerier.mutex.Lock()
+	defer cachingQuerier.mutex.Unlock()
+	entry, ok := cachingQuerier.entries[expression]
+	return entry, ok
+}
+
+// store caches the result, and evicts results beyond max staleness if the cache is full, the result is dropped if
+// the cache is still full
+func (cachingQuerier *CachingQuerier) store(expression string, result Result) {
+	cachingQuerier.mutex.Lock()
+	defer cachingQuerier.mutex.Unlock()
+
+	now := cachingQuerier.now()
+	if _, ok := cachingQuerier.entries[expression]; !ok && len(cachingQuerier.entries) >= cachingQuerier.MaxEntries {
+		for cachedExpression, entry := range cachingQuerier.entries {
+			if now.Sub(entry.storedAt) >= cachingQuerier.ttl(cachedExpression)+cachingQuerier.MaxStaleness {
+				delete(cachingQuerier.entries, cachedExpression)
+			}
+		}
+		if len(cachingQuerier.entries) >= cachingQuerier.MaxEntries {
+			return
+		}
+	}
+	cachingQuerier.entries[expression] = cacheEntry{
+		result:   result,
+		storedAt: now,
+	}
+}
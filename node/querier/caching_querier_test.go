@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingQuerier returns its call count as the result, or its error
+type countingQuerier struct {
+	calls int64
+	err   error
+	delay time.Duration
+}
+
+func (querier *countingQuerier) Init(args ...interface{}) error {
+	return nil
+}
+
+func (querier *countingQuerier) Do(ctx context.Context, expression string) (Result, error) {
+	calls := atomic.AddInt64(&querier.calls, 1)
+	time.Sleep(querier.delay)
+	if querier.err != nil {
+		return Result{}, querier.err
+	}
+	return Result{Type: IntegerResult, Value: fmt.Sprint(calls)}, nil
+}
+
+func (querier *countingQuerier) Close() {}
+
+func TestCachingQuerier(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	backend := &countingQuerier{}
+	cachingQuerier := &CachingQuerier{now: func() time.Time { return now }}
+	require.Nil(t, cachingQuerier.Init(CacheConfig{
+		Ttl:          time.Second,
+		Ttls:         map[string]time.Duration{"short": time.Millisecond},
+		MaxStaleness: time.Minute,
+	}, Querier(backend)))
+
+	result, err := cachingQuerier.Do(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", result.Value)
+	result, err = cachingQuerier.Do(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", result.Value)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1}, cachingQuerier.Stats())
+
+	// ttl by expression
+	_, _ = cachingQuerier.Do(ctx, "short")
+	now = now.Add(10 * time.Millisecond)
+	result, _ = cachingQuerier.Do(ctx, "short")
+	assert.Equal(t, "3", result.Value)
+	result, _ = cachingQuerier.Do(ctx, "key")
+	assert.Equal(t, "1", result.Value)
+
+	// expired results are served within max staleness only if the backend fails
+	backend.err = NilResultErr
+	now = now.Add(time.Second)
+	result, err = cachingQuerier.Do(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", result.Value)
+	assert.Equal(t, uint64(1), cachingQuerier.Stats().StaleHits)
+	now = now.Add(time.Minute)
+	_, err = cachingQuerier.Do(ctx, "key")
+	assert.Equal(t, NilResultErr, err)
+	_, err = cachingQuerier.Do(ctx, "uncached")
+	assert.Equal(t, NilResultErr, err)
+
+	backend.err = nil
+	result, err = cachingQuerier.Do(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, "7", result.Value)
+}
+
+func TestCachingQuerierCoalescing(t *testing.T) {
+	backend := &countingQuerier{delay: 50 * time.Millisecond}
+	cachingQuerier := &CachingQuerier{}
+	require.Nil(t, cachingQuerier.Init(CacheConfig{}, Querier(backend)))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := cachingQuerier.Do(context.Background(), "key")
+			assert.Nil(t, err)
+			assert.Equal(t, "1", result.Value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&backend.calls))
+	stats := cachingQuerier.Stats()
+	assert.Equal(t, uint64(10), stats.Misses+stats.Hits)
+	assert.Equal(t, stats.Misses-1, stats.Coalesced)
+
+	// a waiting request leaves with its context
+	backend.delay = 100 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cachingQuerier.Do(ctx, "another key")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestCachingQuerierDetachedCall(t *testing.T) {
+	backend := &staticQuerier{result: Result{Type: IntegerResult, Value: "1"}, delay: 50 * time.Millisecond}
+	cachingQuerier := &CachingQuerier{}
+	require.Nil(t, cachingQuerier.Init(CacheConfig{}, Querier(backend)))
+
+	// the first request gives up, the waiting one still gets the result of the shared call
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cachingQuerier.Do(ctx, "key")
+		firstErr <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	result, err := cachingQuerier.Do(context.Background(), "key")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", result.Value)
+	assert.Equal(t, context.DeadlineExceeded, <-firstErr)
+
+	// the shared call is limited by its own timeout
+	cachingQuerier.CallTimeout = 10 * time.Millisecond
+	_, err = cachingQuerier.Do(context.Background(), "another key")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestCachingQuerierMaxEntries(t *testing.T) {
+	now := time.Unix(0, 0)
+	cachingQuerier := &CachingQuerier{now: func() time.Time { return now }}
+	require.Nil(t, cachingQuerier.Init(CacheConfig{MaxEntries: 1}, Querier(&countingQuerier{})))
+
+	_, _ = cachingQuerier.Do(context.Background(), "a")
+	_, _ = cachingQuerier.Do(context.Background(), "b")
+	assert.Len(t, cachingQuerier.entries, 1)
+	now = now.Add(time.Second)
+	_, _ = cachingQuerier.Do(context.Background(), "b")
+	assert.Contains(t, cachingQuerier.entries, "b")
+
+	cachingQuerier.Close()
+	_, err := cachingQuerier.Do(context.Background(), "b")
+	assert.Equal(t, NotInitializedErr, err)
+}
@@ -0,0 +1,350 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+)
+
+const (
+	MedianAggregation       = "median"
+	QuorumEqualAggregation  = "quorum_equal"
+	FirstSuccessAggregation = "first_success"
+	WeightedMeanAggregation = "weighted_mean"
+)
+
+var (
+	IllegalAggregationErr = fmt.Errorf("illegal aggregation")
+	NoQuorumErr           = fmt.Errorf("too few sources agreed")
+	NotNumericErr         = fmt.Errorf("result not numeric")
+)
+
+type CompositeConfig struct {
+	// Aggregation is median, quorum_equal, first_success or weighted_mean, median if empty
+	Aggregation string `yaml:"aggregation"`
+	// MaxDeviation rejects numeric results deviating from the median of all results by more than this fraction,
+	// 0 for no rejection
+	MaxDeviation float64 `yaml:"max_deviation"`
+	// Quorum is the min count of equal results for quorum_equal, or of accepted results for median and weighted_mean,
+	// a majority of sources if not positive, and ignored by first_success
+	Quorum int `yaml:"quorum"`
+	// Sources are created by the registry when the composite querier is created by Create
+	Sources []CompositeSourceConfig `yaml:"sources"`
+}
+
+type CompositeSourceConfig struct {
+	// Name is "index.querier_source" if empty
+	Name          string      `yaml:"name"`
+	Weight        float64     `yaml:"weight"`
+	QuerierSource string      `yaml:"querier_source"`
+	QuerierConfig interface{} `yaml:"querier_config"`
+}
+
+type CompositeSource struct {
+	Name    string
+	Querier Querier
+	// Weight is used by weighted_mean, 1 if not positive
+	Weight float64
+}
+
+// CompositeQuerier runs the same expression against sources in parallel, and aggregates their results to one
+type CompositeQuerier struct {
+	Sources      []CompositeSource
+	Aggregation  string
+	MaxDeviation float64
+	Quorum       int
+}
+
+func init() {
+	_ = Register("composite", Factory{
+		NewConfig: func() interface{} {
+			return &CompositeConfig{}
+		},
+		Create: func(config interface{}) (Querier, error) {
+			compositeConfig, ok := config.(*CompositeConfig)
+			if !ok || compositeConfig == nil {
+				return nil, IllegalArgsErr
+			}
+			return createCompositeQuerier(*compositeConfig)
+		},
+	})
+}
+
+// createCompositeQuerier creates sources of the config by the registry, and closes them if failed
+func createCompositeQuerier(compositeConfig CompositeConfig) (Querier, error) {
+	sources := make([]CompositeSource, 0, len(compositeConfig.Sources))
+	closeSources := func() {
+		for _, source := range sources {
+			source.Querier.Close()
+		}
+	}
+	for i, sourceConfig := range compositeConfig.Sources {
+		sourceQuerier, err := Create(sourceConfig.QuerierSource, sourceConfig.QuerierConfig)
+		if err != nil {
+			closeSources()
+			return nil, err
+		}
+		name := sourceConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("%v.%v", i, sourceConfig.QuerierSource)
+		}
+		sources = append(sources, CompositeSource{
+			Name:    name,
+			Querier: sourceQuerier,
+			Weight:  sourceConfig.Weight,
+		})
+	}
+
+	compositeQuerier := &CompositeQuerier{}
+	err := compositeQuerier.Init(compositeConfig, sources)
+	if err != nil {
+		closeSources()
+		return nil, err
+	}
+	return compositeQuerier, nil
+}
+
+type sourceResult struct {
+	source CompositeSource
+	result Result
+	err    error
+}
+
+type indexedSourceResult struct {
+	index int
+	sourceResult
+}
+
+// Init accepts a CompositeConfig and sources in []CompositeSource
+func (compositeQuerier *CompositeQuerier) Init(args ...interface{}) error {
+	if compositeQuerier == nil {
+		log.Error("nil composite querier", "err", utils.NilPtrDerefErr)
+		return utils.NilPtrDerefErr
+	}
+
+	if len(args) < 2 {
+		log.Error("fail to init composite querier", "argc", len(args))
+		return IllegalArgsErr
+	}
+	compositeConfig, ok := args[0].(CompositeConfig)
+	if !ok {
+		log.Error("wrong arg type of config to init composite querier", "arg", args[0])
+		return IllegalArgsErr
+	}
+	sources, ok := args[1].([]CompositeSource)
+	if !ok || len(sources) == 0 {
+		log.Error("wrong arg type or no sources to init composite querier", "arg", args[1])
+		return IllegalArgsErr
+	}
+	aggregation := compositeConfig.Aggregation
+	switch aggregation {
+	case "":
+		aggregation = MedianAggregation
+	case MedianAggregation, QuorumEqualAggregation, FirstSuccessAggregation, WeightedMeanAggregation:
+	default:
+		log.Error("fail to init composite querier", "aggregation", aggregation, "err", IllegalAggregationErr)
+		return IllegalAggregationErr
+	}
+
+	compositeQuerier.Sources = sources
+	compositeQuerier.Aggregation = aggregation
+	compositeQuerier.MaxDeviation = compositeConfig.MaxDeviation
+	compositeQuerier.Quorum = compositeConfig.Quorum
+	if compositeQuerier.Quorum <= 0 {
+		compositeQuerier.Quorum = len(sources)/2 + 1
+	}
+	return nil
+}
+
+// Do queries sources in parallel, first_success returns the first result to arrive and cancels the other sources,
+// other aggregations wait for all sources
+func (compositeQuerier *CompositeQuerier) Do(ctx context.Context, expression string) (Result, error) {
+	if compositeQuerier == nil || len(compositeQuerier.Sources) == 0 {
+		log.Error("nil composite querier or no sources", "err", NotInitializedErr)
+		return Result{}, NotInitializedErr
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// buffered for sources still running after first_success returns
+	sourceResultChan := make(chan indexedSourceResult, len(compositeQuerier.Sources))
+	for i, source := range compositeQuerier.Sources {
+		go func(i int, source CompositeSource) {
+			result, err := source.Querier.Do(ctx, expression)
+			if err != nil {
+				log.Warn("fail to query source of composite querier", "source", source.Name, "err", err)
+			}
+			sourceResultChan <- indexedSourceResult{index: i, sourceResult: sourceResult{source: source,
+				result: result, err: err}}
+		}(i, source)
+	}
+
+	sourceResults := make([]sourceResult, len(compositeQuerier.Sources))
+	for range compositeQuerier.Sources {
+		indexedResult := <-sourceResultChan
+		sourceResults[indexedResult.index] = indexedResult.sourceResult
+		if indexedResult.err == nil && compositeQuerier.Aggregation == FirstSuccessAggregation {
+			return indexedResult.result, nil
+		}
+	}
+
+	succeededResults := make([]sourceResult, 0, len(sourceResults))
+	for _, sourceResult := range sourceResults {
+		if sourceResult.err == nil {
+			succeededResults = append(succeededResults, sourceResult)
+		}
+	}
+	if len(succeededResults) == 0 {
+		log.Warn("all sources of composite querier failed", "expression", expression)
+		return Result{}, sourceResults[0].err
+	}
+
+	if compositeQuerier.Aggregation == QuorumEqualAggregation {
+		return compositeQuerier.aggregateQuorumEqual(succeededResults)
+	}
+	return compositeQuerier.aggregateNumeric(succeededResults)
+}
+
+// aggregateQuorumEqual selects the most common result, ties broken by the order of sources
+func (compositeQuerier *CompositeQuerier) aggregateQuorumEqual(sourceResults []sourceResult) (Result, error) {
+	counts := make(map[string]int)
+	var selectedResult Result
+	selectedCount := 0
+	for _, sourceResult := range sourceResults {
+		counts[sourceResult.result.Value]++
+		if counts[sourceResult.result.Value] > selectedCount {
+			selectedResult = sourceResult.result
+			selectedCount = counts[sourceResult.result.Value]
+		}
+	}
+	if selectedCount < compositeQuerier.Quorum {
+		log.Warn("no quorum of equal results", "quorum", compositeQuerier.Quorum, "max count", selectedCount)
+		return Result{}, NoQuorumErr
+	}
+	return selectedResult, nil
+}
+
+// aggregateNumeric drops results which are not numeric like failed ones, rejects results deviating too far from
+// the median, and aggregates the rest
+func (compositeQuerier *CompositeQuerier) aggregateNumeric(succeededResults []sourceResult) (Result, error) {
+	sourceResults := make([]sourceResult, 0, len(succeededResults))
+	values := make([]*big.Rat, 0, len(succeededResults))
+	for _, sourceResult := range succeededResults {
+		value, ok := parseDecimal(sourceResult.result.Value)
+		if !ok {
+			log.Warn("result of source not numeric, dropped", "source", sourceResult.source.Name,
+				"result", sourceResult.result.Value, "err", NotNumericErr)
+			continue
+		}
+		sourceResults = append(sourceResults, sourceResult)
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		log.Warn("no numeric results of sources", "err", NotNumericErr)
+		return Result{}, NotNumericErr
+	}
+
+	median := medianOf(values)
+	acceptedValues := make([]*big.Rat, 0, len(values))
+	acceptedWeights := make([]*big.Rat, 0, len(values))
+	for i, value := range values {
+		if compositeQuerier.MaxDeviation > 0 && median.Sign() != 0 {
+			deviation := new(big.Rat).Quo(new(big.Rat).Sub(value, median), median)
+			deviation.Abs(deviation)
+			if deviation.Cmp(new(big.Rat).SetFloat64(compositeQuerier.MaxDeviation)) > 0 {
+				log.Warn("result of source deviated too far, rejected", "source", sourceResults[i].source.Name,
+					"result", sourceResults[i].result.Value, "median", median.FloatString(8))
+				continue
+			}
+		}
+		weight := sourceResults[i].source.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		acceptedValues = append(acceptedValues, value)
+		acceptedWeights = append(acceptedWeights, new(big.Rat).SetFloat64(weight))
+	}
+	if len(acceptedValues) < compositeQuerier.Quorum {
+		log.Warn("too few accepted results", "quorum", compositeQuerier.Quorum, "accepted", len(acceptedValues))
+		return Result{}, NoQuorumErr
+	}
+
+	var aggregatedValue *big.Rat
+	if compositeQuerier.Aggregation == WeightedMeanAggregation {
+		weightedSum, weightSum := new(big.Rat), new(big.Rat)
+		for i, value := range acceptedValues {
+			weightedSum.Add(weightedSum, new(big.Rat).Mul(value, acceptedWeights[i]))
+			weightSum.Add(weightSum, acceptedWeights[i])
+		}
+		aggregatedValue = weightedSum.Quo(weightedSum, weightSum)
+	} else {
+		aggregatedValue = medianOf(acceptedValues)
+	}
+	return numericResult(aggregatedValue), nil
+}
+
+func medianOf(values []*big.Rat) *big.Rat {
+	sortedValues := make([]*big.Rat, len(values))
+	copy(sortedValues, values)
+	sort.Slice(sortedValues, func(i, j int) bool {
+		return sortedValues[i].Cmp(sortedValues[j]) < 0
+	})
+	middle := len(sortedValues) / 2
+	if len(sortedValues)%2 == 1 {
+		return new(big.Rat).Set(sortedValues[middle])
+	}
+	median := new(big.Rat).Add(sortedValues[middle-1], sortedValues[middle])
+	return median.Quo(median, big.NewRat(2, 1))
+}
+
+// Ping succeeds if any source is connected, as Do does
+func (compositeQuerier *CompositeQuerier) Ping(ctx context.Context) error {
+	if compositeQuerier == nil || len(compositeQuerier.Sources) == 0 {
+		return NotInitializedErr
+	}
+	var err error
+	for _, source := range compositeQuerier.Sources {
+		err = Ping(ctx, source.Querier)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (compositeQuerier *CompositeQuerier) Close() {
+	if compositeQuerier == nil {
+		log.Error("nil composite querier", "err", utils.NilPtrDerefErr)
+		return
+	}
+	for _, source := range compositeQuerier.Sources {
+		source.Querier.Close()
+	}
+	compositeQuerier.Sources = nil
+}
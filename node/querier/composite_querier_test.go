@@ -0,0 +1,170 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticQuerier returns the same result or error after a delay
+type staticQuerier struct {
+	result Result
+	err    error
+	delay  time.Duration
+}
+
+func (querier *staticQuerier) Init(args ...interface{}) error {
+	return nil
+}
+
+func (querier *staticQuerier) Do(ctx context.Context, expression string) (Result, error) {
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	case <-time.After(querier.delay):
+	}
+	return querier.result, querier.err
+}
+
+func (querier *staticQuerier) Close() {}
+
+func newCompositeQuerier(t *testing.T, compositeConfig CompositeConfig, values ...interface{}) *CompositeQuerier {
+	sources := make([]CompositeSource, 0)
+	for i, value := range values {
+		source := CompositeSource{Name: fmt.Sprint(i), Weight: float64(i + 1)}
+		switch typedValue := value.(type) {
+		case string:
+			source.Querier = &staticQuerier{result: Result{Type: DecimalResult, Value: typedValue}}
+		case error:
+			source.Querier = &staticQuerier{err: typedValue}
+		}
+		sources = append(sources, source)
+	}
+	compositeQuerier := &CompositeQuerier{}
+	require.Nil(t, compositeQuerier.Init(compositeConfig, sources))
+	return compositeQuerier
+}
+
+func TestCompositeQuerier(t *testing.T) {
+	ctx := context.Background()
+
+	compositeQuerier := newCompositeQuerier(t, CompositeConfig{MaxDeviation: 0.1, Quorum: 2}, "100", "101.5", "150", NilResultErr)
+	result, err := compositeQuerier.Do(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Type: DecimalResult, Value: "100.75"}, result)
+	compositeQuerier.Quorum = 3
+	_, err = compositeQuerier.Do(ctx, "")
+	assert.Equal(t, NoQuorumErr, err)
+
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{Aggregation: MedianAggregation}, "1", "2", "30")
+	result, err = compositeQuerier.Do(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Type: IntegerResult, Value: "2"}, result)
+
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{Aggregation: WeightedMeanAggregation}, "1", "2.5", "1")
+	result, err = compositeQuerier.Do(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Type: DecimalResult, Value: "1.5"}, result)
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{Aggregation: WeightedMeanAggregation}, "1", "0")
+	result, err = compositeQuerier.Do(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Type: DecimalResult, Value: "0." + strings.Repeat("3", maxCanonicalScale)}, result)
+
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{Aggregation: QuorumEqualAggregation}, "a", "b", "b")
+	result, err = compositeQuerier.Do(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", result.Value)
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{Aggregation: QuorumEqualAggregation}, "a", "b",
+		IllegalReplyErr)
+	_, err = compositeQuerier.Do(ctx, "")
+	assert.Equal(t, NoQuorumErr, err)
+
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{Aggregation: FirstSuccessAggregation},
+		NilResultErr, "b")
+	result, err = compositeQuerier.Do(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", result.Value)
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{}, NilResultErr, IllegalReplyErr)
+	_, err = compositeQuerier.Do(ctx, "")
+	assert.Equal(t, NilResultErr, err)
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{}, "1", "not numeric", "1")
+	result, err = compositeQuerier.Do(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Type: IntegerResult, Value: "1"}, result)
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{}, "1", "not numeric", "not numeric")
+	_, err = compositeQuerier.Do(ctx, "")
+	assert.Equal(t, NoQuorumErr, err)
+	compositeQuerier = newCompositeQuerier(t, CompositeConfig{}, "not numeric", NilResultErr)
+	_, err = compositeQuerier.Do(ctx, "")
+	assert.Equal(t, NotNumericErr, err)
+
+	assert.Equal(t, IllegalAggregationErr, (&CompositeQuerier{}).Init(CompositeConfig{Aggregation: "mode"},
+		[]CompositeSource{{Querier: &staticQuerier{}}}))
+	assert.Equal(t, IllegalArgsErr, (&CompositeQuerier{}).Init(CompositeConfig{}, []CompositeSource{}))
+}
+
+func TestCompositeQuerierParallel(t *testing.T) {
+	sources := make([]CompositeSource, 0)
+	for i := 0; i < 5; i++ {
+		sources = append(sources, CompositeSource{Querier: &staticQuerier{
+			result: Result{Type: IntegerResult, Value: "1"},
+			delay:  100 * time.Millisecond,
+		}})
+	}
+	compositeQuerier := &CompositeQuerier{}
+	require.Nil(t, compositeQuerier.Init(CompositeConfig{}, sources))
+
+	start := time.Now()
+	result, err := compositeQuerier.Do(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", result.Value)
+	assert.Less(t, time.Since(start), 300*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = compositeQuerier.Do(ctx, "")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestCompositeQuerierFirstSuccess(t *testing.T) {
+	slowQuerier := &staticQuerier{result: Result{Type: StringResult, Value: "slow"}, delay: time.Minute}
+	compositeQuerier := &CompositeQuerier{}
+	require.Nil(t, compositeQuerier.Init(CompositeConfig{Aggregation: FirstSuccessAggregation}, []CompositeSource{
+		{Querier: slowQuerier},
+		{Querier: &staticQuerier{err: NilResultErr}},
+		{Querier: &staticQuerier{result: Result{Type: StringResult, Value: "fast"}, delay: 10 * time.Millisecond}},
+	}))
+
+	start := time.Now()
+	result, err := compositeQuerier.Do(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "fast", result.Value)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,373 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/KofClubs/siwa/node/telemetry"
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	DefaultHttpTimeout         = 10 * time.Second
+	DefaultHttpMaxResponseSize = 1 << 20
+)
+
+var (
+	AddressNotAllowedErr = fmt.Errorf("address not allowed")
+	IllegalHttpConfigErr = fmt.Errorf("illegal http config")
+	MethodNotAllowedErr  = fmt.Errorf("method not allowed")
+	ResponseTooLargeErr  = fmt.Errorf("response too large")
+	UnexpectedStatusErr  = fmt.Errorf("unexpected status code")
+	UrlNotAllowedErr     = fmt.Errorf("url not allowed")
+)
+
+// HttpConfig limits what expressions may request, since expressions may come from anyone on a chain
+type HttpConfig struct {
+	// Timeout is DefaultHttpTimeout if not positive
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxResponseSize in bytes is DefaultHttpMaxResponseSize if not positive
+	MaxResponseSize int64     `yaml:"max_response_size"`
+	Tls             TlsConfig `yaml:"tls"`
+	// AllowedUrls are url prefixes which expressions may request, e.g. "https://api.example.com/v1/", matched by
+	// scheme, host and path segments, every url is refused if empty
+	AllowedUrls []string `yaml:"allowed_urls"`
+	// AllowedMethods are http methods expressions may use, only GET if empty
+	AllowedMethods []string `yaml:"allowed_methods"`
+	// AllowedNetworks are cidrs of loopback, private and link-local addresses which may be dialed, other addresses
+	// of these kinds are refused even if urls are allowed
+	AllowedNetworks []string `yaml:"allowed_networks"`
+	// InternalUrls are url prefixes of internal services, matched like AllowedUrls, requests to them carry the trace
+	// context and baggage, which are never sent to other urls
+	InternalUrls []string `yaml:"internal_urls"`
+}
+
+// HttpExpression is the json form of an expression to HttpQuerier, e.g.
+// {"url": "https://example.com/price?symbol=ETH", "selector": "data.price"}
+type HttpExpression struct {
+	Url string `json:"url"`
+	// Method is GET if empty
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	// Selector is a gjson path into the response, see https://github.com/tidwall/gjson/blob/master/SYNTAX.md,
+	// the whole response is the result if empty
+	Selector string `json:"selector"`
+}
+
+func init() {
+	_ = Register("http", Factory{
+		NewConfig: func() interface{} {
+			return &HttpConfig{}
+		},
+		Create: func(config interface{}) (Querier, error) {
+			return initQuerier(&HttpQuerier{}, config)
+		},
+	})
+}
+
+type HttpQuerier struct {
+	HttpClient      *http.Client
+	MaxResponseSize int64
+	AllowedUrls     []*url.URL
+	AllowedMethods  map[string]struct{}
+	AllowedNetworks []*net.IPNet
+	InternalUrls    []*url.URL
+}
+
+func (httpQuerier *HttpQuerier) Init(args ...interface{}) error {
+	if httpQuerier == nil {
+		log.Error("nil http querier", "err", utils.NilPtrDerefErr)
+		return utils.NilPtrDerefErr
+	}
+
+	var httpConfig HttpConfig
+	if len(args) > 0 {
+		switch config := args[0].(type) {
+		case HttpConfig:
+			httpConfig = config
+		case *HttpConfig:
+			if config != nil {
+				httpConfig = *config
+			}
+		default:
+			log.Error("wrong arg type to init http querier", "arg", args[0])
+			return IllegalArgsErr
+		}
+	}
+	if len(args) > 1 {
+		log.Warn("too many arguments to init http querier", "argc", len(args))
+	}
+
+	err := httpQuerier.initAllowlist(httpConfig)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := httpConfig.Tls.build()
+	if err != nil {
+		log.Error("fail to build tls config of http querier", "err", err)
+		return err
+	}
+	// addresses are checked when dialed, after names are resolved and for every redirect
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   httpQuerier.checkDialedAddress,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	transport.TLSClientConfig = tlsConfig
+	timeout := httpConfig.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHttpTimeout
+	}
+	httpQuerier.HttpClient = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if !httpQuerier.isUrlAllowed(request.URL) {
+				log.Warn("http redirect refused", "url", request.URL.Redacted(), "err", UrlNotAllowedErr)
+				return UrlNotAllowedErr
+			}
+			return nil
+		},
+	}
+	httpQuerier.MaxResponseSize = httpConfig.MaxResponseSize
+	if httpQuerier.MaxResponseSize <= 0 {
+		httpQuerier.MaxResponseSize = DefaultHttpMaxResponseSize
+	}
+	return nil
+}
+
+func (httpQuerier *HttpQuerier) Do(ctx context.Context, expression string) (Result, error) {
+	if httpQuerier == nil || httpQuerier.HttpClient == nil {
+		log.Error("nil http querier or client", "err", NotInitializedErr)
+		return Result{}, NotInitializedErr
+	}
+
+	var httpExpression HttpExpression
+	err := json.Unmarshal([]byte(expression), &httpExpression)
+	if err != nil || httpExpression.Url == "" {
+		log.Error("fail to parse http expression", "expression", expression, "err", err)
+		return Result{}, IllegalExpressionErr
+	}
+	method := strings.ToUpper(httpExpression.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if _, ok := httpQuerier.AllowedMethods[method]; !ok {
+		log.Error("http method not allowed", "method", method, "err", MethodNotAllowedErr)
+		return Result{}, MethodNotAllowedErr
+	}
+	request, err := http.NewRequestWithContext(ctx, method, httpExpression.Url, strings.NewReader(httpExpression.Body))
+	if err != nil {
+		log.Error("fail to create http request", "expression", expression, "err", err)
+		return Result{}, IllegalExpressionErr
+	}
+	if !httpQuerier.isUrlAllowed(request.URL) {
+		log.Error("http url not allowed", "url", request.URL.Redacted(), "err", UrlNotAllowedErr)
+		return Result{}, UrlNotAllowedErr
+	}
+	for key, value := range httpExpression.Headers {
+		request.Header.Set(key, value)
+	}
+	if matchesUrlPrefix(httpQuerier.InternalUrls, request.URL) {
+		telemetry.Inject(ctx, request.Header)
+	}
+
+	response, err := httpQuerier.HttpClient.Do(request)
+	if err != nil {
+		log.Warn("fail to send http request", "url", request.URL.Redacted(), "err", err)
+		for _, refusedErr := range []error{AddressNotAllowedErr, UrlNotAllowedErr} {
+			if errors.Is(err, refusedErr) {
+				return Result{}, refusedErr
+			}
+		}
+		return Result{}, err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		log.Warn("unexpected status code of http response", "url", httpExpression.Url,
+			"status code", response.StatusCode)
+		return Result{}, UnexpectedStatusErr
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, httpQuerier.MaxResponseSize+1))
+	if err != nil {
+		log.Warn("fail to read http response", "url", httpExpression.Url, "err", err)
+		return Result{}, err
+	}
+	if int64(len(body)) > httpQuerier.MaxResponseSize {
+		log.Warn("http response too large", "url", httpExpression.Url,
+			"max response size", httpQuerier.MaxResponseSize)
+		return Result{}, ResponseTooLargeErr
+	}
+
+	if httpExpression.Selector == "" {
+		if gjson.ValidBytes(body) {
+			return resultOfJson(gjson.ParseBytes(body))
+		}
+		return Result{Type: StringResult, Value: string(body)}, nil
+	}
+	if !gjson.ValidBytes(body) {
+		log.Warn("http response not json", "url", httpExpression.Url)
+		return Result{}, NilResultErr
+	}
+	return resultOfJson(gjson.GetBytes(body, httpExpression.Selector))
+}
+
+func (httpQuerier *HttpQuerier) initAllowlist(httpConfig HttpConfig) error {
+	var err error
+	httpQuerier.AllowedUrls, err = parseUrlPrefixes(httpConfig.AllowedUrls)
+	if err != nil {
+		return err
+	}
+	if len(httpQuerier.AllowedUrls) == 0 {
+		log.Warn("no allowed url of http querier, every url is refused")
+	}
+	httpQuerier.AllowedMethods = map[string]struct{}{http.MethodGet: {}}
+	for _, method := range httpConfig.AllowedMethods {
+		httpQuerier.AllowedMethods[strings.ToUpper(method)] = struct{}{}
+	}
+	httpQuerier.AllowedNetworks = make([]*net.IPNet, 0, len(httpConfig.AllowedNetworks))
+	for _, allowedNetwork := range httpConfig.AllowedNetworks {
+		_, ipNet, err := net.ParseCIDR(allowedNetwork)
+		if err != nil {
+			log.Error("illegal allowed network of http querier", "network", allowedNetwork, "err", err)
+			return IllegalHttpConfigErr
+		}
+		httpQuerier.AllowedNetworks = append(httpQuerier.AllowedNetworks, ipNet)
+	}
+	httpQuerier.InternalUrls, err = parseUrlPrefixes(httpConfig.InternalUrls)
+	return err
+}
+
+func parseUrlPrefixes(urlPrefixes []string) ([]*url.URL, error) {
+	parsedUrls := make([]*url.URL, 0, len(urlPrefixes))
+	for _, urlPrefix := range urlPrefixes {
+		parsedUrl, err := url.Parse(urlPrefix)
+		if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+			log.Error("illegal url of http querier", "url", urlPrefix, "err", err)
+			return nil, IllegalHttpConfigErr
+		}
+		parsedUrls = append(parsedUrls, parsedUrl)
+	}
+	return parsedUrls, nil
+}
+
+// isUrlAllowed matches the url against allowed url prefixes, urls with user info are refused
+func (httpQuerier *HttpQuerier) isUrlAllowed(requestUrl *url.URL) bool {
+	return matchesUrlPrefix(httpQuerier.AllowedUrls, requestUrl)
+}
+
+// matchesUrlPrefix matches the url against url prefixes by scheme, host with port, and whole path segments, urls
+// with user info match none
+func matchesUrlPrefix(urlPrefixes []*url.URL, requestUrl *url.URL) bool {
+	if requestUrl == nil || requestUrl.User != nil {
+		return false
+	}
+	for _, allowedUrl := range urlPrefixes {
+		if requestUrl.Scheme != allowedUrl.Scheme || !strings.EqualFold(requestUrl.Host, allowedUrl.Host) {
+			continue
+		}
+		allowedPath := allowedUrl.EscapedPath()
+		requestPath := requestUrl.EscapedPath()
+		if allowedPath == "" || allowedPath == "/" || requestPath == allowedPath ||
+			strings.HasPrefix(requestPath, strings.TrimSuffix(allowedPath, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDialedAddress refuses loopback, private, link-local and unspecified addresses out of allowed networks
+func (httpQuerier *HttpQuerier) checkDialedAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return AddressNotAllowedErr
+	}
+	if !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsUnspecified() {
+		return nil
+	}
+	for _, allowedNetwork := range httpQuerier.AllowedNetworks {
+		if allowedNetwork.Contains(ip) {
+			return nil
+		}
+	}
+	log.Warn("http querier refused to dial address", "network", network, "address", address,
+		"err", AddressNotAllowedErr)
+	return AddressNotAllowedErr
+}
+
+// resultOfJson types a json value, numbers are kept in their raw text
+func resultOfJson(value gjson.Result) (Result, error) {
+	switch value.Type {
+	case gjson.String:
+		return Result{Type: StringResult, Value: value.Str}, nil
+	case gjson.Number:
+		if strings.ContainsAny(value.Raw, ".eE") {
+			return Result{Type: DecimalResult, Value: value.Raw}, nil
+		}
+		return Result{Type: IntegerResult, Value: value.Raw}, nil
+	case gjson.True, gjson.False:
+		return Result{Type: BooleanResult, Value: value.Raw}, nil
+	case gjson.JSON:
+		return Result{Type: JsonResult, Value: value.Raw}, nil
+	default:
+		return Result{}, NilResultErr
+	}
+}
+
+func (httpQuerier *HttpQuerier) Close() {
+	if httpQuerier == nil {
+		log.Error("nil http querier", "err", utils.NilPtrDerefErr)
+		return
+	}
+	if httpQuerier.HttpClient != nil {
+		httpQuerier.HttpClient.CloseIdleConnections()
+	}
+	httpQuerier.HttpClient = nil
+}
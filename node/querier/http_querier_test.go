@@ -0,0 +1,232 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newPriceHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/price", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("X-Api-Key") != "key" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(writer, `{"data": {"symbol": "ETH", "price": 1234.5, "volume": 42, "listed": true,
+			"tags": ["l1", "pos"], "delisted_at": null}}`)
+	})
+	mux.HandleFunc("/echo", func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		_, _ = fmt.Fprintf(writer, `{"method": %q, "body": %q, "traceparent": %q}`, request.Method, body,
+			request.Header.Get("traceparent"))
+	})
+	mux.HandleFunc("/text", func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = fmt.Fprint(writer, strings.Repeat("a", 64))
+	})
+	mux.HandleFunc("/redirect", func(writer http.ResponseWriter, request *http.Request) {
+		http.Redirect(writer, request, "/text", http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case <-request.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	return mux
+}
+
+func httpExpression(url, selector string) string {
+	return fmt.Sprintf(`{"url": %q, "headers": {"X-Api-Key": "key"}, "selector": %q}`, url, selector)
+}
+
+// localHttpConfig allows every url of the test server on the loopback address
+func localHttpConfig(serverUrl string) HttpConfig {
+	return HttpConfig{
+		AllowedUrls:     []string{serverUrl},
+		AllowedMethods:  []string{"post"},
+		AllowedNetworks: []string{"127.0.0.0/8", "::1/128"},
+	}
+}
+
+func TestHttpQuerier(t *testing.T) {
+	server := httptest.NewServer(newPriceHandler())
+	defer server.Close()
+	httpQuerier := &HttpQuerier{}
+	httpConfig := localHttpConfig(server.URL)
+	httpConfig.Timeout = 100 * time.Millisecond
+	err := httpQuerier.Init(httpConfig)
+	require.Nil(t, err)
+	defer httpQuerier.Close()
+	ctx := context.Background()
+
+	expectedResults := map[string]Result{
+		"data.symbol": {Type: StringResult, Value: "ETH"},
+		"data.price":  {Type: DecimalResult, Value: "1234.5"},
+		"data.volume": {Type: IntegerResult, Value: "42"},
+		"data.listed": {Type: BooleanResult, Value: "true"},
+		"data.tags":   {Type: JsonResult, Value: `["l1", "pos"]`},
+	}
+	for selector, expectedResult := range expectedResults {
+		result, err := httpQuerier.Do(ctx, httpExpression(server.URL+"/price", selector))
+		assert.Nil(t, err)
+		assert.Equal(t, expectedResult, result)
+	}
+	for _, selector := range []string{"data.delisted_at", "data.not_existed"} {
+		_, err = httpQuerier.Do(ctx, httpExpression(server.URL+"/price", selector))
+		assert.Equal(t, NilResultErr, err)
+	}
+
+	result, err := httpQuerier.Do(ctx, fmt.Sprintf(`{"url": %q, "method": "POST", "body": "b", "selector": "body"}`,
+		server.URL+"/echo"))
+	assert.Nil(t, err)
+	assert.Equal(t, "b", result.Value)
+
+	_, err = httpQuerier.Do(ctx, fmt.Sprintf(`{"url": %q}`, server.URL+"/price"))
+	assert.Equal(t, UnexpectedStatusErr, err)
+	_, err = httpQuerier.Do(ctx, "not json")
+	assert.Equal(t, IllegalExpressionErr, err)
+	_, err = httpQuerier.Do(ctx, httpExpression(server.URL+"/slow", ""))
+	assert.NotNil(t, err)
+
+	httpQuerier.MaxResponseSize = 64
+	result, err = httpQuerier.Do(ctx, httpExpression(server.URL+"/text", ""))
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Type: StringResult, Value: strings.Repeat("a", 64)}, result)
+	httpQuerier.MaxResponseSize = 32
+	_, err = httpQuerier.Do(ctx, httpExpression(server.URL+"/text", ""))
+	assert.Equal(t, ResponseTooLargeErr, err)
+}
+
+func TestHttpQuerierTraceContext(t *testing.T) {
+	server := httptest.NewServer(newPriceHandler())
+	defer server.Close()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+	expression := fmt.Sprintf(`{"url": %q, "selector": "traceparent"}`, server.URL+"/echo")
+
+	// the trace context is only sent to internal urls
+	httpQuerier := &HttpQuerier{}
+	require.Nil(t, httpQuerier.Init(localHttpConfig(server.URL)))
+	defer httpQuerier.Close()
+	result, err := httpQuerier.Do(ctx, expression)
+	require.Nil(t, err)
+	assert.Empty(t, result.Value)
+
+	internalQuerier := &HttpQuerier{}
+	httpConfig := localHttpConfig(server.URL)
+	httpConfig.InternalUrls = []string{server.URL + "/echo"}
+	require.Nil(t, internalQuerier.Init(httpConfig))
+	defer internalQuerier.Close()
+	result, err = internalQuerier.Do(ctx, expression)
+	require.Nil(t, err)
+	assert.Contains(t, result.Value, spanContext.TraceID().String())
+
+	httpConfig.InternalUrls = []string{"not a url"}
+	assert.Equal(t, IllegalHttpConfigErr, (&HttpQuerier{}).Init(httpConfig))
+}
+
+func TestHttpQuerierTls(t *testing.T) {
+	server := httptest.NewTLSServer(newPriceHandler())
+	defer server.Close()
+	ctx := context.Background()
+
+	httpQuerier := &HttpQuerier{}
+	httpConfig := localHttpConfig(server.URL)
+	require.Nil(t, httpQuerier.Init(httpConfig))
+	_, err := httpQuerier.Do(ctx, httpExpression(server.URL+"/price", "data.symbol"))
+	assert.NotNil(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.Nil(t, os.WriteFile(caFile, caPem, 0600))
+	httpConfig.Tls = TlsConfig{CaFile: caFile}
+	require.Nil(t, httpQuerier.Init(&httpConfig))
+	result, err := httpQuerier.Do(ctx, httpExpression(server.URL+"/price", "data.symbol"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ETH", result.Value)
+
+	assert.Equal(t, IllegalTlsConfigErr, httpQuerier.Init(HttpConfig{Tls: TlsConfig{CertFile: caFile}}))
+}
+
+func TestHttpQuerierAllowlist(t *testing.T) {
+	server := httptest.NewServer(newPriceHandler())
+	defer server.Close()
+	ctx := context.Background()
+
+	httpQuerier := &HttpQuerier{}
+	require.Nil(t, httpQuerier.Init())
+	_, err := httpQuerier.Do(ctx, httpExpression(server.URL+"/price", "data.symbol"))
+	assert.Equal(t, UrlNotAllowedErr, err)
+
+	httpConfig := localHttpConfig(server.URL + "/price")
+	httpConfig.AllowedMethods = nil
+	require.Nil(t, httpQuerier.Init(httpConfig))
+	result, err := httpQuerier.Do(ctx, httpExpression(server.URL+"/price", "data.symbol"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ETH", result.Value)
+	_, err = httpQuerier.Do(ctx, httpExpression(server.URL+"/price/", "data.symbol"))
+	assert.NotEqual(t, UrlNotAllowedErr, err)
+	for _, refusedUrl := range []string{server.URL + "/pricey", server.URL + "/text", "http://user@" +
+		strings.TrimPrefix(server.URL, "http://") + "/price", "http://169.254.169.254/price"} {
+		_, err = httpQuerier.Do(ctx, httpExpression(refusedUrl, ""))
+		assert.Equal(t, UrlNotAllowedErr, err, refusedUrl)
+	}
+	_, err = httpQuerier.Do(ctx, fmt.Sprintf(`{"url": %q, "method": "POST"}`, server.URL+"/price"))
+	assert.Equal(t, MethodNotAllowedErr, err)
+
+	// the loopback address of the server is refused out of allowed networks
+	httpConfig.AllowedNetworks = []string{"10.0.0.0/8"}
+	require.Nil(t, httpQuerier.Init(httpConfig))
+	_, err = httpQuerier.Do(ctx, httpExpression(server.URL+"/price", "data.symbol"))
+	assert.Equal(t, AddressNotAllowedErr, err)
+
+	// redirects are checked against allowed urls too
+	require.Nil(t, httpQuerier.Init(localHttpConfig(server.URL+"/redirect")))
+	_, err = httpQuerier.Do(ctx, httpExpression(server.URL+"/redirect", ""))
+	assert.Equal(t, UrlNotAllowedErr, err)
+
+	assert.Equal(t, IllegalHttpConfigErr, httpQuerier.Init(HttpConfig{AllowedUrls: []string{"ftp://example.com"}}))
+	assert.Equal(t, IllegalHttpConfigErr, httpQuerier.Init(HttpConfig{AllowedNetworks: []string{"10.0.0.0"}}))
+}
@@ -0,0 +1,348 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+const (
+	RoundDown     = "down"
+	RoundUp       = "up"
+	RoundHalfUp   = "half_up"
+	RoundHalfEven = "half_even"
+
+	TextEncoding    = "text"
+	Uint256Encoding = "uint256"
+
+	// maxCanonicalScale is the max count of fractional digits of decimals without a configured scale
+	maxCanonicalScale = 77
+)
+
+var (
+	IllegalNormalizationErr = fmt.Errorf("illegal normalization config")
+	NotBooleanErr           = fmt.Errorf("result not boolean")
+	OutOfRangeErr           = fmt.Errorf("result out of range")
+)
+
+type NormalizationConfig struct {
+	// Type is integer, decimal, boolean, json or string, the type of the result if empty, then decimals are in
+	// their shortest exact form
+	Type string `yaml:"type"`
+	// Scale is count of fractional digits of decimals
+	Scale int `yaml:"scale"`
+	// Rounding is down, up, half_up or half_even, half_even if empty
+	Rounding string `yaml:"rounding"`
+	// Encoding is text, or uint256 for 32 big-endian bytes of an integer, decimal scaled by 10^scale or boolean
+	Encoding string `yaml:"encoding"`
+}
+
+// Normalizer formats results in a canonical form, so that honest nodes sign byte-identical messages
+type Normalizer struct {
+	// Type is nil if results are normalized by their own types
+	Type     *ResultType
+	Scale    int
+	Rounding string
+	Encoding string
+}
+
+func NewNormalizer(normalizationConfig NormalizationConfig) (*Normalizer, error) {
+	normalizer := &Normalizer{
+		Scale:    normalizationConfig.Scale,
+		Rounding: normalizationConfig.Rounding,
+		Encoding: normalizationConfig.Encoding,
+	}
+	if normalizationConfig.Type != "" {
+		resultType, err := ParseResultType(normalizationConfig.Type)
+		if err != nil {
+			return nil, err
+		}
+		normalizer.Type = &resultType
+	}
+	if normalizer.Rounding == "" {
+		normalizer.Rounding = RoundHalfEven
+	}
+	if normalizer.Encoding == "" {
+		normalizer.Encoding = TextEncoding
+	}
+
+	switch normalizer.Rounding {
+	case RoundDown, RoundUp, RoundHalfUp, RoundHalfEven:
+	default:
+		return nil, IllegalNormalizationErr
+	}
+	if normalizer.Scale < 0 || normalizer.Scale > maxCanonicalScale {
+		return nil, IllegalNormalizationErr
+	}
+	switch normalizer.Encoding {
+	case TextEncoding:
+	case Uint256Encoding:
+		if normalizer.Type == nil || (*normalizer.Type != IntegerResult && *normalizer.Type != DecimalResult &&
+			*normalizer.Type != BooleanResult) {
+			return nil, IllegalNormalizationErr
+		}
+	default:
+		return nil, IllegalNormalizationErr
+	}
+	return normalizer, nil
+}
+
+func ParseResultType(resultType string) (ResultType, error) {
+	for _, candidate := range []ResultType{StringResult, IntegerResult, DecimalResult, BooleanResult, JsonResult} {
+		if resultType == candidate.String() {
+			return candidate, nil
+		}
+	}
+	return StringResult, IllegalNormalizationErr
+}
+
+// Normalize returns the message to sign of the result
+func (normalizer *Normalizer) Normalize(result Result) (string, error) {
+	resultType := result.Type
+	if normalizer.Type != nil {
+		resultType = *normalizer.Type
+	}
+
+	switch resultType {
+	case IntegerResult, DecimalResult:
+		value, ok := parseDecimal(result.Value)
+		if !ok {
+			return "", NotNumericErr
+		}
+		if normalizer.Type == nil && resultType == DecimalResult {
+			return canonicalDecimal(value)
+		}
+		scale := normalizer.Scale
+		if resultType == IntegerResult {
+			scale = 0
+		}
+		units := roundToScale(value, scale, normalizer.Rounding)
+		if normalizer.Encoding == Uint256Encoding {
+			return encodeUint256(units)
+		}
+		return formatUnits(units, scale), nil
+	case BooleanResult:
+		value, err := parseBoolean(result.Value)
+		if err != nil {
+			return "", err
+		}
+		if normalizer.Encoding == Uint256Encoding {
+			if value {
+				return encodeUint256(big.NewInt(1))
+			}
+			return encodeUint256(big.NewInt(0))
+		}
+		return fmt.Sprint(value), nil
+	case JsonResult:
+		return canonicalJson(result.Value)
+	default:
+		return result.Value, nil
+	}
+}
+
+// parseDecimal parses numbers in decimal or scientific notation, but not fractions
+func parseDecimal(text string) (*big.Rat, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.Contains(text, "/") {
+		return nil, false
+	}
+	return new(big.Rat).SetString(text)
+}
+
+func parseBoolean(text string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	default:
+		return false, NotBooleanErr
+	}
+}
+
+// roundToScale returns the value in units of 10^-scale, rounded
+func roundToScale(value *big.Rat, scale int, rounding string) *big.Int {
+	scaled := new(big.Rat).Mul(value, new(big.Rat).SetInt(pow10(scale)))
+	quotient, remainder := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	awayFromZero := false
+	switch rounding {
+	case RoundUp:
+		awayFromZero = true
+	case RoundHalfUp, RoundHalfEven:
+		twiceRemainder := new(big.Int).Abs(remainder)
+		twiceRemainder.Lsh(twiceRemainder, 1)
+		switch twiceRemainder.Cmp(scaled.Denom()) {
+		case 1:
+			awayFromZero = true
+		case 0:
+			awayFromZero = rounding == RoundHalfUp || quotient.Bit(0) == 1
+		}
+	}
+	if awayFromZero {
+		quotient.Add(quotient, big.NewInt(int64(scaled.Sign())))
+	}
+	return quotient
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+// formatUnits formats units of 10^-scale with exactly scale fractional digits, without the sign of zero
+func formatUnits(units *big.Int, scale int) string {
+	digits := new(big.Int).Abs(units).String()
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if units.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// canonicalDecimal formats the value with the fewest fractional digits which represent it exactly
+func canonicalDecimal(value *big.Rat) (string, error) {
+	for scale := 0; scale <= maxCanonicalScale; scale++ {
+		units := roundToScale(value, scale, RoundDown)
+		if new(big.Rat).SetFrac(units, pow10(scale)).Cmp(value) == 0 {
+			return formatUnits(units, scale), nil
+		}
+	}
+	return "", OutOfRangeErr
+}
+
+// numericResult types the value as an integer or a decimal in its shortest exact form, after rounding half to even
+// at maxCanonicalScale fractional digits, since values like means may not terminate
+func numericResult(value *big.Rat) Result {
+	rounded := new(big.Rat).SetFrac(roundToScale(value, maxCanonicalScale, RoundHalfEven), pow10(maxCanonicalScale))
+	if rounded.IsInt() {
+		return Result{Type: IntegerResult, Value: rounded.Num().String()}
+	}
+	// a rounded value is always exact at maxCanonicalScale
+	text, _ := canonicalDecimal(rounded)
+	return Result{Type: DecimalResult, Value: text}
+}
+
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// encodeUint256 encodes a non-negative integer in 32 big-endian bytes, the layout of uint256 in abi encoding
+func encodeUint256(value *big.Int) (string, error) {
+	if value.Sign() < 0 || value.Cmp(maxUint256) > 0 {
+		return "", OutOfRangeErr
+	}
+	encoded := make([]byte, 32)
+	value.FillBytes(encoded)
+	return string(encoded), nil
+}
+
+// canonicalJson re-encodes json with keys of objects sorted, numbers in their shortest exact form, no insignificant
+// whitespace and no html escaping
+func canonicalJson(text string) (string, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil || decoder.More() {
+		return "", IllegalReplyErr
+	}
+
+	buffer := &bytes.Buffer{}
+	err = writeCanonicalJson(buffer, value)
+	if err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func writeCanonicalJson(buffer *bytes.Buffer, value interface{}) error {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buffer.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeJsonString(buffer, key)
+			buffer.WriteByte(':')
+			err := writeCanonicalJson(buffer, typedValue[key])
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case []interface{}:
+		buffer.WriteByte('[')
+		for i, element := range typedValue {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			err := writeCanonicalJson(buffer, element)
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	case json.Number:
+		number, ok := parseDecimal(typedValue.String())
+		if !ok {
+			return NotNumericErr
+		}
+		canonicalNumber, err := canonicalDecimal(number)
+		if err != nil {
+			return err
+		}
+		buffer.WriteString(canonicalNumber)
+	case string:
+		writeJsonString(buffer, typedValue)
+	case bool:
+		buffer.WriteString(fmt.Sprint(typedValue))
+	case nil:
+		buffer.WriteString("null")
+	}
+	return nil
+}
+
+func writeJsonString(buffer *bytes.Buffer, value string) {
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	// Encode terminates the value with a newline
+	buffer.Truncate(buffer.Len() - 1)
+}
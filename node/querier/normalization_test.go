@@ -0,0 +1,107 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizer(t *testing.T) {
+	tests := []struct {
+		config   NormalizationConfig
+		result   Result
+		expected string
+		err      error
+	}{
+		// by result types
+		{NormalizationConfig{}, Result{Type: DecimalResult, Value: "1.50"}, "1.5", nil},
+		{NormalizationConfig{}, Result{Type: DecimalResult, Value: "1.5e3"}, "1500", nil},
+		{NormalizationConfig{}, Result{Type: DecimalResult, Value: "-0.0"}, "0", nil},
+		{NormalizationConfig{}, Result{Type: IntegerResult, Value: "+0012"}, "12", nil},
+		{NormalizationConfig{}, Result{Type: StringResult, Value: " 1.50 "}, " 1.50 ", nil},
+		{NormalizationConfig{}, Result{Type: BooleanResult, Value: "TRUE"}, "true", nil},
+		{NormalizationConfig{}, Result{Type: DecimalResult, Value: "3/2"}, "", NotNumericErr},
+		// fixed-point decimals
+		{NormalizationConfig{Type: "decimal", Scale: 2}, Result{Value: "1.5"}, "1.50", nil},
+		{NormalizationConfig{Type: "decimal", Scale: 2}, Result{Value: "0.005"}, "0.00", nil},
+		{NormalizationConfig{Type: "decimal", Scale: 2}, Result{Value: "0.015"}, "0.02", nil},
+		{NormalizationConfig{Type: "decimal", Scale: 2, Rounding: RoundHalfUp}, Result{Value: "-0.005"}, "-0.01", nil},
+		{NormalizationConfig{Type: "decimal", Scale: 2, Rounding: RoundDown}, Result{Value: "-0.019"}, "-0.01", nil},
+		{NormalizationConfig{Type: "decimal", Scale: 2, Rounding: RoundUp}, Result{Value: "0.011"}, "0.02", nil},
+		{NormalizationConfig{Type: "decimal", Scale: 2, Rounding: RoundDown}, Result{Value: "-0.001"}, "0.00", nil},
+		// big integers
+		{NormalizationConfig{Type: "integer"}, Result{Value: "123456789012345678901234567890"},
+			"123456789012345678901234567890", nil},
+		{NormalizationConfig{Type: "integer"}, Result{Value: "2.5"}, "2", nil},
+		{NormalizationConfig{Type: "boolean"}, Result{Value: "1"}, "true", nil},
+		{NormalizationConfig{Type: "boolean"}, Result{Value: "yes"}, "", NotBooleanErr},
+		// canonical json
+		{NormalizationConfig{}, Result{Type: JsonResult, Value: `{ "b": [1.50, 2e2, "<&>"], "a": null }`},
+			`{"a":null,"b":[1.5,200,"<&>"]}`, nil},
+		{NormalizationConfig{Type: "json"}, Result{Value: `{"a": 1} {}`}, "", IllegalReplyErr},
+	}
+	for _, test := range tests {
+		normalizer, err := NewNormalizer(test.config)
+		require.Nil(t, err)
+		message, err := normalizer.Normalize(test.result)
+		assert.Equal(t, test.err, err, test.result.Value)
+		assert.Equal(t, test.expected, message, test.result.Value)
+	}
+}
+
+func TestUint256Encoding(t *testing.T) {
+	normalizer, err := NewNormalizer(NormalizationConfig{Type: "decimal", Scale: 18, Encoding: Uint256Encoding})
+	require.Nil(t, err)
+	message, err := normalizer.Normalize(Result{Value: "1.5"})
+	assert.Nil(t, err)
+	require.Len(t, message, 32)
+	expected, _ := new(big.Int).SetString("1500000000000000000", 10)
+	assert.Equal(t, expected, new(big.Int).SetBytes([]byte(message)))
+
+	_, err = normalizer.Normalize(Result{Value: "-1"})
+	assert.Equal(t, OutOfRangeErr, err)
+	_, err = normalizer.Normalize(Result{Value: "1" + strings.Repeat("0", 60)})
+	assert.Equal(t, OutOfRangeErr, err)
+
+	normalizer, err = NewNormalizer(NormalizationConfig{Type: "boolean", Encoding: Uint256Encoding})
+	require.Nil(t, err)
+	message, _ = normalizer.Normalize(Result{Value: "true"})
+	assert.Equal(t, string(append(make([]byte, 31), 1)), message)
+
+	for _, config := range []NormalizationConfig{
+		{Type: "float"},
+		{Rounding: "ceiling"},
+		{Scale: -1},
+		{Encoding: Uint256Encoding},
+		{Type: "string", Encoding: Uint256Encoding},
+		{Encoding: "hex"},
+	} {
+		_, err = NewNormalizer(config)
+		assert.Equal(t, IllegalNormalizationErr, err, config)
+	}
+}
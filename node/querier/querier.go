@@ -22,8 +22,74 @@ THE SOFTWARE.
 
 package querier
 
+import (
+	"context"
+	"fmt"
+)
+
+var (
+	IllegalArgsErr       = fmt.Errorf("illegal args to init querier")
+	IllegalExpressionErr = fmt.Errorf("illegal expression")
+	IllegalReplyErr      = fmt.Errorf("illegal reply from data source")
+	NilResultErr         = fmt.Errorf("nil result")
+	NotInitializedErr    = fmt.Errorf("querier not initialized")
+)
+
+type ResultType int
+
+const (
+	StringResult ResultType = iota
+	IntegerResult
+	DecimalResult
+	BooleanResult
+	JsonResult
+)
+
+func (resultType ResultType) String() string {
+	switch resultType {
+	case IntegerResult:
+		return "integer"
+	case DecimalResult:
+		return "decimal"
+	case BooleanResult:
+		return "boolean"
+	case JsonResult:
+		return "json"
+	default:
+		return "string"
+	}
+}
+
+// Result is the value queried from a data source, in its text form, typed by the querier
+type Result struct {
+	Type  ResultType
+	Value string
+}
+
+func (result Result) String() string {
+	return result.Value
+}
+
+// Querier queries a data source, Do should respect cancellation and deadline of ctx
 type Querier interface {
-	Init(args ...interface{})
-	Do(expression string) string
+	Init(args ...interface{}) error
+	Do(ctx context.Context, expression string) (Result, error)
 	Close()
 }
+
+// Pinger is implemented by queriers which check connectivity to their data sources without querying
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks connectivity of the querier if it is a Pinger, other queriers are assumed connected
+func Ping(ctx context.Context, querier Querier) error {
+	if querier == nil {
+		return NotInitializedErr
+	}
+	pinger, ok := querier.(Pinger)
+	if !ok {
+		return nil
+	}
+	return pinger.Ping(ctx)
+}
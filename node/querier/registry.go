@@ -0,0 +1,137 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/MonteCarloClub/log"
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	QuerierRegisteredErr = fmt.Errorf("querier registered")
+	UnknownQuerierErr    = fmt.Errorf("unknown querier")
+)
+
+// Factory creates queriers of a data source, queriers outside this package are registered in init functions of
+// their packages, like built-in ones
+type Factory struct {
+	// NewConfig returns a pointer to a zero config, which the config section of a querier is decoded into by yaml tags
+	NewConfig func() interface{}
+	// Create creates a querier and inits it by the decoded config
+	Create func(config interface{}) (Querier, error)
+}
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = make(map[string]Factory)
+)
+
+func Register(name string, factory Factory) error {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if _, ok := factories[name]; ok {
+		log.Error("fail to register querier", "name", name, "err", QuerierRegisteredErr)
+		return QuerierRegisteredErr
+	}
+	if factory.NewConfig == nil || factory.Create == nil {
+		log.Error("fail to register querier", "name", name, "err", IllegalArgsErr)
+		return IllegalArgsErr
+	}
+	factories[name] = factory
+	return nil
+}
+
+// List returns names of registered queriers, sorted
+func List() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Create creates a registered querier, rawConfig is either the typed config of the querier, or its config section
+// like map[string]interface{}
+func Create(name string, rawConfig interface{}) (Querier, error) {
+	factoriesMutex.RLock()
+	factory, ok := factories[name]
+	factoriesMutex.RUnlock()
+	if !ok {
+		log.Error("fail to create querier", "name", name, "err", UnknownQuerierErr)
+		return nil, UnknownQuerierErr
+	}
+
+	config := factory.NewConfig()
+	err := decodeConfig(rawConfig, config)
+	if err != nil {
+		log.Error("fail to decode config of querier", "name", name, "err", err)
+		return nil, err
+	}
+	return factory.Create(config)
+}
+
+func decodeConfig(rawConfig interface{}, config interface{}) error {
+	if rawConfig == nil {
+		return nil
+	}
+	configValue := reflect.ValueOf(config).Elem()
+	rawConfigValue := reflect.ValueOf(rawConfig)
+	if rawConfigValue.Type() == configValue.Type() {
+		configValue.Set(rawConfigValue)
+		return nil
+	}
+	if rawConfigValue.Type() == reflect.PtrTo(configValue.Type()) && !rawConfigValue.IsNil() {
+		configValue.Set(rawConfigValue.Elem())
+		return nil
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
+		Result:     config,
+		TagName:    "yaml",
+		Squash:     true,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(rawConfig)
+}
+
+// initQuerier inits the querier by its config, and returns it only if succeeded
+func initQuerier(querier Querier, config interface{}) (Querier, error) {
+	err := querier.Init(config)
+	if err != nil {
+		return nil, err
+	}
+	return querier, nil
+}
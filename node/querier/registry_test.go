@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticConfig struct {
+	Value string        `yaml:"value"`
+	Delay time.Duration `yaml:"delay"`
+}
+
+func TestRegistry(t *testing.T) {
+	assert.Subset(t, List(), []string{"composite", "http", "redis", "sql"})
+
+	factory := Factory{
+		NewConfig: func() interface{} {
+			return &staticConfig{}
+		},
+		Create: func(config interface{}) (Querier, error) {
+			typedConfig := config.(*staticConfig)
+			return &staticQuerier{
+				result: Result{Type: DecimalResult, Value: typedConfig.Value},
+				delay:  typedConfig.Delay,
+			}, nil
+		},
+	}
+	require.Nil(t, Register("static", factory))
+	assert.Equal(t, QuerierRegisteredErr, Register("static", factory))
+	assert.Equal(t, IllegalArgsErr, Register("incomplete", Factory{}))
+	assert.Contains(t, List(), "static")
+
+	// config sections decoded by yaml tags
+	staticQuerierOfConfig, err := Create("static", map[string]interface{}{"value": "1.5", "delay": "1ms"})
+	require.Nil(t, err)
+	assert.Equal(t, time.Millisecond, staticQuerierOfConfig.(*staticQuerier).delay)
+
+	// typed configs
+	staticQuerierOfConfig, err = Create("static", staticConfig{Value: "2"})
+	require.Nil(t, err)
+	assert.Equal(t, "2", staticQuerierOfConfig.(*staticQuerier).result.Value)
+
+	_, err = Create("unknown", nil)
+	assert.Equal(t, UnknownQuerierErr, err)
+
+	// registered queriers as sources of a composite querier
+	compositeQuerier, err := Create("composite", map[string]interface{}{
+		"aggregation": WeightedMeanAggregation,
+		"sources": []interface{}{
+			map[string]interface{}{"querier_source": "static", "querier_config": map[string]interface{}{"value": "1"}},
+			map[string]interface{}{"querier_source": "static", "querier_config": map[string]interface{}{"value": "4"},
+				"weight": 2},
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "0.static", compositeQuerier.(*CompositeQuerier).Sources[0].Name)
+	result, err := compositeQuerier.Do(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Type: IntegerResult, Value: "3"}, result)
+
+	_, err = Create("composite", map[string]interface{}{
+		"sources": []interface{}{map[string]interface{}{"querier_source": "unknown"}},
+	})
+	assert.Equal(t, UnknownQuerierErr, err)
+}
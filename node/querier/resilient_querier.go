@@ -0,0 +1,350 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+)
+
+const (
+	DefaultInitialBackoff     = 100 * time.Millisecond
+	DefaultMaxBackoff         = 2 * time.Second
+	DefaultCircuitOpenTimeout = 30 * time.Second
+)
+
+var CircuitOpenErr = fmt.Errorf("circuit open")
+
+// permanentErrs are not retried, and count as answers of sources by circuit breakers
+var permanentErrs = []error{NilResultErr, IllegalExpressionErr, IllegalArgsErr, IllegalQueryErr, NotInitializedErr,
+	UrlNotAllowedErr, MethodNotAllowedErr, AddressNotAllowedErr}
+
+type ResilienceConfig struct {
+	// MaxRetries is count of retries after the first attempt of a source, 0 for no retry
+	MaxRetries int `yaml:"max_retries"`
+	// InitialBackoff doubles after each retry up to MaxBackoff, DefaultInitialBackoff and DefaultMaxBackoff if not
+	// positive
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	// Jitter shortens each backoff by a random fraction up to it, in [0, 1]
+	Jitter float64 `yaml:"jitter"`
+	// FailureThreshold is count of consecutive failed queries opening the circuit of a source, 0 for no breaker
+	FailureThreshold int `yaml:"failure_threshold"`
+	// OpenTimeout is how long a circuit stays open before a trial query, DefaultCircuitOpenTimeout if not positive
+	OpenTimeout time.Duration `yaml:"open_timeout"`
+	// QuerierSource and QuerierConfig create the primary source by the registry
+	QuerierSource string      `yaml:"querier_source"`
+	QuerierConfig interface{} `yaml:"querier_config"`
+	// Fallback is queried if the primary source fails or its circuit is open, optional
+	Fallback ResilienceFallbackConfig `yaml:"fallback"`
+}
+
+type ResilienceFallbackConfig struct {
+	QuerierSource string      `yaml:"querier_source"`
+	QuerierConfig interface{} `yaml:"querier_config"`
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker opens after consecutive failures, and lets one trial query through after the open timeout
+type circuitBreaker struct {
+	mutex            sync.Mutex
+	failureThreshold int
+	openTimeout      time.Duration
+	state            circuitState
+	failures         int
+	openedAt         time.Time
+}
+
+func (breaker *circuitBreaker) allow(now time.Time) bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	switch breaker.state {
+	case circuitOpen:
+		if now.Sub(breaker.openedAt) < breaker.openTimeout {
+			return false
+		}
+		breaker.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+func (breaker *circuitBreaker) record(err error, now time.Time) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	if err == nil {
+		breaker.state = circuitClosed
+		breaker.failures = 0
+		return
+	}
+	breaker.failures++
+	if breaker.state == circuitHalfOpen || breaker.failures >= breaker.failureThreshold {
+		breaker.state = circuitOpen
+		breaker.openedAt = now
+	}
+}
+
+// ResilientQuerier retries a source with exponential backoff, breaks its circuit after consecutive failures, and
+// queries the fallback source if the primary one fails
+type ResilientQuerier struct {
+	Querier        Querier
+	Fallback       Querier
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Jitter         float64
+
+	breakers []*circuitBreaker
+	now      func() time.Time
+	sleep    func(ctx context.Context, duration time.Duration) error
+	random   func() float64
+}
+
+func init() {
+	_ = Register("resilient", Factory{
+		NewConfig: func() interface{} {
+			return &ResilienceConfig{}
+		},
+		Create: func(config interface{}) (Querier, error) {
+			resilienceConfig, ok := config.(*ResilienceConfig)
+			if !ok || resilienceConfig == nil {
+				return nil, IllegalArgsErr
+			}
+			return createResilientQuerier(*resilienceConfig)
+		},
+	})
+}
+
+func createResilientQuerier(resilienceConfig ResilienceConfig) (Querier, error) {
+	primary, err := Create(resilienceConfig.QuerierSource, resilienceConfig.QuerierConfig)
+	if err != nil {
+		return nil, err
+	}
+	var fallback Querier
+	if resilienceConfig.Fallback.QuerierSource != "" {
+		fallback, err = Create(resilienceConfig.Fallback.QuerierSource, resilienceConfig.Fallback.QuerierConfig)
+		if err != nil {
+			primary.Close()
+			return nil, err
+		}
+	}
+
+	resilientQuerier := &ResilientQuerier{}
+	err = resilientQuerier.Init(resilienceConfig, primary, fallback)
+	if err != nil {
+		primary.Close()
+		if fallback != nil {
+			fallback.Close()
+		}
+		return nil, err
+	}
+	return resilientQuerier, nil
+}
+
+// Init accepts a ResilienceConfig, the primary Querier and an optional fallback Querier
+func (resilientQuerier *ResilientQuerier) Init(args ...interface{}) error {
+	if resilientQuerier == nil {
+		log.Error("nil resilient querier", "err", utils.NilPtrDerefErr)
+		return utils.NilPtrDerefErr
+	}
+
+	if len(args) < 2 {
+		log.Error("fail to init resilient querier", "argc", len(args))
+		return IllegalArgsErr
+	}
+	resilienceConfig, ok := args[0].(ResilienceConfig)
+	if !ok || resilienceConfig.MaxRetries < 0 || resilienceConfig.Jitter < 0 || resilienceConfig.Jitter > 1 {
+		log.Error("wrong arg type or illegal config to init resilient querier", "arg", args[0])
+		return IllegalArgsErr
+	}
+	primary, ok := args[1].(Querier)
+	if !ok || primary == nil {
+		log.Error("wrong arg type of primary querier to init resilient querier", "arg", args[1])
+		return IllegalArgsErr
+	}
+	var fallback Querier
+	if len(args) > 2 && args[2] != nil {
+		fallback, ok = args[2].(Querier)
+		if !ok {
+			log.Error("wrong arg type of fallback querier to init resilient querier", "arg", args[2])
+			return IllegalArgsErr
+		}
+	}
+
+	resilientQuerier.Querier = primary
+	resilientQuerier.Fallback = fallback
+	resilientQuerier.MaxRetries = resilienceConfig.MaxRetries
+	resilientQuerier.InitialBackoff = resilienceConfig.InitialBackoff
+	if resilientQuerier.InitialBackoff <= 0 {
+		resilientQuerier.InitialBackoff = DefaultInitialBackoff
+	}
+	resilientQuerier.MaxBackoff = resilienceConfig.MaxBackoff
+	if resilientQuerier.MaxBackoff <= 0 {
+		resilientQuerier.MaxBackoff = DefaultMaxBackoff
+	}
+	resilientQuerier.Jitter = resilienceConfig.Jitter
+
+	resilientQuerier.breakers = make([]*circuitBreaker, 2)
+	if resilienceConfig.FailureThreshold > 0 {
+		openTimeout := resilienceConfig.OpenTimeout
+		if openTimeout <= 0 {
+			openTimeout = DefaultCircuitOpenTimeout
+		}
+		for i := range resilientQuerier.breakers {
+			resilientQuerier.breakers[i] = &circuitBreaker{
+				failureThreshold: resilienceConfig.FailureThreshold,
+				openTimeout:      openTimeout,
+			}
+		}
+	}
+	if resilientQuerier.now == nil {
+		resilientQuerier.now = time.Now
+	}
+	if resilientQuerier.sleep == nil {
+		resilientQuerier.sleep = sleepContext
+	}
+	if resilientQuerier.random == nil {
+		resilientQuerier.random = rand.Float64
+	}
+	return nil
+}
+
+func (resilientQuerier *ResilientQuerier) Do(ctx context.Context, expression string) (Result, error) {
+	if resilientQuerier == nil || resilientQuerier.Querier == nil {
+		log.Error("resilient querier not initialized", "err", NotInitializedErr)
+		return Result{}, NotInitializedErr
+	}
+
+	result, err := resilientQuerier.doSource(ctx, 0, resilientQuerier.Querier, expression)
+	if err == nil || resilientQuerier.Fallback == nil || isPermanentErr(err) || ctx.Err() != nil {
+		return result, err
+	}
+	log.Warn("query fallback source", "expression", expression, "err", err)
+	return resilientQuerier.doSource(ctx, 1, resilientQuerier.Fallback, expression)
+}
+
+// Ping succeeds if the primary source or the fallback source is connected
+func (resilientQuerier *ResilientQuerier) Ping(ctx context.Context) error {
+	if resilientQuerier == nil || resilientQuerier.Querier == nil {
+		return NotInitializedErr
+	}
+	err := Ping(ctx, resilientQuerier.Querier)
+	if err == nil || resilientQuerier.Fallback == nil {
+		return err
+	}
+	return Ping(ctx, resilientQuerier.Fallback)
+}
+
+func (resilientQuerier *ResilientQuerier) Close() {
+	if resilientQuerier == nil || resilientQuerier.Querier == nil {
+		return
+	}
+	resilientQuerier.Querier.Close()
+	resilientQuerier.Querier = nil
+	if resilientQuerier.Fallback != nil {
+		resilientQuerier.Fallback.Close()
+		resilientQuerier.Fallback = nil
+	}
+}
+
+// doSource queries a source behind its circuit breaker, with retries
+func (resilientQuerier *ResilientQuerier) doSource(ctx context.Context, sourceIndex int, source Querier,
+	expression string) (Result, error) {
+	breaker := resilientQuerier.breakers[sourceIndex]
+	if breaker != nil && !breaker.allow(resilientQuerier.now()) {
+		return Result{}, CircuitOpenErr
+	}
+
+	var result Result
+	var err error
+	for attempt := 0; ; attempt++ {
+		result, err = source.Do(ctx, expression)
+		if err == nil || isPermanentErr(err) || ctx.Err() != nil || attempt >= resilientQuerier.MaxRetries {
+			break
+		}
+		log.Warn("retry querier", "expression", expression, "attempt", attempt+1, "err", err)
+		if resilientQuerier.sleep(ctx, resilientQuerier.backoff(attempt)) != nil {
+			break
+		}
+	}
+	if breaker != nil {
+		// the source answered if the error is permanent
+		if isPermanentErr(err) {
+			breaker.record(nil, resilientQuerier.now())
+		} else {
+			breaker.record(err, resilientQuerier.now())
+		}
+	}
+	return result, err
+}
+
+// backoff returns the delay before the retry after the attempt, from 0
+func (resilientQuerier *ResilientQuerier) backoff(attempt int) time.Duration {
+	backoff := resilientQuerier.InitialBackoff
+	for i := 0; i < attempt && backoff < resilientQuerier.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > resilientQuerier.MaxBackoff {
+		backoff = resilientQuerier.MaxBackoff
+	}
+	return backoff - time.Duration(float64(backoff)*resilientQuerier.Jitter*resilientQuerier.random())
+}
+
+func isPermanentErr(err error) bool {
+	for _, permanentErr := range permanentErrs {
+		if errors.Is(err, permanentErr) {
+			return true
+		}
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var unavailableErr = fmt.Errorf("unavailable")
+
+// flakyQuerier fails its first failures calls, and returns its name afterwards
+type flakyQuerier struct {
+	name     string
+	failures int
+	calls    int
+}
+
+func (querier *flakyQuerier) Init(args ...interface{}) error {
+	return nil
+}
+
+func (querier *flakyQuerier) Do(ctx context.Context, expression string) (Result, error) {
+	querier.calls++
+	if querier.calls <= querier.failures {
+		return Result{}, unavailableErr
+	}
+	if expression == "missing" {
+		return Result{}, NilResultErr
+	}
+	return Result{Type: StringResult, Value: querier.name}, nil
+}
+
+func (querier *flakyQuerier) Close() {}
+
+func newResilientQuerier(t *testing.T, resilienceConfig ResilienceConfig, primary Querier,
+	fallback Querier) (*ResilientQuerier, *time.Time, *[]time.Duration) {
+	now := time.Unix(0, 0)
+	backoffs := make([]time.Duration, 0)
+	resilientQuerier := &ResilientQuerier{
+		now: func() time.Time { return now },
+		sleep: func(ctx context.Context, duration time.Duration) error {
+			backoffs = append(backoffs, duration)
+			return nil
+		},
+		random: func() float64 { return 0.5 },
+	}
+	require.Nil(t, resilientQuerier.Init(resilienceConfig, primary, fallback))
+	return resilientQuerier, &now, &backoffs
+}
+
+func TestResilientQuerierRetry(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyQuerier{name: "primary", failures: 3}
+	resilientQuerier, _, backoffs := newResilientQuerier(t, ResilienceConfig{
+		MaxRetries:     3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+		Jitter:         0.2,
+	}, primary, nil)
+
+	result, err := resilientQuerier.Do(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", result.Value)
+	assert.Equal(t, 4, primary.calls)
+	// exponential, capped and shortened by jitter
+	assert.Equal(t, []time.Duration{90 * time.Millisecond, 180 * time.Millisecond, 270 * time.Millisecond}, *backoffs)
+
+	primary.calls, primary.failures = 0, 5
+	_, err = resilientQuerier.Do(ctx, "")
+	assert.Equal(t, unavailableErr, err)
+	assert.Equal(t, 4, primary.calls)
+
+	// permanent errors are not retried
+	primary.calls, primary.failures = 0, 0
+	_, err = resilientQuerier.Do(ctx, "missing")
+	assert.Equal(t, NilResultErr, err)
+	assert.Equal(t, 1, primary.calls)
+}
+
+func TestResilientQuerierCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyQuerier{name: "primary", failures: 3}
+	fallback := &flakyQuerier{name: "fallback"}
+	resilientQuerier, now, _ := newResilientQuerier(t, ResilienceConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+	}, primary, fallback)
+
+	for i := 0; i < 3; i++ {
+		result, err := resilientQuerier.Do(ctx, "")
+		assert.Nil(t, err)
+		assert.Equal(t, "fallback", result.Value)
+	}
+	// the circuit opened after 2 failures
+	assert.Equal(t, 2, primary.calls)
+
+	// a failed trial opens the circuit again
+	*now = now.Add(time.Minute)
+	result, _ := resilientQuerier.Do(ctx, "")
+	assert.Equal(t, "fallback", result.Value)
+	assert.Equal(t, 3, primary.calls)
+	result, _ = resilientQuerier.Do(ctx, "")
+	assert.Equal(t, "fallback", result.Value)
+	assert.Equal(t, 3, primary.calls)
+
+	// a successful trial closes the circuit
+	*now = now.Add(time.Minute)
+	result, _ = resilientQuerier.Do(ctx, "")
+	assert.Equal(t, "primary", result.Value)
+	result, _ = resilientQuerier.Do(ctx, "")
+	assert.Equal(t, "primary", result.Value)
+	assert.Equal(t, 5, primary.calls)
+
+	// permanent errors of the primary source are not hidden by the fallback one
+	_, err := resilientQuerier.Do(ctx, "missing")
+	assert.Equal(t, NilResultErr, err)
+
+	// both circuits open
+	fallback.calls, fallback.failures = 0, 100
+	primary.calls, primary.failures = 0, 100
+	for i := 0; i < 3; i++ {
+		_, err = resilientQuerier.Do(ctx, "")
+	}
+	assert.Equal(t, CircuitOpenErr, err)
+	assert.Equal(t, 2, primary.calls)
+	assert.Equal(t, 2, fallback.calls)
+}
+
+func TestResilientQuerierRegistry(t *testing.T) {
+	resilientQuerier, err := Create("resilient", map[string]interface{}{
+		"max_retries":     2,
+		"initial_backoff": "10ms",
+		"querier_source":  "composite",
+		"querier_config": map[string]interface{}{
+			"sources": []interface{}{map[string]interface{}{
+				"querier_source": "cache",
+				"querier_config": map[string]interface{}{
+					"querier_source": "sql",
+					"querier_config": map[string]interface{}{"driver": "sqlite3", "dsn": ":memory:"},
+				},
+			}},
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 10*time.Millisecond, resilientQuerier.(*ResilientQuerier).InitialBackoff)
+	// connectivity is checked through every wrapper down to the sql source
+	assert.Nil(t, Ping(context.Background(), resilientQuerier))
+	resilientQuerier.Close()
+	assert.Equal(t, NotInitializedErr, Ping(context.Background(), resilientQuerier))
+	assert.Nil(t, Ping(context.Background(), &HttpQuerier{}))
+
+	_, err = Create("resilient", map[string]interface{}{"querier_source": "redis", "jitter": 2})
+	assert.Equal(t, IllegalArgsErr, err)
+}
@@ -0,0 +1,283 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const DefaultSqlStatementTimeout = 5 * time.Second
+
+var (
+	IllegalQueryErr = fmt.Errorf("illegal query, only select queries are allowed")
+	TooManyRowsErr  = fmt.Errorf("too many rows, at most one row is allowed")
+)
+
+type SqlConfig struct {
+	// Driver is postgres or sqlite3
+	Driver string `yaml:"driver"`
+	Dsn    string `yaml:"dsn"`
+	// Queries maps names to parameterised select queries, which are the only queries an expression can run
+	Queries map[string]string `yaml:"queries"`
+	// StatementTimeout is DefaultSqlStatementTimeout if not positive
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+	MaxOpenConns     int           `yaml:"max_open_conns"`
+}
+
+// SqlExpression is the json form of an expression to SqlQuerier, e.g. {"query": "price", "args": ["ETH"]},
+// a bare query name like price is also accepted for queries without args
+type SqlExpression struct {
+	Query string        `json:"query"`
+	Args  []interface{} `json:"args"`
+}
+
+func init() {
+	_ = Register("sql", Factory{
+		NewConfig: func() interface{} {
+			return &SqlConfig{}
+		},
+		Create: func(config interface{}) (Querier, error) {
+			return initQuerier(&SqlQuerier{}, config)
+		},
+	})
+}
+
+// SqlQuerier runs named queries in read-only transactions, a result is a scalar if the query selects one column,
+// or a json object of the row with keys sorted
+type SqlQuerier struct {
+	Db               *sql.DB
+	Driver           string
+	Queries          map[string]string
+	StatementTimeout time.Duration
+}
+
+func (sqlQuerier *SqlQuerier) Init(args ...interface{}) error {
+	if sqlQuerier == nil {
+		log.Error("nil sql querier", "err", utils.NilPtrDerefErr)
+		return utils.NilPtrDerefErr
+	}
+
+	if len(args) < 1 {
+		log.Error("fail to init sql querier", "argc", len(args))
+		return IllegalArgsErr
+	}
+	if len(args) > 1 {
+		log.Warn("too many arguments to init sql querier", "argc", len(args))
+	}
+	var sqlConfig SqlConfig
+	switch config := args[0].(type) {
+	case SqlConfig:
+		sqlConfig = config
+	case *SqlConfig:
+		if config == nil {
+			log.Error("nil sql config", "err", utils.NilPtrDerefErr)
+			return IllegalArgsErr
+		}
+		sqlConfig = *config
+	default:
+		log.Error("wrong arg type to init sql querier", "arg", args[0])
+		return IllegalArgsErr
+	}
+	for name, query := range sqlConfig.Queries {
+		if !isSelectQuery(query) {
+			log.Error("fail to init sql querier", "query name", name, "query", query, "err", IllegalQueryErr)
+			return IllegalQueryErr
+		}
+	}
+
+	db, err := sql.Open(sqlConfig.Driver, sqlConfig.Dsn)
+	if err != nil {
+		log.Error("fail to open sql database", "driver", sqlConfig.Driver, "err", err)
+		return err
+	}
+	if sqlConfig.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(sqlConfig.MaxOpenConns)
+	}
+	sqlQuerier.Db = db
+	sqlQuerier.Driver = sqlConfig.Driver
+	sqlQuerier.Queries = sqlConfig.Queries
+	sqlQuerier.StatementTimeout = sqlConfig.StatementTimeout
+	if sqlQuerier.StatementTimeout <= 0 {
+		sqlQuerier.StatementTimeout = DefaultSqlStatementTimeout
+	}
+	return nil
+}
+
+func isSelectQuery(query string) bool {
+	fields := strings.Fields(strings.ToLower(query))
+	return len(fields) > 0 && (fields[0] == "select" || fields[0] == "with")
+}
+
+func (sqlQuerier *SqlQuerier) Do(ctx context.Context, expression string) (Result, error) {
+	if sqlQuerier == nil || sqlQuerier.Db == nil {
+		log.Error("nil sql querier or db", "err", NotInitializedErr)
+		return Result{}, NotInitializedErr
+	}
+
+	var sqlExpression SqlExpression
+	if strings.HasPrefix(strings.TrimSpace(expression), "{") {
+		err := json.Unmarshal([]byte(expression), &sqlExpression)
+		if err != nil {
+			log.Error("fail to parse sql expression", "expression", expression, "err", err)
+			return Result{}, IllegalExpressionErr
+		}
+	} else {
+		sqlExpression.Query = strings.TrimSpace(expression)
+	}
+	query, ok := sqlQuerier.Queries[sqlExpression.Query]
+	if !ok {
+		log.Error("query not existed", "expression", expression)
+		return Result{}, IllegalExpressionErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sqlQuerier.StatementTimeout)
+	defer cancel()
+	tx, err := sqlQuerier.Db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		log.Warn("fail to begin read-only transaction", "err", err)
+		return Result{}, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	err = sqlQuerier.restrictTx(ctx, tx)
+	if err != nil {
+		log.Warn("fail to restrict transaction", "driver", sqlQuerier.Driver, "err", err)
+		return Result{}, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, sqlExpression.Args...)
+	if err != nil {
+		log.Warn("fail to run query", "query name", sqlExpression.Query, "err", err)
+		return Result{}, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	return resultOfRows(rows)
+}
+
+// restrictTx enforces read-only and statement timeout, for drivers ignoring them in sql.TxOptions or ctx
+func (sqlQuerier *SqlQuerier) restrictTx(ctx context.Context, tx *sql.Tx) error {
+	switch sqlQuerier.Driver {
+	case "postgres":
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %v",
+			sqlQuerier.StatementTimeout.Milliseconds()))
+		return err
+	case "sqlite3":
+		_, err := tx.ExecContext(ctx, "PRAGMA query_only = ON")
+		return err
+	default:
+		return nil
+	}
+}
+
+func resultOfRows(rows *sql.Rows) (Result, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return Result{}, err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return Result{}, err
+		}
+		return Result{}, NilResultErr
+	}
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	err = rows.Scan(valuePtrs...)
+	if err != nil {
+		return Result{}, err
+	}
+	if rows.Next() {
+		return Result{}, TooManyRowsErr
+	}
+
+	if len(columns) == 1 {
+		if values[0] == nil {
+			return Result{}, NilResultErr
+		}
+		return resultOfSqlValue(values[0]), nil
+	}
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		if bytesValue, ok := values[i].([]byte); ok {
+			values[i] = string(bytesValue)
+		}
+		row[column] = values[i]
+	}
+	rowJson, err := json.Marshal(row)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Type: JsonResult, Value: string(rowJson)}, nil
+}
+
+func resultOfSqlValue(value interface{}) Result {
+	switch typedValue := value.(type) {
+	case int64:
+		return Result{Type: IntegerResult, Value: strconv.FormatInt(typedValue, 10)}
+	case float64:
+		return Result{Type: DecimalResult, Value: strconv.FormatFloat(typedValue, 'f', -1, 64)}
+	case bool:
+		return Result{Type: BooleanResult, Value: strconv.FormatBool(typedValue)}
+	case []byte:
+		return Result{Type: StringResult, Value: string(typedValue)}
+	case time.Time:
+		return Result{Type: StringResult, Value: typedValue.UTC().Format(time.RFC3339Nano)}
+	default:
+		return Result{Type: StringResult, Value: fmt.Sprint(typedValue)}
+	}
+}
+
+func (sqlQuerier *SqlQuerier) Ping(ctx context.Context) error {
+	if sqlQuerier == nil || sqlQuerier.Db == nil {
+		return NotInitializedErr
+	}
+	return sqlQuerier.Db.PingContext(ctx)
+}
+
+func (sqlQuerier *SqlQuerier) Close() {
+	if sqlQuerier == nil {
+		log.Error("nil sql querier", "err", utils.NilPtrDerefErr)
+		return
+	}
+	if sqlQuerier.Db != nil {
+		_ = sqlQuerier.Db.Close()
+	}
+	sqlQuerier.Db = nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqlQuerier(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "siwa.db")
+	db, err := sql.Open("sqlite3", dsn)
+	require.Nil(t, err)
+	_, err = db.Exec(`CREATE TABLE prices (symbol TEXT PRIMARY KEY, price REAL, volume INTEGER, note TEXT);
+		INSERT INTO prices VALUES ('ETH', 1234.5, 42, NULL), ('BTC', 20000, 7, 'digital gold');`)
+	require.Nil(t, err)
+	require.Nil(t, db.Close())
+
+	sqlQuerier := &SqlQuerier{}
+	err = sqlQuerier.Init(SqlConfig{
+		Driver: "sqlite3",
+		Dsn:    dsn,
+		Queries: map[string]string{
+			"price":   "SELECT price FROM prices WHERE symbol = ?",
+			"volume":  "SELECT volume FROM prices WHERE symbol = ?",
+			"note":    "SELECT note FROM prices WHERE symbol = ?",
+			"row":     "SELECT symbol, price, note FROM prices WHERE symbol = ?",
+			"count":   "SELECT count(*) FROM prices",
+			"symbols": "SELECT symbol FROM prices",
+			"sneaky":  "WITH t AS (SELECT 1) INSERT INTO prices VALUES ('DOGE', 0.1, 1, NULL)",
+		},
+	})
+	require.Nil(t, err)
+	defer sqlQuerier.Close()
+	ctx := context.Background()
+	assert.Nil(t, sqlQuerier.Ping(ctx))
+
+	expectedResults := map[string]Result{
+		`{"query": "price", "args": ["ETH"]}`:  {Type: DecimalResult, Value: "1234.5"},
+		`{"query": "volume", "args": ["BTC"]}`: {Type: IntegerResult, Value: "7"},
+		`{"query": "row", "args": ["BTC"]}`: {Type: JsonResult,
+			Value: `{"note":"digital gold","price":20000,"symbol":"BTC"}`},
+		"count": {Type: IntegerResult, Value: "2"},
+	}
+	for expression, expectedResult := range expectedResults {
+		result, err := sqlQuerier.Do(ctx, expression)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedResult, result)
+	}
+
+	_, err = sqlQuerier.Do(ctx, `{"query": "price", "args": ["DOGE"]}`)
+	assert.Equal(t, NilResultErr, err)
+	_, err = sqlQuerier.Do(ctx, `{"query": "note", "args": ["ETH"]}`)
+	assert.Equal(t, NilResultErr, err)
+	_, err = sqlQuerier.Do(ctx, "symbols")
+	assert.Equal(t, TooManyRowsErr, err)
+	_, err = sqlQuerier.Do(ctx, "SELECT 1")
+	assert.Equal(t, IllegalExpressionErr, err)
+	_, err = sqlQuerier.Do(ctx, "sneaky")
+	assert.NotNil(t, err)
+	result, err := sqlQuerier.Do(ctx, "count")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", result.Value)
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = sqlQuerier.Do(cancelledCtx, "count")
+	assert.NotNil(t, err)
+
+	err = (&SqlQuerier{}).Init(SqlConfig{
+		Driver:  "sqlite3",
+		Dsn:     dsn,
+		Queries: map[string]string{"drop": "DROP TABLE prices"},
+	})
+	assert.Equal(t, IllegalQueryErr, err)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package querier
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/MonteCarloClub/log"
+)
+
+var IllegalTlsConfigErr = fmt.Errorf("illegal tls config")
+
+// TlsConfig is shared by queriers connecting to data sources over tls, system roots are used if CaFile is empty
+type TlsConfig struct {
+	CaFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+func (tlsConfig *TlsConfig) build() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         tlsConfig.ServerName,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if tlsConfig.CaFile != "" {
+		caPem, err := os.ReadFile(tlsConfig.CaFile)
+		if err != nil {
+			log.Error("fail to read ca file", "ca file", tlsConfig.CaFile, "err", err)
+			return nil, err
+		}
+		rootCas := x509.NewCertPool()
+		if !rootCas.AppendCertsFromPEM(caPem) {
+			log.Error("no certificate in ca file", "ca file", tlsConfig.CaFile)
+			return nil, IllegalTlsConfigErr
+		}
+		config.RootCAs = rootCas
+	}
+
+	if (tlsConfig.CertFile == "") != (tlsConfig.KeyFile == "") {
+		log.Error("cert file and key file should be specified together", "cert file", tlsConfig.CertFile,
+			"key file", tlsConfig.KeyFile)
+		return nil, IllegalTlsConfigErr
+	}
+	if tlsConfig.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(tlsConfig.CertFile, tlsConfig.KeyFile)
+		if err != nil {
+			log.Error("fail to load client certificate", "cert file", tlsConfig.CertFile,
+				"key file", tlsConfig.KeyFile, "err", err)
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
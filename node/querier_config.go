@@ -0,0 +1,56 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package node
+
+import (
+	"github.com/KofClubs/siwa/node/querier"
+	"github.com/MonteCarloClub/log"
+	"github.com/MonteCarloClub/utils"
+)
+
+// UnmarshalledQuerier configures the querier of a node, the querier_config section is decoded into the typed config
+// registered with querier_source
+type UnmarshalledQuerier struct {
+	QuerierSource string      `yaml:"querier_source"`
+	QuerierConfig interface{} `yaml:"querier_config"`
+	// RedisAddress is short for a Redis config with a single address, if querier_config is absent
+	RedisAddress string `yaml:"redis_address"`
+}
+
+func (unmarshalledQuerier *UnmarshalledQuerier) CreateQuerier() (querier.Querier, error) {
+	if unmarshalledQuerier == nil {
+		log.Error("nil unmarshalled querier", "err", utils.NilPtrDerefErr)
+		return nil, utils.NilPtrDerefErr
+	}
+
+	querierConfig := unmarshalledQuerier.QuerierConfig
+	if querierConfig == nil && unmarshalledQuerier.QuerierSource == "redis" {
+		querierConfig = querier.RedisConfig{Addrs: []string{unmarshalledQuerier.RedisAddress}}
+	}
+	querierOfNode, err := querier.Create(unmarshalledQuerier.QuerierSource, querierConfig)
+	if err != nil {
+		log.Error("fail to init querier", "querier source", unmarshalledQuerier.QuerierSource, "err", err)
+		return nil, err
+	}
+	return querierOfNode, nil
+}
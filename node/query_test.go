@@ -24,16 +24,26 @@ package node
 
 import (
 	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/KofClubs/siwa/crypto"
+	"github.com/KofClubs/siwa/node/audit"
 	"github.com/KofClubs/siwa/node/querier"
 	"github.com/MonteCarloClub/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
 	pedersendkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
 	"go.dedis.ch/kyber/v3/util/key"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 const (
@@ -47,34 +57,50 @@ var (
 )
 
 func genRandomPrivateKey() string {
-	return key.NewKeyPair(crypto.GetBlsSuite()).Private.String()
+	// Scalar.String() trims leading zeros, which GetBlsPrivateKey can not unmarshal
+	privateKeyBytes, _ := key.NewKeyPair(crypto.GetBlsSuite()).Private.MarshalBinary()
+	return hex.EncodeToString(privateKeyBytes)
 }
 
-func fullCommunicate() {
+// createRedisNode creates a node querying the test redis, in a scheduled group if groupId is empty
+func createRedisNode(t *testing.T, groupId string) *Node {
+	node := (&UnmarshalledNode{
+		GroupId:    groupId,
+		PrivateKey: genRandomPrivateKey(),
+		UnmarshalledQuerier: UnmarshalledQuerier{
+			QuerierSource: "redis",
+			RedisAddress:  RedisAddress,
+		},
+	}).CreateNode()
+	require.NotNil(t, node)
+	return node
+}
+
+func fullCommunicate(groupId string, nodes []*Node) {
 	// tested by TestPedersenDkg
 	for _, node := range nodes {
-		_ = node.Dkg.CreatePedersenDkgDeals()
+		_ = node.GetDkg(groupId).CreatePedersenDkgDeals()
 	}
 
 	pedersenDkgResponses := make([]*pedersendkg.Response, 0)
 
 	for _, node := range nodes {
-		for j, pedersenDkgDeal := range node.Dkg.PedersendkgDeals {
-			pedersenDkgResponse, _ := getNodeByDkgIndex(j).Dkg.VerifyPedersenDkgDeal(pedersenDkgDeal)
+		for j, pedersenDkgDeal := range node.GetDkg(groupId).PedersendkgDeals {
+			pedersenDkgResponse, _ := getNodeByDkgIndex(groupId, j).GetDkg(groupId).VerifyPedersenDkgDeal(pedersenDkgDeal)
 			pedersenDkgResponses = append(pedersenDkgResponses, pedersenDkgResponse)
 		}
 	}
 
 	for _, pedersenDkgResponse := range pedersenDkgResponses {
 		for _, node := range nodes {
-			node.Dkg.VerifyPedersenDkgResponse(pedersenDkgResponse)
+			node.GetDkg(groupId).VerifyPedersenDkgResponse(pedersenDkgResponse)
 		}
 	}
 }
 
 func initRedis() {
 	redisQuerier := &querier.RedisQuerier{}
-	redisQuerier.Init(RedisAddress)
+	_ = redisQuerier.Init(RedisAddress)
 	_ = redisQuerier.RedisClient.Set(context.Background(), "k1", "v1", 0).Err()
 }
 
@@ -89,10 +115,12 @@ func TestQuery(t *testing.T) {
 	// 2. generate node entities and create nodes
 	for rank := 0; rank < NodeCount; rank++ {
 		unmarshalledNodes = append(unmarshalledNodes, &UnmarshalledNode{
-			GroupId:       group.Id,
-			PrivateKey:    genRandomPrivateKey(),
-			QuerierSource: "redis",
-			RedisAddress:  RedisAddress,
+			GroupId:    group.Id,
+			PrivateKey: genRandomPrivateKey(),
+			UnmarshalledQuerier: UnmarshalledQuerier{
+				QuerierSource: "redis",
+				RedisAddress:  RedisAddress,
+			},
 		})
 		log.Info("unmarshalled nodes generated",
 			"private key", unmarshalledNodes[len(unmarshalledNodes)-1].PrivateKey)
@@ -104,9 +132,9 @@ func TestQuery(t *testing.T) {
 	}
 
 	// 3. fully communicate to certify all dkgs
-	fullCommunicate()
+	fullCommunicate(group.Id, nodes)
 	for _, node := range nodes {
-		assert.True(t, node.ReadyToQuery())
+		assert.True(t, node.ReadyToQuery(group.Id))
 	}
 
 	// 4. query and aggregate result
@@ -117,13 +145,289 @@ func TestQuery(t *testing.T) {
 	expectedValue := "v1"
 	signatures := make([][]byte, 0)
 	for _, node := range nodes {
-		message, signature := node.Query(expression)
+		message, signature, err := node.Query(context.Background(), group.Id, expression)
+		assert.Nil(t, err)
 		assert.Equal(t, expectedValue, message)
-		ok := verifier.Verify(message, signature)
+		ok := verifier.Verify(group.Id, "", expression, message, signature)
 		assert.True(t, ok)
 		signatures = append(signatures, signature)
 	}
-	signature, ok := verifier.Recover(expectedValue, signatures)
+	signature, ok := verifier.Recover(group.Id, "", expression, expectedValue, signatures)
 	assert.NotNil(t, signature)
 	assert.True(t, ok)
+	// signatures are bound to the expression
+	assert.False(t, verifier.Verify(group.Id, "", "k2", expectedValue, signatures[0]))
+
+	// 5. refuse to sign if querier fails
+	message, signature, err := verifier.Query(context.Background(), group.Id, "k_not_existed")
+	assert.Equal(t, querier.NilResultErr, err)
+	assert.Equal(t, "", message)
+	assert.Nil(t, signature)
+}
+
+func TestMultiGroupQuery(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	// 1. create groups, nodes 0, 1, 2 in group g1, node 3 in group g2, then nodes 0, 1 join group g2
+	g1, err := CreateGroup("g1", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	g2, err := CreateGroup("g2", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	groupNodes := make([]*Node, 0)
+	for _, groupId := range []string{g1.Id, g1.Id, g1.Id, g2.Id} {
+		node := createRedisNode(t, groupId)
+		groupNodes = append(groupNodes, node)
+	}
+	require.Nil(t, groupNodes[0].JoinGroup(g2.Id))
+	require.Nil(t, groupNodes[1].JoinGroup(g2.Id))
+	assert.Equal(t, []string{g1.Id, g2.Id}, groupNodes[0].GroupIds())
+	assert.Equal(t, groupNodes[0], getNodeByDkgIndex(g1.Id, 0))
+	assert.Equal(t, groupNodes[3], getNodeByDkgIndex(g2.Id, 0))
+	assert.Equal(t, groupNodes[0], getNodeByDkgIndex(g2.Id, 1))
+
+	// 2. fully communicate in each group
+	membersByGroup := map[string][]*Node{
+		g1.Id: groupNodes[:3],
+		g2.Id: {groupNodes[3], groupNodes[0], groupNodes[1]},
+	}
+	for groupId, members := range membersByGroup {
+		fullCommunicate(groupId, members)
+		for _, node := range members {
+			assert.True(t, node.ReadyToQuery(groupId))
+		}
+	}
+
+	// 3. query and aggregate result in each group, signatures are not shared between groups
+	initRedis()
+	signaturesByGroup := make(map[string][][]byte)
+	for groupId, members := range membersByGroup {
+		for _, node := range members {
+			message, signature, err := node.Query(context.Background(), groupId, "k1")
+			assert.Nil(t, err)
+			assert.Equal(t, "v1", message)
+			signaturesByGroup[groupId] = append(signaturesByGroup[groupId], signature)
+		}
+		_, ok := members[0].Recover(groupId, "", "k1", "v1", signaturesByGroup[groupId])
+		assert.True(t, ok)
+	}
+	_, ok := groupNodes[0].Recover(g2.Id, "", "k1", "v1", signaturesByGroup[g1.Id])
+	assert.False(t, ok)
+
+	// 4. leave one of groups
+	require.Nil(t, groupNodes[0].LeaveGroup(g2.Id))
+	assert.Equal(t, []string{g1.Id}, groupNodes[0].GroupIds())
+	assert.Nil(t, groupNodes[0].GetDkg(g2.Id))
+	assert.NotNil(t, groupNodes[0].GetDkg(g1.Id))
+}
+
+// formattedQuerier returns a decimal in its own format, like data sources of different nodes
+type formattedQuerier struct {
+	Value   string `yaml:"value"`
+	pingErr error
+}
+
+func (formattedQuerier *formattedQuerier) Init(args ...interface{}) error {
+	return nil
+}
+
+func (formattedQuerier *formattedQuerier) Do(ctx context.Context, expression string) (querier.Result, error) {
+	return querier.Result{Type: querier.DecimalResult, Value: formattedQuerier.Value}, nil
+}
+
+func (formattedQuerier *formattedQuerier) Ping(ctx context.Context) error {
+	return formattedQuerier.pingErr
+}
+
+func (formattedQuerier *formattedQuerier) Close() {}
+
+func init() {
+	_ = querier.Register("formatted", querier.Factory{
+		NewConfig: func() interface{} {
+			return &formattedQuerier{}
+		},
+		Create: func(config interface{}) (querier.Querier, error) {
+			return config.(*formattedQuerier), nil
+		},
+	})
+}
+
+func createFormattedNodes(t *testing.T, groupId string, normalizationConfig querier.NormalizationConfig,
+	values ...string) []*Node {
+	groupNodes := make([]*Node, 0)
+	for _, value := range values {
+		node := (&UnmarshalledNode{
+			GroupId:    groupId,
+			PrivateKey: genRandomPrivateKey(),
+			UnmarshalledQuerier: UnmarshalledQuerier{
+				QuerierSource: "formatted",
+				QuerierConfig: map[string]interface{}{"value": value},
+			},
+			Normalization: normalizationConfig,
+		}).CreateNode()
+		require.NotNil(t, node)
+		groupNodes = append(groupNodes, node)
+	}
+	return groupNodes
+}
+
+func TestNormalizedQuery(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	group, err := CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	groupNodes := createFormattedNodes(t, group.Id, querier.NormalizationConfig{
+		Type:     "decimal",
+		Scale:    2,
+		Encoding: querier.Uint256Encoding,
+	}, "1.50", "1.5", "1.5e0", "15e-1")
+	fullCommunicate(group.Id, groupNodes)
+
+	// byte-identical messages from differently formatted values
+	expectedMessage := string(append(make([]byte, 31), 150))
+	signatures := make([][]byte, 0)
+	for _, node := range groupNodes {
+		message, signature, err := node.Query(context.Background(), group.Id, "")
+		require.Nil(t, err)
+		assert.Equal(t, expectedMessage, message)
+		signatures = append(signatures, signature)
+	}
+	_, ok := groupNodes[0].Recover(group.Id, "", "", expectedMessage, signatures)
+	assert.True(t, ok)
+
+	// illegal normalization
+	node := (&UnmarshalledNode{
+		GroupId:             group.Id,
+		PrivateKey:          genRandomPrivateKey(),
+		UnmarshalledQuerier: UnmarshalledQuerier{QuerierSource: "formatted"},
+		Normalization:       querier.NormalizationConfig{Type: "json", Encoding: querier.Uint256Encoding},
+	}).CreateNode()
+	assert.Nil(t, node)
+}
+
+func TestQueryGroup(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	group, err := CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	_, err = QueryGroup(context.Background(), group.Id, "")
+	assert.Equal(t, NoAgreementErr, err)
+
+	// 3 of 4 nodes agree, the threshold of majority is 3
+	groupNodes := createFormattedNodes(t, group.Id, querier.NormalizationConfig{}, "2.0", "2", "3", "2e0")
+	// not certified before running dkg
+	_, err = QueryGroup(context.Background(), group.Id, "")
+	assert.Equal(t, NoAgreementErr, err)
+	assert.Equal(t, GroupNotExistedErr, RunDkg("not existed"))
+	require.Nil(t, RunDkg(group.Id))
+	report, err := QueryGroup(context.Background(), group.Id, "")
+	require.Nil(t, err)
+	assert.Equal(t, "2", report.Message)
+	assert.Equal(t, []string{groupNodes[0].Id, groupNodes[1].Id, groupNodes[3].Id}, report.Signers)
+	assert.NotNil(t, report.Signature)
+
+	// 2 of 4 nodes agree
+	groupNodes[1].Querier.(*formattedQuerier).Value = "3"
+	_, err = QueryGroup(context.Background(), group.Id, "")
+	assert.Equal(t, NoAgreementErr, err)
+}
+
+func TestQueryGroupSpans(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+
+	group, err := CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	createFormattedNodes(t, group.Id, querier.NormalizationConfig{}, "2", "2", "3")
+	require.Nil(t, RunDkg(group.Id))
+	_, err = QueryGroup(context.Background(), group.Id, "")
+	require.Nil(t, err)
+
+	// every span is in the trace of QueryGroup, under the span of its phase
+	spansByName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range spanRecorder.Ended() {
+		spansByName[span.Name()] = append(spansByName[span.Name()], span)
+	}
+	require.Len(t, spansByName["QueryGroup"], 1)
+	root := spansByName["QueryGroup"][0]
+	require.Len(t, spansByName["CollectPartialSignatures"], 1)
+	collect := spansByName["CollectPartialSignatures"][0]
+	assert.Equal(t, root.SpanContext().SpanID(), collect.Parent().SpanID())
+
+	require.Len(t, spansByName["Node.Query"], 3)
+	nodeQuerySpanIds := make(map[string]bool)
+	for _, span := range spansByName["Node.Query"] {
+		assert.Equal(t, collect.SpanContext().SpanID(), span.Parent().SpanID())
+		nodeQuerySpanIds[span.SpanContext().SpanID().String()] = true
+	}
+	for _, name := range []string{"Querier.Do", "crypto.Sign"} {
+		require.Len(t, spansByName[name], 3, name)
+		for _, span := range spansByName[name] {
+			assert.True(t, nodeQuerySpanIds[span.Parent().SpanID().String()], name)
+		}
+	}
+	// partial signatures of the 2 agreed nodes are verified before recovery
+	require.Len(t, spansByName["crypto.Verify"], 2)
+	require.Len(t, spansByName["crypto.Recover"], 1)
+	for _, span := range append(spansByName["crypto.Verify"], spansByName["crypto.Recover"]...) {
+		assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	for _, spans := range spansByName {
+		for _, span := range spans {
+			assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID())
+		}
+	}
+}
+
+func TestQueryGroupAudit(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.Open(path)
+	require.Nil(t, err)
+	AuditLog = auditLog
+	defer func() {
+		AuditLog = nil
+	}()
+
+	group, err := CreateGroup("", ThresholdPolicy{}, JoinSequenceIndex)
+	require.Nil(t, err)
+	groupNodes := createFormattedNodes(t, group.Id, querier.NormalizationConfig{}, "2.0", "2", "3", "2e0")
+	require.Nil(t, RunDkg(group.Id))
+	report, err := QueryGroup(context.Background(), group.Id, "")
+	require.Nil(t, err)
+	// no entry without agreement
+	groupNodes[1].Querier.(*formattedQuerier).Value = "3"
+	_, err = QueryGroup(context.Background(), group.Id, "")
+	assert.Equal(t, NoAgreementErr, err)
+	require.Nil(t, auditLog.Close())
+
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+	entry := audit.Entry{}
+	require.Nil(t, json.NewDecoder(file).Decode(&entry))
+	assert.Equal(t, hex.EncodeToString(report.Signature), entry.Signature)
+	assert.Equal(t, report.Signers, entry.Signers)
+	// raw results of all nodes are recorded, including the disagreed one
+	require.Len(t, entry.Answers, 4)
+	results := make(map[string]string)
+	for _, answer := range entry.Answers {
+		results[answer.NodeId] = answer.Result
+		assert.NotEmpty(t, answer.PartialSignature)
+	}
+	assert.Equal(t, "2.0", results[groupNodes[0].Id])
+	assert.Equal(t, "3", results[groupNodes[2].Id])
+
+	groupPublicKey, err := groupNodes[0].GetDkg(group.Id).GetDistributedPublicKey()
+	require.Nil(t, err)
+	_, err = file.Seek(0, io.SeekStart)
+	require.Nil(t, err)
+	count, err := audit.Verify(file, map[string]kyber.Point{group.Id: groupPublicKey})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
 }
@@ -24,18 +24,31 @@ package node
 
 import (
 	"fmt"
+	"sort"
 )
 
+// dkgIndexKey locates a node by its dkg index, which is scoped to each group
+type dkgIndexKey struct {
+	groupId string
+	index   int
+}
+
 // todo: implement these variables at off-chain registry
 var (
 	groupCounter       *int
 	nodeCounterByGroup map[string]int
 	groupTable         map[string]*Group
 	nodeTable          map[string]*Node
-	dkgIndexTable      map[int]*Node
+	dkgIndexTable      map[dkgIndexKey]*Node
+	// joinSequenceTable lists encoded public keys of nodes by the sequence they joined groups, see LoadDkgState
+	joinSequenceTable map[string][]string
 )
 
 func init() {
+	initRegistry()
+}
+
+func initRegistry() {
 	if groupCounter == nil {
 		var groupCounterValue int
 		groupCounter = &groupCounterValue
@@ -50,7 +63,10 @@ func init() {
 		nodeTable = make(map[string]*Node)
 	}
 	if dkgIndexTable == nil {
-		dkgIndexTable = make(map[int]*Node)
+		dkgIndexTable = make(map[dkgIndexKey]*Node)
+	}
+	if joinSequenceTable == nil {
+		joinSequenceTable = make(map[string][]string)
 	}
 }
 
@@ -63,6 +79,16 @@ func generateNodeId(groupId string) string {
 	return fmt.Sprintf("%v.%v", count, groupId)
 }
 
+func generateGroupId() string {
+	for {
+		groupId := fmt.Sprintf("%v", *groupCounter)
+		*groupCounter++
+		if getGroup(groupId) == nil {
+			return groupId
+		}
+	}
+}
+
 func getGroup(groupId string) *Group {
 	if groupTable == nil {
 		return nil
@@ -73,6 +99,18 @@ func getGroup(groupId string) *Group {
 	return nil
 }
 
+// getGroups returns all groups sorted by id
+func getGroups() []*Group {
+	groups := make([]*Group, 0, len(groupTable))
+	for _, group := range groupTable {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Id < groups[j].Id
+	})
+	return groups
+}
+
 func getNode(nodeId string) *Node {
 	if nodeTable == nil {
 		return nil
@@ -83,11 +121,11 @@ func getNode(nodeId string) *Node {
 	return nil
 }
 
-func getNodeByDkgIndex(index int) *Node {
+func getNodeByDkgIndex(groupId string, index int) *Node {
 	if dkgIndexTable == nil {
 		return nil
 	}
-	if node, ok := dkgIndexTable[index]; ok {
+	if node, ok := dkgIndexTable[dkgIndexKey{groupId: groupId, index: index}]; ok {
 		return node
 	}
 	return nil
@@ -105,5 +143,42 @@ func setNode(node *Node) {
 		return
 	}
 	nodeTable[node.Id] = node
-	dkgIndexTable[node.Dkg.GetIndex()] = node
+	for groupId, dkg := range node.Dkgs {
+		dkgIndexTable[dkgIndexKey{groupId: groupId, index: dkg.GetIndex()}] = node
+	}
+}
+
+func unsetGroup(groupId string) {
+	if groupTable == nil {
+		return
+	}
+	delete(groupTable, groupId)
+	delete(nodeCounterByGroup, groupId)
+}
+
+func unsetNode(nodeId string) {
+	if nodeTable == nil {
+		return
+	}
+	node, ok := nodeTable[nodeId]
+	if !ok {
+		return
+	}
+	delete(nodeTable, nodeId)
+	for key, indexedNode := range dkgIndexTable {
+		if indexedNode == node {
+			delete(dkgIndexTable, key)
+		}
+	}
+}
+
+func unsetDkgIndices(groupId string) {
+	if dkgIndexTable == nil {
+		return
+	}
+	for key := range dkgIndexTable {
+		if key.groupId == groupId {
+			delete(dkgIndexTable, key)
+		}
+	}
 }
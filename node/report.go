@@ -0,0 +1,320 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package node
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KofClubs/siwa/crypto"
+	"github.com/KofClubs/siwa/node/audit"
+	"github.com/KofClubs/siwa/node/querier"
+	"github.com/KofClubs/siwa/node/telemetry"
+	"github.com/MonteCarloClub/log"
+	"go.dedis.ch/kyber/v3"
+	pedersendkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	DkgErr         = fmt.Errorf("dkg not certified")
+	NoAgreementErr = fmt.Errorf("too few nodes agreed on a message")
+	RecoverErr     = fmt.Errorf("fail to recover threshold signature")
+	AuditErr       = fmt.Errorf("fail to append audit log")
+)
+
+// AuditLog records every report recovered by QueryGroup if not nil, a report failed to record is not returned
+var AuditLog *audit.Log
+
+// Report is a message agreed by at least threshold nodes of a group, with the recovered threshold signature of its
+// payload signed with the group id, the request id and the expression
+type Report struct {
+	GroupId string
+	// RequestId is the id of the request on a chain answered by the report, empty if none
+	RequestId  string
+	Expression string
+	Message    string
+	Signature  []byte
+	// Signers are ids of nodes which signed the message
+	Signers   []string
+	Timestamp time.Time
+}
+
+type nodeAnswer struct {
+	nodeId     string
+	result     querier.Result
+	message    string
+	signature  []byte
+	answeredAt time.Time
+	err        error
+}
+
+// RunDkg exchanges deals and responses of dkgs in the group among its nodes in this process
+func RunDkg(groupId string) error {
+	group := getGroup(groupId)
+	if group == nil {
+		log.Error("fail to run dkg", "group id", groupId, "err", GroupNotExistedErr)
+		return GroupNotExistedErr
+	}
+
+	members := make([]*Node, 0, len(group.DkgIndices))
+	for _, nodeId := range group.getNodeIds() {
+		node := getNode(nodeId)
+		if node == nil || node.GetDkg(groupId) == nil {
+			log.Error("fail to run dkg", "group id", groupId, "node id", nodeId, "err", NodeNotExistedErr)
+			return NodeNotExistedErr
+		}
+		start := time.Now()
+		err := node.GetDkg(groupId).CreatePedersenDkgDeals()
+		if err != nil {
+			return err
+		}
+		observeDuration(dkgPhaseDuration.WithLabelValues(node.Id, groupId, "deal"), start)
+		members = append(members, node)
+	}
+
+	// every deal is processed before any response, durations of phases are summed by node
+	responseDurations := make(map[string]time.Duration)
+	pedersenDkgResponses := make([]*pedersendkg.Response, 0)
+	for _, node := range members {
+		for index, pedersenDkgDeal := range node.GetDkg(groupId).PedersendkgDeals {
+			verifier := getNodeByDkgIndex(groupId, index)
+			start := time.Now()
+			pedersenDkgResponse, ok := verifier.GetDkg(groupId).VerifyPedersenDkgDeal(pedersenDkgDeal)
+			responseDurations[verifier.Id] += time.Since(start)
+			if !ok {
+				log.Error("fail to verify deal", "group id", groupId, "dealer id", node.Id, "index", index)
+				return DkgErr
+			}
+			pedersenDkgResponses = append(pedersenDkgResponses, pedersenDkgResponse)
+		}
+	}
+	processDurations := make(map[string]time.Duration)
+	for _, pedersenDkgResponse := range pedersenDkgResponses {
+		for _, node := range members {
+			start := time.Now()
+			node.GetDkg(groupId).VerifyPedersenDkgResponse(pedersenDkgResponse)
+			processDurations[node.Id] += time.Since(start)
+		}
+	}
+	for _, node := range members {
+		dkgPhaseDuration.WithLabelValues(node.Id, groupId, "response").Observe(responseDurations[node.Id].Seconds())
+		dkgPhaseDuration.WithLabelValues(node.Id, groupId, "process_response").Observe(
+			processDurations[node.Id].Seconds())
+	}
+
+	for _, node := range members {
+		if !node.ReadyToQuery(groupId) {
+			log.Error("fail to run dkg", "group id", groupId, "node id", node.Id, "err", DkgErr)
+			return DkgErr
+		}
+	}
+	return nil
+}
+
+// GroupPublicKey returns the distributed public key of the group, which verifies recovered threshold signatures
+func GroupPublicKey(groupId string) (kyber.Point, error) {
+	group := getGroup(groupId)
+	if group == nil {
+		log.Error("fail to get public key of group", "group id", groupId, "err", GroupNotExistedErr)
+		return nil, GroupNotExistedErr
+	}
+	for _, nodeId := range group.getNodeIds() {
+		node := getNode(nodeId)
+		if node == nil || node.GetDkg(groupId) == nil || !node.ReadyToQuery(groupId) {
+			continue
+		}
+		return node.GetDkg(groupId).GetDistributedPublicKey()
+	}
+	log.Error("fail to get public key of group", "group id", groupId, "err", DkgErr)
+	return nil, DkgErr
+}
+
+// QueryGroup queries the expression by all nodes of the group in parallel, and recovers the threshold signature of
+// the message which most nodes agreed on
+func QueryGroup(ctx context.Context, groupId, expression string) (*Report, error) {
+	return AnswerRequest(ctx, groupId, "", expression)
+}
+
+// AnswerRequest queries like QueryGroup, and binds signatures to the id of the request on a chain
+func AnswerRequest(ctx context.Context, groupId, requestId, expression string) (report *Report, err error) {
+	ctx, span := tracer.Start(ctx, "QueryGroup", trace.WithAttributes(attribute.String("siwa.group_id", groupId),
+		attribute.String("siwa.request_id", requestId), attribute.String("siwa.expression", expression)))
+	defer func() {
+		telemetry.End(span, err)
+	}()
+	group := getGroup(groupId)
+	if group == nil {
+		log.Error("fail to query group", "group id", groupId, "err", GroupNotExistedErr)
+		return nil, GroupNotExistedErr
+	}
+	nodeIds := group.getNodeIds()
+
+	// partial signatures are collected in a span, which is the parent of queries of nodes
+	collectCtx, collectSpan := tracer.Start(ctx, "CollectPartialSignatures")
+	queriedAt := time.Now()
+	answers := make([]nodeAnswer, len(nodeIds))
+	var wg sync.WaitGroup
+	for i, nodeId := range nodeIds {
+		wg.Add(1)
+		go func(i int, nodeId string) {
+			defer wg.Done()
+			answers[i].nodeId = nodeId
+			node := getNode(nodeId)
+			if node == nil {
+				answers[i].err = NodeNotExistedErr
+				return
+			}
+			answers[i].result, answers[i].message, answers[i].signature, answers[i].err = node.query(collectCtx,
+				groupId, requestId, expression)
+			answers[i].answeredAt = time.Now()
+		}(i, nodeId)
+	}
+	wg.Wait()
+	collectSpan.End()
+
+	answersByMessage := make(map[string][]nodeAnswer)
+	for _, answer := range answers {
+		if answer.err == nil {
+			answersByMessage[answer.message] = append(answersByMessage[answer.message], answer)
+		}
+	}
+	messages := make([]string, 0, len(answersByMessage))
+	for message := range answersByMessage {
+		messages = append(messages, message)
+	}
+	// most agreed first, ties broken by message for every node to pick the same one
+	sort.Slice(messages, func(i, j int) bool {
+		if len(answersByMessage[messages[i]]) != len(answersByMessage[messages[j]]) {
+			return len(answersByMessage[messages[i]]) > len(answersByMessage[messages[j]])
+		}
+		return messages[i] < messages[j]
+	})
+	if len(messages) == 0 || group.Threshold <= 0 || len(answersByMessage[messages[0]]) < group.Threshold {
+		log.Error("fail to query group", "group id", groupId, "expression", expression, "threshold",
+			group.Threshold, "err", NoAgreementErr)
+		return nil, NoAgreementErr
+	}
+
+	message := messages[0]
+	span.SetAttributes(attribute.Int("siwa.agreed_count", len(answersByMessage[message])))
+	report = &Report{
+		GroupId:    groupId,
+		RequestId:  requestId,
+		Expression: expression,
+		Message:    message,
+		Signers:    make([]string, 0, len(answersByMessage[message])),
+		Timestamp:  time.Now(),
+	}
+	signatures := make([][]byte, 0, len(answersByMessage[message]))
+	for _, answer := range answersByMessage[message] {
+		report.Signers = append(report.Signers, answer.nodeId)
+		signatures = append(signatures, answer.signature)
+	}
+	recoverer := getNode(report.Signers[0])
+	observePartialSignatures(ctx, recoverer, report, answersByMessage[message])
+	_, recoverSpan := tracer.Start(ctx, "crypto.Recover", telemetry.NodeAttributes(recoverer.Id, groupId))
+	signature, ok := recoverer.Recover(groupId, requestId, expression, message, signatures)
+	if !ok {
+		log.Error("fail to query group", "group id", groupId, "expression", expression, "err", RecoverErr)
+		telemetry.End(recoverSpan, RecoverErr)
+		return nil, RecoverErr
+	}
+	recoverSpan.End()
+	report.Signature = signature
+
+	if AuditLog != nil {
+		err = AuditLog.Append(auditEntryOf(recoverer, group, report, answers, queriedAt))
+		if err != nil {
+			log.Error("fail to query group", "group id", groupId, "expression", expression, "err", err)
+			return nil, AuditErr
+		}
+	}
+	return report, nil
+}
+
+// auditEntryOf records answers of all nodes, including those which failed or disagreed
+func auditEntryOf(recoverer *Node, group *Group, report *Report, answers []nodeAnswer,
+	queriedAt time.Time) *audit.Entry {
+	entry := &audit.Entry{
+		GroupId:    report.GroupId,
+		RequestId:  report.RequestId,
+		Expression: report.Expression,
+		Threshold:  group.Threshold,
+		NodeCount:  len(group.NodeIds),
+		Message:    hex.EncodeToString([]byte(report.Message)),
+		Signature:  hex.EncodeToString(report.Signature),
+		Signers:    report.Signers,
+		Answers:    make([]audit.Answer, 0, len(answers)),
+		QueriedAt:  queriedAt,
+		SignedAt:   report.Timestamp,
+	}
+	publicKey, err := recoverer.GetDkg(group.Id).GetDistributedPublicKey()
+	if err == nil {
+		entry.PublicKey = hex.EncodeToString(crypto.EncodeBlsPublicKey(publicKey))
+	}
+	for _, answer := range answers {
+		auditAnswer := audit.Answer{
+			NodeId:     answer.nodeId,
+			AnsweredAt: answer.answeredAt,
+		}
+		// the result is kept even if normalization failed
+		if answer.result.Value != "" {
+			auditAnswer.ResultType = answer.result.Type.String()
+			auditAnswer.Result = answer.result.Value
+		}
+		if answer.err != nil {
+			auditAnswer.Err = answer.err.Error()
+		} else {
+			auditAnswer.Message = hex.EncodeToString([]byte(answer.message))
+			auditAnswer.PartialSignature = hex.EncodeToString(answer.signature)
+		}
+		entry.Answers = append(entry.Answers, auditAnswer)
+	}
+	return entry
+}
+
+// observePartialSignatures verifies partial signatures of answers by the recoverer, for metrics only
+func observePartialSignatures(ctx context.Context, recoverer *Node, report *Report, answers []nodeAnswer) {
+	groupId := report.GroupId
+	valid := 0
+	for _, answer := range answers {
+		_, span := tracer.Start(ctx, "crypto.Verify", telemetry.NodeAttributes(answer.nodeId, groupId))
+		ok := recoverer.Verify(report.GroupId, report.RequestId, report.Expression, report.Message,
+			answer.signature)
+		span.SetAttributes(attribute.Bool("siwa.valid", ok))
+		span.End()
+		if ok {
+			valid++
+			partialSignaturesTotal.WithLabelValues(answer.nodeId, groupId, "true").Inc()
+		} else {
+			partialSignaturesTotal.WithLabelValues(answer.nodeId, groupId, "false").Inc()
+		}
+	}
+	partialSignatureValidity.WithLabelValues(recoverer.Id, groupId).Set(float64(valid) / float64(len(answers)))
+}
@@ -0,0 +1,156 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package node
+
+import (
+	"github.com/MonteCarloClub/log"
+)
+
+// DefaultMaxGroupSize is the max group size of the default group scheduler
+const DefaultMaxGroupSize = 16
+
+// GroupScheduler assigns a node without a specified group to a group
+type GroupScheduler interface {
+	// Schedule selects a group among groups sorted by id, and returns its id, or "" if all groups are full
+	Schedule(unmarshalledNode *UnmarshalledNode, groups []*Group) string
+}
+
+// LeastPopulatedScheduler fills the least-populated group, ties broken by group id
+type LeastPopulatedScheduler struct {
+	MaxGroupSize int
+}
+
+func (scheduler *LeastPopulatedScheduler) Schedule(unmarshalledNode *UnmarshalledNode, groups []*Group) string {
+	if scheduler == nil {
+		log.Error("nil least populated scheduler")
+		return ""
+	}
+
+	var selectedGroup *Group
+	for _, group := range groups {
+		if !hasRoom(group, scheduler.MaxGroupSize) {
+			continue
+		}
+		if selectedGroup == nil || len(group.NodeIds) < len(selectedGroup.NodeIds) {
+			selectedGroup = group
+		}
+	}
+	if selectedGroup == nil {
+		return ""
+	}
+	return selectedGroup.Id
+}
+
+type SpreadAttribute int
+
+const (
+	SpreadByRegion SpreadAttribute = iota
+	SpreadByQuerierSource
+)
+
+// SpreadScheduler selects the group with the fewest nodes sharing the attribute of the new node,
+// ties broken by group population and then group id
+type SpreadScheduler struct {
+	MaxGroupSize int
+	Attribute    SpreadAttribute
+}
+
+func (scheduler *SpreadScheduler) Schedule(unmarshalledNode *UnmarshalledNode, groups []*Group) string {
+	if scheduler == nil || unmarshalledNode == nil {
+		log.Error("nil spread scheduler or unmarshalled node")
+		return ""
+	}
+
+	attribute := scheduler.attributeOfUnmarshalledNode(unmarshalledNode)
+	var selectedGroup *Group
+	selectedSharingCount := 0
+	for _, group := range groups {
+		if !hasRoom(group, scheduler.MaxGroupSize) {
+			continue
+		}
+		sharingCount := 0
+		for nodeId := range group.NodeIds {
+			node := getNode(nodeId)
+			if node != nil && scheduler.attributeOfNode(node) == attribute {
+				sharingCount++
+			}
+		}
+		if selectedGroup == nil || sharingCount < selectedSharingCount ||
+			(sharingCount == selectedSharingCount && len(group.NodeIds) < len(selectedGroup.NodeIds)) {
+			selectedGroup = group
+			selectedSharingCount = sharingCount
+		}
+	}
+	if selectedGroup == nil {
+		return ""
+	}
+	return selectedGroup.Id
+}
+
+func (scheduler *SpreadScheduler) attributeOfUnmarshalledNode(unmarshalledNode *UnmarshalledNode) string {
+	if scheduler.Attribute == SpreadByQuerierSource {
+		return unmarshalledNode.QuerierSource
+	}
+	return unmarshalledNode.Region
+}
+
+func (scheduler *SpreadScheduler) attributeOfNode(node *Node) string {
+	if scheduler.Attribute == SpreadByQuerierSource {
+		return node.QuerierSource
+	}
+	return node.Region
+}
+
+// hasRoom reports whether a node can join the group, a non-positive max group size means unlimited
+func hasRoom(group *Group, maxGroupSize int) bool {
+	if group == nil {
+		return false
+	}
+	return maxGroupSize <= 0 || len(group.NodeIds) < maxGroupSize
+}
+
+var groupScheduler GroupScheduler = &LeastPopulatedScheduler{MaxGroupSize: DefaultMaxGroupSize}
+
+func SetGroupScheduler(scheduler GroupScheduler) {
+	if scheduler == nil {
+		log.Warn("nil group scheduler, not set")
+		return
+	}
+	groupScheduler = scheduler
+}
+
+// scheduleGroup selects a group for the node, and creates a new one when all groups are full
+func scheduleGroup(unmarshalledNode *UnmarshalledNode) string {
+	groupId := groupScheduler.Schedule(unmarshalledNode, getGroups())
+	if groupId != "" {
+		return groupId
+	}
+
+	group, err := CreateGroup("", ThresholdPolicy{Type: MajorityThreshold}, JoinSequenceIndex)
+	if err != nil {
+		log.Error("fail to create group when all groups full", "err", err)
+		return ""
+	}
+	log.Info("all groups full, new group created", "group id", group.Id)
+	return group.Id
+}
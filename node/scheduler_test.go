@@ -0,0 +1,107 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package node
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetRegistry() {
+	groupCounter = nil
+	nodeCounterByGroup = nil
+	groupTable = nil
+	nodeTable = nil
+	dkgIndexTable = nil
+	joinSequenceTable = nil
+	dkgStateFile = ""
+	initRegistry()
+}
+
+func createGroupOfSize(groupId string, size int, region string) *Group {
+	group := &Group{
+		Id:      groupId,
+		NodeIds: make(map[string]struct{}),
+	}
+	for i := 0; i < size; i++ {
+		nodeId := fmt.Sprintf("%v.%v", i, groupId)
+		group.NodeIds[nodeId] = struct{}{}
+		nodeTable[nodeId] = &Node{
+			Id:            nodeId,
+			GroupId:       groupId,
+			Region:        region,
+			QuerierSource: "redis",
+		}
+	}
+	setGroup(group)
+	return group
+}
+
+func TestLeastPopulatedScheduler(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	createGroupOfSize("a", 2, "")
+	createGroupOfSize("b", 1, "")
+	createGroupOfSize("c", 1, "")
+	scheduler := &LeastPopulatedScheduler{MaxGroupSize: 2}
+	assert.Equal(t, "b", scheduler.Schedule(&UnmarshalledNode{}, getGroups()))
+
+	scheduler.MaxGroupSize = 1
+	assert.Equal(t, "", scheduler.Schedule(&UnmarshalledNode{}, getGroups()))
+}
+
+func TestSpreadScheduler(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+
+	createGroupOfSize("a", 1, "eu")
+	createGroupOfSize("b", 2, "us")
+	createGroupOfSize("c", 3, "us")
+	scheduler := &SpreadScheduler{MaxGroupSize: 3, Attribute: SpreadByRegion}
+	assert.Equal(t, "b", scheduler.Schedule(&UnmarshalledNode{Region: "eu"}, getGroups()))
+	assert.Equal(t, "a", scheduler.Schedule(&UnmarshalledNode{Region: "us"}, getGroups()))
+	assert.Equal(t, "a", scheduler.Schedule(&UnmarshalledNode{Region: "ap"}, getGroups()))
+
+	scheduler.Attribute = SpreadByQuerierSource
+	unmarshalledNode := &UnmarshalledNode{UnmarshalledQuerier: UnmarshalledQuerier{QuerierSource: "redis"}}
+	assert.Equal(t, "a", scheduler.Schedule(unmarshalledNode, getGroups()))
+}
+
+func TestScheduleGroup(t *testing.T) {
+	resetRegistry()
+	defer resetRegistry()
+	SetGroupScheduler(&LeastPopulatedScheduler{MaxGroupSize: 2})
+	defer SetGroupScheduler(&LeastPopulatedScheduler{MaxGroupSize: DefaultMaxGroupSize})
+
+	groupIds := make([]string, 0)
+	for i := 0; i < 3; i++ {
+		node := createRedisNode(t, "")
+		groupIds = append(groupIds, node.GroupId)
+	}
+	assert.Equal(t, []string{"0", "0", "1"}, groupIds)
+	assert.Equal(t, 2, len(getGroup("0").NodeIds))
+	assert.Equal(t, 1, len(getGroup("1").NodeIds))
+}
@@ -0,0 +1,83 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package node
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/MonteCarloClub/log"
+)
+
+// dkgStateFile keeps join sequences of groups apart from the config file, it is not written if empty
+var dkgStateFile string
+
+type dkgState struct {
+	// JoinSequences lists public keys in hex of nodes of each group under the join_sequence policy, by the sequence
+	// they first joined, since node ids are generated again when nodes are created
+	JoinSequences map[string][]string `json:"join_sequences"`
+}
+
+// LoadDkgState reads join sequences of groups from the state file, and saves them to the file whenever they
+// change, so that nodes joining again after restarts get the same dkg indices. A missing file is an empty state
+func LoadDkgState(path string) error {
+	state := dkgState{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Error("fail to read dkg state file", "path", path, "err", err)
+		return err
+	}
+	if len(data) > 0 {
+		err = json.Unmarshal(data, &state)
+		if err != nil {
+			log.Error("fail to unmarshal dkg state file", "path", path, "err", err)
+			return err
+		}
+	}
+	if state.JoinSequences == nil {
+		state.JoinSequences = make(map[string][]string)
+	}
+	joinSequenceTable = state.JoinSequences
+	dkgStateFile = path
+	return nil
+}
+
+// saveDkgState replaces the state file by a complete one, so that a crash never leaves it half written
+func saveDkgState() {
+	if dkgStateFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(dkgState{JoinSequences: joinSequenceTable}, "", "  ")
+	if err != nil {
+		log.Error("fail to marshal dkg state", "err", err)
+		return
+	}
+	tempFile := dkgStateFile + ".tmp"
+	err = os.WriteFile(tempFile, data, 0600)
+	if err == nil {
+		err = os.Rename(tempFile, dkgStateFile)
+	}
+	if err != nil {
+		log.Error("fail to save dkg state file", "path", dkgStateFile, "err", err)
+	}
+}
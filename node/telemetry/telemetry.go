@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+// Package telemetry configures OpenTelemetry tracing of siwa, and propagates trace context over http
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/MonteCarloClub/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	NoneExporter   = "none"
+	StdoutExporter = "stdout"
+	OtlpExporter   = "otlp"
+
+	DefaultServiceName = "siwa"
+
+	instrumentationName = "github.com/KofClubs/siwa/node/telemetry"
+)
+
+var IllegalExporterErr = fmt.Errorf("illegal trace exporter")
+
+type UnmarshalledTracing struct {
+	// Exporter is none, stdout or otlp, spans are not exported if none or empty
+	Exporter string `yaml:"exporter"`
+	// Endpoint is host:port of the otlp collector over http, or the file written by the stdout exporter, which
+	// writes to stdout if empty
+	Endpoint string `yaml:"endpoint"`
+	// Insecure disables tls to the otlp collector
+	Insecure bool `yaml:"insecure"`
+	// SampleRatio of root spans, 1 if not positive, child spans follow their parents
+	SampleRatio float64 `yaml:"sample_ratio"`
+	// ServiceName is DefaultServiceName if empty
+	ServiceName string `yaml:"service_name"`
+}
+
+// CreateTracerProvider installs the global propagator of trace context and baggage, and the global tracer provider
+// exporting to the configured exporter, the returned shutdown flushes spans, and closes the file of the stdout
+// exporter
+func (unmarshalledTracing *UnmarshalledTracing) CreateTracerProvider(ctx context.Context) (
+	func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{},
+		propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	var err error
+	switch unmarshalledTracing.Exporter {
+	case "", NoneExporter:
+		return func(context.Context) error { return nil }, nil
+	case StdoutExporter:
+		options := []stdouttrace.Option{stdouttrace.WithPrettyPrint()}
+		if unmarshalledTracing.Endpoint != "" {
+			file, err = os.OpenFile(unmarshalledTracing.Endpoint, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				log.Error("fail to open file of spans", "path", unmarshalledTracing.Endpoint, "err", err)
+				return nil, err
+			}
+			options = []stdouttrace.Option{stdouttrace.WithWriter(file)}
+		}
+		exporter, err = stdouttrace.New(options...)
+	case OtlpExporter:
+		options := []otlptracehttp.Option{}
+		if unmarshalledTracing.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(unmarshalledTracing.Endpoint))
+		}
+		if unmarshalledTracing.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		log.Error("fail to create tracer provider", "exporter", unmarshalledTracing.Exporter,
+			"err", IllegalExporterErr)
+		return nil, IllegalExporterErr
+	}
+	if err != nil {
+		log.Error("fail to create trace exporter", "exporter", unmarshalledTracing.Exporter, "err", err)
+		if file != nil {
+			_ = file.Close()
+		}
+		return nil, err
+	}
+
+	serviceName := unmarshalledTracing.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	sampleRatio := unmarshalledTracing.SampleRatio
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	log.Info("tracing enabled", "exporter", unmarshalledTracing.Exporter, "service name", serviceName,
+		"sample ratio", sampleRatio)
+	return func(ctx context.Context) error {
+		err := tracerProvider.Shutdown(ctx)
+		if file != nil {
+			closeErr := file.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Handler continues the trace of the request by its headers, in a server span named by the path
+func Handler(handler http.Handler) http.Handler {
+	tracer := otel.Tracer(instrumentationName)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+		ctx, span := tracer.Start(ctx, request.URL.Path, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPMethod(request.Method), semconv.HTTPTarget(request.URL.RequestURI())))
+		defer span.End()
+		handler.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+// Inject writes the trace context of ctx to headers of an outgoing request
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// End records err on the span if not nil, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// NodeAttributes identifies the node and its group in spans
+func NodeAttributes(nodeId, groupId string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("siwa.node_id", nodeId), attribute.String("siwa.group_id", groupId))
+}
@@ -0,0 +1,89 @@
+/*
+Copyright (c) 2022 Zhang Zhanpeng <zhangregister@outlook.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCreateTracerProvider(t *testing.T) {
+	ctx := context.Background()
+	_, err := (&UnmarshalledTracing{Exporter: "jaeger"}).CreateTracerProvider(ctx)
+	assert.Equal(t, IllegalExporterErr, err)
+	shutdown, err := (&UnmarshalledTracing{}).CreateTracerProvider(ctx)
+	require.Nil(t, err)
+	assert.Nil(t, shutdown(ctx))
+
+	// spans are written to the file by the stdout exporter when shut down
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err = (&UnmarshalledTracing{Exporter: StdoutExporter, Endpoint: path}).CreateTracerProvider(ctx)
+	require.Nil(t, err)
+	_, span := otel.Tracer("test").Start(ctx, "TestSpan")
+	span.End()
+	require.Nil(t, shutdown(ctx))
+	spans, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.Contains(t, string(spans), `"Name":"TestSpan"`)
+	assert.Contains(t, string(spans), `"Value":"siwa"`)
+	// the file is closed by shutdown
+	assert.ErrorIs(t, shutdown(ctx), os.ErrClosed)
+}
+
+func TestPropagation(t *testing.T) {
+	ctx := context.Background()
+	shutdown, err := (&UnmarshalledTracing{Exporter: StdoutExporter, Endpoint: filepath.Join(t.TempDir(),
+		"spans.json")}).CreateTracerProvider(ctx)
+	require.Nil(t, err)
+	defer func() {
+		_ = shutdown(ctx)
+	}()
+
+	// the server span continues the trace of the client
+	var serverSpanContext trace.SpanContext
+	server := httptest.NewServer(Handler(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		serverSpanContext = trace.SpanContextFromContext(request.Context())
+	})))
+	defer server.Close()
+
+	clientCtx, clientSpan := otel.Tracer("test").Start(ctx, "Client")
+	request, err := http.NewRequestWithContext(clientCtx, http.MethodGet, server.URL+"/stream", nil)
+	require.Nil(t, err)
+	Inject(clientCtx, request.Header)
+	assert.NotEmpty(t, request.Header.Get("traceparent"))
+	response, err := http.DefaultClient.Do(request)
+	require.Nil(t, err)
+	_ = response.Body.Close()
+	clientSpan.End()
+
+	assert.Equal(t, clientSpan.SpanContext().TraceID(), serverSpanContext.TraceID())
+	assert.NotEqual(t, clientSpan.SpanContext().SpanID(), serverSpanContext.SpanID())
+}